@@ -0,0 +1,32 @@
+package main
+
+import (
+	"BkC/config"
+	"embed"
+	"io/fs"
+	"os"
+)
+
+// embeddedAssets contient les modèles HTML et les fichiers statiques, intégrés au binaire
+// pour qu'il fonctionne quel que soit le répertoire de lancement
+//
+//go:embed templates static
+var embeddedAssets embed.FS
+
+// templatesFS retourne les modèles : intégrés, ou lus dans templatesDir en mode développement
+func templatesFS(cfg *config.Config) fs.FS {
+	if cfg.Dev {
+		return os.DirFS(cfg.TemplatesDir)
+	}
+	sub, _ := fs.Sub(embeddedAssets, "templates")
+	return sub
+}
+
+// staticFS retourne les fichiers statiques : intégrés, ou lus dans staticDir en mode développement
+func staticFS(cfg *config.Config) fs.FS {
+	if cfg.Dev {
+		return os.DirFS(cfg.StaticDir)
+	}
+	sub, _ := fs.Sub(embeddedAssets, "static")
+	return sub
+}
@@ -0,0 +1,59 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// auditEventType distingue les événements d'audit des autres données de bloc
+const auditEventType = "audit"
+
+// AuditEvent représente une action d'administration enregistrée dans la blockchain
+type AuditEvent struct {
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`             // Administrateur à l'origine de l'action
+	Action    string    `json:"action"`            // Ex: "role_change", "disable", "delete", "force_logout"
+	Target    string    `json:"target"`            // Compte concerné
+	Details   string    `json:"details,omitempty"` // Informations complémentaires (ancien/nouveau rôle...)
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CreateAuditEvent crée un nouvel événement d'audit
+func CreateAuditEvent(actor, action, target, details string) AuditEvent {
+	return AuditEvent{
+		Type:      auditEventType,
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		Timestamp: time.Now(),
+	}
+}
+
+// AddAuditBlockAsync enregistre un événement d'audit dans un nouveau bloc de manière asynchrone
+func (bc *Blockchain) AddAuditBlockAsync(event AuditEvent, difficulty int) {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	bc.AddBlockAsync(string(eventData), difficulty)
+}
+
+// GetAuditEvents retourne tous les événements d'audit enregistrés dans la chaîne
+func (bc *Blockchain) GetAuditEvents() []AuditEvent {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	var events []AuditEvent
+
+	// Parcourir tous les blocs sauf le genesis
+	for i := 1; i < len(bc.Blocks); i++ {
+		var event AuditEvent
+		err := json.Unmarshal([]byte(bc.Blocks[i].Data), &event)
+		if err == nil && event.Type == auditEventType {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
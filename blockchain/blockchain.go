@@ -1,12 +1,15 @@
 package blockchain
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,6 +37,18 @@ type Blockchain struct {
 	updateChannel chan BlockUpdate
 	subscribers   []chan BlockUpdate
 	subMutex      sync.RWMutex
+	dataFile      string               // Fichier de sauvegarde de la chaîne
+	jobs          sync.WaitGroup       // Minages asynchrones en cours
+	jobsMutex     sync.Mutex           // Protège jobStarts, nextJob et draining
+	jobStarts     map[uint64]time.Time // Date de lancement des minages asynchrones en cours
+	nextJob       uint64
+	draining      bool         // Vrai une fois l'arrêt commencé : les minages asynchrones sont refusés
+	lastBlockAt   time.Time    // Date d'ajout du dernier bloc, pour l'intervalle entre blocs
+	height        atomic.Int64 // Nombre de blocs, lisible sans attendre la fin d'un minage
+	dispatching   atomic.Bool  // Vrai tant que processUpdates diffuse les mises à jour
+	statusMutex   sync.Mutex   // Protège loadErr et validationErr
+	loadErr       error        // Erreur de lecture du fichier au démarrage
+	validationErr error        // Incohérence détectée dans la chaîne chargée
 }
 
 type block struct {
@@ -46,7 +61,7 @@ type block struct {
 }
 
 // CreateGenesisBlock crée le premier bloc (genesis block)
-func CreateGenesisBlock() *Block {
+func CreateGenesisBlock(difficulty int) *Block {
 	block := &Block{
 		Index:     0,
 		Timestamp: time.Now().String(),
@@ -55,22 +70,33 @@ func CreateGenesisBlock() *Block {
 		Nonce:     0,
 	}
 	block.Hash = block.ComputeHash()
-	block.ProofOfWork(4) // Par défaut, difficulté = 4
+	block.ProofOfWork(difficulty)
 	return block
 }
 
-// NewBlockchain initialise une nouvelle blockchain
-func NewBlockchain() *Blockchain {
+// NewBlockchain initialise une blockchain sauvegardée dans dataFile, en la rechargeant si le
+// fichier existe ; sinon la chaîne démarre avec un bloc genesis miné à la difficulté indiquée
+func NewBlockchain(dataFile string, genesisDifficulty int) *Blockchain {
 	bc := &Blockchain{
-		Blocks:        []*Block{CreateGenesisBlock()},
+		Blocks:        []*Block{CreateGenesisBlock(genesisDifficulty)},
 		updateChannel: make(chan BlockUpdate, 100), // Buffer de 100 pour éviter le blocage
 		subscribers:   make([]chan BlockUpdate, 0),
+		dataFile:      dataFile,
+		jobStarts:     make(map[uint64]time.Time),
 	}
 
 	// Essayer de charger la blockchain depuis un fichier
-	bc.LoadFromFile()
+	if err := bc.LoadFromFile(); err != nil {
+		slog.Error("Chargement de la blockchain impossible", "file", dataFile, "error", err)
+		bc.statusMutex.Lock()
+		bc.loadErr = err
+		bc.statusMutex.Unlock()
+	}
+	bc.height.Store(int64(len(bc.Blocks)))
+	chainHeight.Set(float64(len(bc.Blocks)))
 
 	// Démarrer la goroutine pour traiter les mises à jour
+	bc.dispatching.Store(true)
 	go bc.processUpdates()
 
 	return bc
@@ -78,6 +104,7 @@ func NewBlockchain() *Blockchain {
 
 // processUpdates traite les mises à jour et les envoie aux abonnés
 func (bc *Blockchain) processUpdates() {
+	defer bc.dispatching.Store(false)
 	for update := range bc.updateChannel {
 		// Envoyer l'update à tous les abonnés
 		bc.subMutex.RLock()
@@ -87,6 +114,7 @@ func (bc *Blockchain) processUpdates() {
 				// Message envoyé avec succès
 			default:
 				// Canal plein, on ignore
+				blockUpdatesDropped.Inc()
 			}
 		}
 		bc.subMutex.RUnlock()
@@ -158,6 +186,7 @@ func (bc *Blockchain) AddBlockWithMiner(data string, difficulty int, miner strin
 	newBlock.MiningInfo = string(miningJson)
 
 	bc.Blocks = append(bc.Blocks, newBlock)
+	bc.recordBlock(newBlock, time.Since(startTime))
 
 	// Envoyer une notification de mise à jour
 	bc.updateChannel <- BlockUpdate{
@@ -166,17 +195,17 @@ func (bc *Blockchain) AddBlockWithMiner(data string, difficulty int, miner strin
 		Miner: miner,
 	}
 
-	// Sauvegarde automatique après ajout d'un bloc
-	bc.SaveToFile()
+	// Sauvegarde automatique après ajout d'un bloc (le verrou est déjà détenu)
+	bc.saveToFileLocked()
 
 	return newBlock
 }
 
 // AddBlockWithMinerAsync ajoute un bloc de manière asynchrone avec information sur le mineur
 func (bc *Blockchain) AddBlockWithMinerAsync(data string, difficulty int, miner string) {
-	go func() {
+	bc.runAsync(func() {
 		bc.AddBlockWithMiner(data, difficulty, miner)
-	}()
+	})
 }
 
 // GetBlocksByMiner récupère tous les blocs minés par un utilisateur spécifique
@@ -195,6 +224,43 @@ func (bc *Blockchain) GetBlocksByMiner(miner string) []*Block {
 	return minerBlocks
 }
 
+// GetBlock retourne le bloc d'index donné
+func (bc *Blockchain) GetBlock(index int) (*Block, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if index < 0 || index >= len(bc.Blocks) {
+		return nil, false
+	}
+	return bc.Blocks[index], true
+}
+
+// GetBlockByHash retourne le bloc de hash donné
+func (bc *Blockchain) GetBlockByHash(hash string) (*Block, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	for _, block := range bc.Blocks {
+		if block.Hash == hash {
+			return block, true
+		}
+	}
+	return nil, false
+}
+
+// GetBlocks retourne au plus limit blocs à partir de l'index offset, ainsi que le nombre total de blocs
+func (bc *Blockchain) GetBlocks(offset, limit int) ([]*Block, int) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	total := len(bc.Blocks)
+	if offset >= total {
+		return []*Block{}, total
+	}
+	end := min(offset+limit, total)
+	return append([]*Block(nil), bc.Blocks[offset:end]...), total
+}
+
 // IsMiningBlock vérifie si un bloc a été miné (commence par "Miné par")
 func (bc *Blockchain) IsMiningBlock(block *Block) bool {
 	return strings.HasPrefix(block.Data, "Miné par")
@@ -213,8 +279,10 @@ func (bc *Blockchain) AddBlock(data string, difficulty int) {
 		PrevHash:  prevBlock.Hash,
 		Nonce:     0,
 	}
+	startTime := time.Now()
 	newBlock.ProofOfWork(difficulty)
 	bc.Blocks = append(bc.Blocks, newBlock)
+	bc.recordBlock(newBlock, time.Since(startTime))
 
 	// Envoyer une notification de mise à jour
 	bc.updateChannel <- BlockUpdate{
@@ -222,15 +290,15 @@ func (bc *Blockchain) AddBlock(data string, difficulty int) {
 		Type:  "new",
 	}
 
-	// Sauvegarde automatique après ajout d'un bloc
-	bc.SaveToFile()
+	// Sauvegarde automatique après ajout d'un bloc (le verrou est déjà détenu)
+	bc.saveToFileLocked()
 }
 
 // AddBlockAsync ajoute un bloc de manière asynchrone
 func (bc *Blockchain) AddBlockAsync(data string, difficulty int) {
-	go func() {
+	bc.runAsync(func() {
 		bc.AddBlock(data, difficulty)
-	}()
+	})
 }
 
 // AddMessageBlock ajoute un message entre utilisateurs comme un nouveau bloc
@@ -247,9 +315,62 @@ func (bc *Blockchain) AddMessageBlock(message Message, difficulty int) {
 
 // AddMessageBlockAsync ajoute un message de manière asynchrone
 func (bc *Blockchain) AddMessageBlockAsync(message Message, difficulty int) {
-	go func() {
+	bc.runAsync(func() {
 		bc.AddMessageBlock(message, difficulty)
+	})
+}
+
+// runAsync lance un minage en arrière-plan et le comptabilise pour Drain.
+// Après le début de l'arrêt, le minage est abandonné.
+func (bc *Blockchain) runAsync(job func()) {
+	bc.jobsMutex.Lock()
+	defer bc.jobsMutex.Unlock()
+
+	if bc.draining {
+		slog.Warn("Arrêt en cours, minage asynchrone ignoré")
+		return
+	}
+	id := bc.nextJob
+	bc.nextJob++
+	bc.jobStarts[id] = time.Now()
+	miningJobsPending.Inc()
+	bc.jobs.Add(1)
+
+	go func() {
+		defer func() {
+			bc.jobsMutex.Lock()
+			delete(bc.jobStarts, id)
+			miningJobsPending.Dec()
+			bc.jobsMutex.Unlock()
+			bc.jobs.Done()
+		}()
+		job()
+	}()
+}
+
+// Drain refuse les nouveaux minages asynchrones, attend la fin de ceux en cours puis sauvegarde
+// la chaîne. Si le contexte expire avant, les minages restants sont abandonnés : chaque bloc
+// étant sauvegardé dès son ajout, le fichier contient alors tous les blocs déjà minés.
+func (bc *Blockchain) Drain(ctx context.Context) error {
+	bc.jobsMutex.Lock()
+	bc.draining = true
+	bc.jobsMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		bc.jobs.Wait()
+		close(done)
 	}()
+
+	select {
+	case <-done:
+		return bc.SaveToFile()
+	case <-ctx.Done():
+		bc.jobsMutex.Lock()
+		pending := len(bc.jobStarts)
+		bc.jobsMutex.Unlock()
+		return fmt.Errorf("%d minage(s) abandonné(s): %v", pending, ctx.Err())
+	}
 }
 
 // GetMessageBlocks retourne tous les blocs qui contiennent des messages
@@ -300,19 +421,12 @@ func (bc *Blockchain) SaveToFile() error {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 
-	// Convertir la blockchain en JSON
-	data, err := json.MarshalIndent(bc.Blocks, "", "  ")
-	if err != nil {
-		return fmt.Errorf("erreur lors de la sérialisation de la blockchain: %v", err)
-	}
-
-	// Écrire dans un fichier
-	err = ioutil.WriteFile("blockchain_data.json", data, 0644)
-	if err != nil {
-		return fmt.Errorf("erreur lors de l'écriture de la blockchain: %v", err)
-	}
+	return bc.saveToFileLocked()
+}
 
-	return nil
+// saveToFileLocked sauvegarde la blockchain, l'appelant devant détenir le verrou
+func (bc *Blockchain) saveToFileLocked() error {
+	return WriteBlocksFile(bc.dataFile, bc.Blocks)
 }
 
 // LoadFromFile charge la blockchain depuis un fichier
@@ -321,28 +435,61 @@ func (bc *Blockchain) LoadFromFile() error {
 	defer bc.mu.Unlock()
 
 	// Vérifier si le fichier existe
-	if _, err := os.Stat("blockchain_data.json"); os.IsNotExist(err) {
+	if _, err := os.Stat(bc.dataFile); os.IsNotExist(err) {
 		return nil // Le fichier n'existe pas, utiliser la blockchain par défaut
 	}
 
-	// Lire le fichier
-	data, err := ioutil.ReadFile("blockchain_data.json")
+	blocks, err := ReadBlocksFile(bc.dataFile)
 	if err != nil {
-		return fmt.Errorf("erreur lors de la lecture du fichier blockchain: %v", err)
+		return err
 	}
 
-	// Désérialiser les données
-	var blocks []*Block
-	err = json.Unmarshal(data, &blocks)
+	// Vérifier la validité de la chaîne : une chaîne incohérente est conservée (pour ne pas
+	// perdre de données) mais signalée, ce qui rend le nœud non prêt
+	if len(blocks) > 0 {
+		bc.Blocks = blocks
+		validationErr := ValidateBlocks(blocks)
+		if validationErr != nil {
+			slog.Error("Blockchain chargée invalide", "file", bc.dataFile, "error", validationErr)
+		}
+		bc.statusMutex.Lock()
+		bc.validationErr = validationErr
+		bc.statusMutex.Unlock()
+	}
+
+	return nil
+}
+
+// ReadBlocksFile lit une liste de blocs enregistrée au format JSON, sans la valider
+func ReadBlocksFile(path string) ([]*Block, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("erreur lors de la désérialisation de la blockchain: %v", err)
+		return nil, fmt.Errorf("erreur lors de la lecture du fichier blockchain: %v", err)
 	}
 
-	// Vérifier la validité de la chaîne
-	if len(blocks) > 0 {
-		bc.Blocks = blocks
+	var blocks []*Block
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return nil, fmt.Errorf("erreur lors de la désérialisation de la blockchain: %v", err)
 	}
+	return blocks, nil
+}
 
+// WriteBlocksFile enregistre une liste de blocs au format JSON. Le fichier est écrit à côté puis
+// renommé, pour qu'une interruption ne laisse jamais une chaîne tronquée.
+func WriteBlocksFile(path string, blocks []*Block) error {
+	data, err := json.MarshalIndent(blocks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation de la blockchain: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture de la blockchain: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("erreur lors de l'écriture de la blockchain: %v", err)
+	}
 	return nil
 }
 
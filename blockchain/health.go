@@ -0,0 +1,93 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+)
+
+// StuckJobAfter est la durée au-delà de laquelle un minage asynchrone est considéré comme bloqué
+const StuckJobAfter = 5 * time.Minute
+
+// ValidateBlocks vérifie l'enchaînement d'une liste de blocs : index consécutifs, hash de chaque
+// bloc conforme à son contenu et égal au PrevHash du bloc suivant
+func ValidateBlocks(blocks []*Block) error {
+	for i, block := range blocks {
+		if block == nil {
+			return fmt.Errorf("bloc #%d absent", i)
+		}
+		if block.Index != i {
+			return fmt.Errorf("bloc #%d: index %d inattendu", i, block.Index)
+		}
+		if hash := block.ComputeHash(); block.Hash != hash {
+			return fmt.Errorf("bloc #%d: hash %.16s... ne correspond pas au contenu (%.16s...)", i, block.Hash, hash)
+		}
+		if i > 0 && block.PrevHash != blocks[i-1].Hash {
+			return fmt.Errorf("bloc #%d: PrevHash ne correspond pas au hash du bloc #%d", i, i-1)
+		}
+	}
+	return nil
+}
+
+// ValidateProofOfWork vérifie que le hash de chaque bloc commence par au moins minDifficulty zéros,
+// ou par la difficulté déclarée dans ses informations de minage si elle est plus élevée.
+// Le type des blocs n'étant pas enregistré, minDifficulty est la plus petite difficulté acceptée.
+func ValidateProofOfWork(blocks []*Block, minDifficulty int) error {
+	for i, block := range blocks {
+		if block == nil {
+			return fmt.Errorf("bloc #%d absent", i)
+		}
+		difficulty := max(minDifficulty, block.DeclaredDifficulty())
+		if !block.MeetsDifficulty(difficulty) {
+			return fmt.Errorf("bloc #%d: hash %.16s... sans les %d zéros de tête exigés", i, block.Hash, difficulty)
+		}
+	}
+	return nil
+}
+
+// Validate vérifie l'intégralité de la chaîne
+func (bc *Blockchain) Validate() error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return ValidateBlocks(bc.Blocks)
+}
+
+// Health décrit l'état de la chaîne pour les sondes de disponibilité
+type Health struct {
+	Height            int           // Nombre de blocs
+	LoadError         error         // Erreur de lecture du fichier au démarrage
+	ValidationError   error         // Incohérence détectée dans la chaîne chargée
+	DispatcherRunning bool          // Diffusion des mises à jour active
+	PendingUpdates    int           // Mises à jour en attente de diffusion
+	UpdateQueueSize   int           // Capacité de la file des mises à jour
+	PendingJobs       int           // Minages asynchrones en attente ou en cours
+	OldestJob         time.Duration // Ancienneté du plus ancien minage asynchrone
+	Draining          bool          // Arrêt en cours
+}
+
+// Health retourne l'état de la chaîne sans attendre la fin d'un minage en cours
+func (bc *Blockchain) Health() Health {
+	h := Health{
+		Height:            int(bc.height.Load()),
+		DispatcherRunning: bc.dispatching.Load(),
+		PendingUpdates:    len(bc.updateChannel),
+		UpdateQueueSize:   cap(bc.updateChannel),
+	}
+
+	bc.statusMutex.Lock()
+	h.LoadError = bc.loadErr
+	h.ValidationError = bc.validationErr
+	bc.statusMutex.Unlock()
+
+	bc.jobsMutex.Lock()
+	h.PendingJobs = len(bc.jobStarts)
+	h.Draining = bc.draining
+	now := time.Now()
+	for _, start := range bc.jobStarts {
+		if age := now.Sub(start); age > h.OldestJob {
+			h.OldestJob = age
+		}
+	}
+	bc.jobsMutex.Unlock()
+
+	return h
+}
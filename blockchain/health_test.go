@@ -0,0 +1,50 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// minedBlock construit un bloc dont le hash a exactement zeros zéros en tête
+func minedBlock(zeros int, info *MiningData) *Block {
+	block := &Block{Index: 1, Timestamp: "t", Data: "données", PrevHash: "p"}
+	if info != nil {
+		raw, _ := json.Marshal(info)
+		block.MiningInfo = string(raw)
+	}
+	for {
+		block.Hash = block.ComputeHash()
+		if block.MeetsDifficulty(zeros) && !block.MeetsDifficulty(zeros+1) {
+			return block
+		}
+		block.Nonce++
+	}
+}
+
+func TestValidateProofOfWork(t *testing.T) {
+	tests := []struct {
+		name          string
+		block         *Block
+		minDifficulty int
+		valid         bool
+	}{
+		{"difficulté atteinte", minedBlock(2, nil), 2, true},
+		{"difficulté dépassée", minedBlock(3, nil), 2, true},
+		{"difficulté insuffisante", minedBlock(1, nil), 2, false},
+		{"aucun zéro", minedBlock(0, nil), 1, false},
+		{"difficulté déclarée atteinte", minedBlock(2, &MiningData{Difficulty: 2}), 1, true},
+		{"difficulté déclarée non atteinte", minedBlock(1, &MiningData{Difficulty: 3}), 1, false},
+		{"déclaration inférieure au minimum", minedBlock(1, &MiningData{Difficulty: 1}), 2, false},
+		{"informations de minage illisibles", minedBlock(1, nil), 1, true},
+	}
+	tests[len(tests)-1].block.MiningInfo = "{"
+	for _, tt := range tests {
+		err := ValidateProofOfWork([]*Block{tt.block}, tt.minDifficulty)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s : erreur %v", tt.name, err)
+		}
+	}
+	if err := ValidateProofOfWork([]*Block{nil}, 1); err == nil {
+		t.Error("bloc absent accepté")
+	}
+}
@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"BkC/metrics"
+	"time"
+)
+
+// Métriques de la chaîne, exposées par /metrics
+var (
+	chainHeight = metrics.NewGauge("bkc_chain_height",
+		"Nombre de blocs de la chaîne, genesis compris.")
+	blockInterval = metrics.NewHistogram("bkc_block_interval_seconds",
+		"Intervalle entre deux blocs ajoutés à la chaîne.",
+		[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600})
+	miningDuration = metrics.NewHistogram("bkc_mining_duration_seconds",
+		"Durée de la preuve de travail d'un bloc.",
+		[]float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60})
+	hashesTotal = metrics.NewCounter("bkc_hashes_total",
+		"Nombre de hashs calculés par la preuve de travail.")
+	hashRate = metrics.NewGauge("bkc_hash_rate",
+		"Hashs par seconde lors du dernier bloc miné.")
+	miningJobsPending = metrics.NewGauge("bkc_mining_jobs_pending",
+		"Minages asynchrones en attente ou en cours.")
+	blockUpdatesDropped = metrics.NewCounter("bkc_block_updates_dropped_total",
+		"Notifications de nouveaux blocs perdues car l'abonné ne suivait pas.")
+)
+
+// recordBlock met à jour les métriques après l'ajout d'un bloc miné en duration.
+// L'appelant doit détenir bc.mu.
+func (bc *Blockchain) recordBlock(block *Block, duration time.Duration) {
+	now := time.Now()
+	if !bc.lastBlockAt.IsZero() {
+		blockInterval.Observe(now.Sub(bc.lastBlockAt).Seconds())
+	}
+	bc.lastBlockAt = now
+
+	bc.height.Store(int64(len(bc.Blocks)))
+	chainHeight.Set(float64(len(bc.Blocks)))
+	miningDuration.Observe(duration.Seconds())
+	hashesTotal.Add(float64(block.Nonce))
+	if duration > 0 {
+		hashRate.Set(float64(block.Nonce) / duration.Seconds())
+	}
+}
@@ -1,12 +1,29 @@
 package blockchain
 
-import "strings"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // ProofOfWork effectue une preuve de travail (PoW)
 func (b *Block) ProofOfWork(difficulty int) {
-	prefix := strings.Repeat("0", difficulty)
-	for !strings.HasPrefix(b.Hash, prefix) {
+	for !b.MeetsDifficulty(difficulty) {
 		b.Nonce++
 		b.Hash = b.ComputeHash()
 	}
 }
+
+// MeetsDifficulty indique si le hash du bloc commence par au moins difficulty zéros
+func (b *Block) MeetsDifficulty(difficulty int) bool {
+	return strings.HasPrefix(b.Hash, strings.Repeat("0", difficulty))
+}
+
+// DeclaredDifficulty retourne la difficulté enregistrée dans MiningInfo, 0 si le bloc n'en déclare pas
+// (blocs de messages, d'audit, genesis...)
+func (b *Block) DeclaredDifficulty() int {
+	var info MiningData
+	if b.MiningInfo == "" || json.Unmarshal([]byte(b.MiningInfo), &info) != nil {
+		return 0
+	}
+	return info.Difficulty
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"BkC/blockchain"
+	"BkC/config"
+	"BkC/handlers"
+	"BkC/utils"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Codes de sortie de la ligne de commande
+const (
+	exitError = 1 // La commande a échoué
+	exitUsage = 2 // Commande, options ou arguments invalides
+)
+
+// cliActor est l'auteur des actions de la ligne de commande dans la piste d'audit, et le mineur
+// par défaut de bkc mine (nom réservé, qu'aucun compte ne peut porter)
+const cliActor = "bkc"
+
+// command est une sous-commande de bkc
+type command struct {
+	name    string
+	args    string // Arguments attendus, pour l'aide
+	summary string
+	run     func(name string, args []string) int
+}
+
+// commands liste les sous-commandes de bkc (initialisée dans init : help y fait référence)
+var commands []command
+
+// userCommands liste les sous-commandes de bkc user
+var userCommands = []command{
+	{"add", "[-role rôle] nom", "créer un compte (mot de passe lu sur l'entrée standard)", userAddCommand},
+	{"list", "[-json]", "lister les comptes", userListCommand},
+	{"disable", "[-enable] nom", "désactiver (ou réactiver) un compte et fermer ses connexions", userDisableCommand},
+	{"reset-password", "nom", "changer le mot de passe d'un compte (lu sur l'entrée standard)", userResetPasswordCommand},
+}
+
+func init() {
+	commands = []command{
+		{"serve", "[options]", "démarrer le serveur web (commande par défaut)", func(_ string, args []string) int { return serve(args) }},
+		{"verify", "[-min-difficulty n] [fichier]", "vérifier une blockchain (par défaut celle du répertoire de données)", verifyCommand},
+		{"export", "[-o fichier]", "exporter la blockchain (sortie standard par défaut)", exportCommand},
+		{"import", "[-force] fichier", "remplacer la blockchain par un fichier exporté", importCommand},
+		{"mine", "[-miner nom] données...", "miner un bloc localement", mineCommand},
+		{"user", "add|list|disable|reset-password ...", "gérer les comptes", userCommand},
+		{"stats", "[-json]", "afficher les statistiques", statsCommand},
+		{"help", "", "afficher cette aide", func(string, []string) int { printUsage(os.Stdout, "bkc", commands); return 0 }},
+	}
+}
+
+// run exécute la sous-commande désignée par args. Sans sous-commande (ou avec des options seules,
+// comme avant l'ajout des sous-commandes), le serveur est démarré.
+func run(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serve(args)
+	}
+	return dispatch("bkc", commands, args)
+}
+
+// dispatch exécute la commande de table nommée par args[0]
+func dispatch(prefix string, table []command, args []string) int {
+	if len(args) > 0 {
+		for _, cmd := range table {
+			if cmd.name == args[0] {
+				return cmd.run(prefix+" "+cmd.name, args[1:])
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%s : commande inconnue %q\n\n", prefix, args[0])
+	}
+	printUsage(os.Stderr, prefix, table)
+	return exitUsage
+}
+
+// printUsage affiche la liste des commandes d'une table
+func printUsage(w io.Writer, prefix string, table []command) {
+	fmt.Fprintf(w, "Usage : %s <commande> [options] [arguments]\n\nCommandes :\n", prefix)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range table {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nLes options de configuration du serveur (-config, -data-dir, ...) sont acceptées par toutes les commandes.\n")
+	fmt.Fprintf(w, "Aide d'une commande : %s <commande> -h\n", prefix)
+}
+
+// parseCommand lit la configuration et les options d'une commande. Si cfg est nil, la commande
+// doit s'arrêter avec le code retourné (aide affichée ou options invalides).
+func parseCommand(name, args string, argv []string, bind func(fs *flag.FlagSet)) (*config.Config, []string, int) {
+	cfg, rest, err := config.LoadCommand(name, argv, func(fs *flag.FlagSet) {
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage : %s [options] %s\n\nOptions :\n", name, args)
+			fs.PrintDefaults()
+		}
+		if bind != nil {
+			bind(fs)
+		}
+	})
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return nil, nil, 0
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s : %v\n", name, err)
+		return nil, nil, exitUsage
+	}
+	return cfg, rest, 0
+}
+
+// usageError signale des arguments invalides
+func usageError(name, args string) int {
+	fmt.Fprintf(os.Stderr, "Usage : %s [options] %s\n", name, args)
+	return exitUsage
+}
+
+// failed affiche l'erreur d'une commande
+func failed(name string, err error) int {
+	fmt.Fprintf(os.Stderr, "❌ %s : %v\n", name, err)
+	return exitError
+}
+
+// openData charge la blockchain et les comptes du répertoire de données, comme au démarrage du
+// serveur. Le journal n'est écrit que sur la sortie d'erreur (avertissements et erreurs), pour ne
+// pas partager le fichier journal avec un serveur en cours d'exécution.
+func openData(cfg *config.Config) (*blockchain.Blockchain, error) {
+	if err := utils.SetupLogging(utils.LogOptions{Level: slog.LevelWarn, JSON: cfg.Log.Format == "json"}); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("création du répertoire de données impossible : %v", err)
+	}
+
+	bc := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), cfg.Difficulty.Genesis)
+	handlers.InitGlobalBC(bc, cfg)
+	return bc, nil
+}
+
+// closeData attend la fin des minages en cours (blocs d'audit notamment)
+func closeData(bc *blockchain.Blockchain) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := bc.Drain(ctx); err != nil {
+		slog.Warn("Minages en cours non terminés", "error", err)
+	}
+}
+
+// readPassword lit un mot de passe sur la première ligne de l'entrée standard, avec une invite
+// si elle est un terminal
+func readPassword(prompt string) (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("lecture du mot de passe impossible : %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// verifyCommand vérifie l'enchaînement et les preuves de travail d'une blockchain enregistrée :
+// chaque hash doit commencer par au moins -min-difficulty zéros (par défaut la plus petite des
+// difficultés configurées), ou par la difficulté déclarée par le bloc si elle est plus élevée
+func verifyCommand(name string, argv []string) int {
+	const args = "[-min-difficulty n] [fichier]"
+	var minDifficulty int
+	cfg, rest, code := parseCommand(name, "[fichier]", argv, func(fs *flag.FlagSet) {
+		fs.IntVar(&minDifficulty, "min-difficulty", 0, "nombre minimal de zéros en tête des hash (par défaut la plus petite difficulté configurée)")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) > 1 {
+		return usageError(name, args)
+	}
+
+	path := cfg.DataPath(cfg.Files.Blockchain)
+	if len(rest) == 1 {
+		path = rest[0]
+	}
+	blocks, err := blockchain.ReadBlocksFile(path)
+	if err != nil {
+		return failed(name, err)
+	}
+	if len(blocks) == 0 {
+		return failed(name, fmt.Errorf("%s : aucun bloc", path))
+	}
+	if err := blockchain.ValidateBlocks(blocks); err != nil {
+		return failed(name, fmt.Errorf("%s : %v", path, err))
+	}
+	if minDifficulty <= 0 {
+		d := cfg.Difficulty
+		minDifficulty = min(d.Genesis, d.Mining, d.Messages, d.Signup, d.Audit, d.Visits, d.Sessions)
+	}
+	if err := blockchain.ValidateProofOfWork(blocks, minDifficulty); err != nil {
+		return failed(name, fmt.Errorf("%s : %v", path, err))
+	}
+
+	fmt.Printf("✅ %s : %d blocs valides (au moins %d zéros en tête), dernier hash %s\n", path, len(blocks), minDifficulty, blocks[len(blocks)-1].Hash)
+	return 0
+}
+
+// exportCommand écrit la blockchain enregistrée dans un fichier ou sur la sortie standard
+func exportCommand(name string, argv []string) int {
+	var output string
+	cfg, rest, code := parseCommand(name, "", argv, func(fs *flag.FlagSet) {
+		fs.StringVar(&output, "o", "", "fichier de destination (sortie standard par défaut)")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) > 0 {
+		return usageError(name, "")
+	}
+
+	blocks, err := blockchain.ReadBlocksFile(cfg.DataPath(cfg.Files.Blockchain))
+	if err != nil {
+		return failed(name, err)
+	}
+	if err := blockchain.ValidateBlocks(blocks); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️ Blockchain invalide, exportée telle quelle : %v\n", err)
+	}
+
+	if output == "" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(blocks); err != nil {
+			return failed(name, err)
+		}
+		return 0
+	}
+	if err := blockchain.WriteBlocksFile(output, blocks); err != nil {
+		return failed(name, err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ %d blocs exportés dans %s\n", len(blocks), output)
+	return 0
+}
+
+// importCommand remplace la blockchain du répertoire de données par une chaîne exportée.
+// La chaîne importée doit être valide et, sauf avec -force, prolonger la chaîne actuelle.
+// L'ancienne chaîne est conservée dans un fichier .bak.
+func importCommand(name string, argv []string) int {
+	const args = "[-force] fichier"
+	var force bool
+	cfg, rest, code := parseCommand(name, "fichier", argv, func(fs *flag.FlagSet) {
+		fs.BoolVar(&force, "force", false, "remplacer la chaîne actuelle même si la chaîne importée ne la prolonge pas")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) != 1 {
+		return usageError(name, args)
+	}
+
+	blocks, err := blockchain.ReadBlocksFile(rest[0])
+	if err != nil {
+		return failed(name, err)
+	}
+	if len(blocks) == 0 {
+		return failed(name, fmt.Errorf("%s : aucun bloc", rest[0]))
+	}
+	if err := blockchain.ValidateBlocks(blocks); err != nil {
+		return failed(name, fmt.Errorf("%s : %v", rest[0], err))
+	}
+
+	release, err := lockDataDir(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer release()
+
+	path := cfg.DataPath(cfg.Files.Blockchain)
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		current = nil
+	case err != nil:
+		return failed(name, err)
+	}
+
+	added := len(blocks)
+	if current != nil {
+		var existing []*blockchain.Block
+		if err := json.Unmarshal(current, &existing); err != nil && !force {
+			return failed(name, fmt.Errorf("chaîne actuelle illisible (%v), utilisez -force pour la remplacer", err))
+		}
+		common := commonPrefix(existing, blocks)
+		if common < len(existing) && !force {
+			return failed(name, errors.New("la chaîne importée ne prolonge pas la chaîne actuelle, utilisez -force pour la remplacer"))
+		}
+		added -= common
+		if err := os.WriteFile(path+".bak", current, 0644); err != nil {
+			return failed(name, fmt.Errorf("sauvegarde de la chaîne actuelle impossible : %v", err))
+		}
+	}
+
+	if err := blockchain.WriteBlocksFile(path, blocks); err != nil {
+		return failed(name, err)
+	}
+	fmt.Printf("✅ %d blocs importés (%d nouveaux) dans %s\n", len(blocks), added, path)
+	return 0
+}
+
+// commonPrefix retourne le nombre de blocs identiques au début des deux chaînes
+func commonPrefix(a, b []*blockchain.Block) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] != nil && a[n].Hash == b[n].Hash {
+		n++
+	}
+	return n
+}
+
+// mineCommand mine un bloc dans la blockchain du répertoire de données, à la difficulté -difficulty
+func mineCommand(name string, argv []string) int {
+	const args = "[-miner nom] données..."
+	miner := cliActor
+	cfg, rest, code := parseCommand(name, "données...", argv, func(fs *flag.FlagSet) {
+		fs.StringVar(&miner, "miner", miner, "compte auquel le bloc est attribué")
+	})
+	if cfg == nil {
+		return code
+	}
+	data := strings.TrimSpace(strings.Join(rest, " "))
+	if data == "" {
+		return usageError(name, args)
+	}
+
+	release, err := lockDataDir(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer release()
+
+	bc, err := openData(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer closeData(bc)
+
+	if err := bc.Validate(); err != nil {
+		return failed(name, fmt.Errorf("blockchain invalide, minage refusé : %v", err))
+	}
+	if miner != cliActor && !handlers.UserExists(miner) {
+		return failed(name, fmt.Errorf("%s : %v", miner, handlers.ErrUserNotFound))
+	}
+
+	start := time.Now()
+	block := handlers.MineBlock(bc, miner, data)
+	if err := bc.SaveToFile(); err != nil {
+		return failed(name, err)
+	}
+
+	fmt.Printf("✅ Bloc #%d miné en %v\n   hash  %s\n   nonce %d\n", block.Index, time.Since(start).Round(time.Millisecond), block.Hash, block.Nonce)
+	return 0
+}
+
+// userCommand regroupe la gestion des comptes
+func userCommand(name string, argv []string) int {
+	return dispatch(name, userCommands, argv)
+}
+
+// userAddCommand crée un compte
+func userAddCommand(name string, argv []string) int {
+	const args = "[-role rôle] nom"
+	roleName := string(utils.RoleMember)
+	cfg, rest, code := parseCommand(name, "nom", argv, func(fs *flag.FlagSet) {
+		fs.StringVar(&roleName, "role", roleName, "rôle du compte (readonly, member, admin)")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) != 1 {
+		return usageError(name, args)
+	}
+	role, valid := utils.ParseRole(roleName)
+	if !valid {
+		fmt.Fprintf(os.Stderr, "%s : rôle inconnu %q\n", name, roleName)
+		return exitUsage
+	}
+
+	password, err := readPassword("Mot de passe : ")
+	if err != nil {
+		return failed(name, err)
+	}
+
+	release, err := lockDataDir(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer release()
+
+	bc, err := openData(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer closeData(bc)
+
+	if err := handlers.CreateUser(cliActor, rest[0], password, role); err != nil {
+		return failed(name, err)
+	}
+	fmt.Printf("✅ Compte %s créé (%s)\n", rest[0], role)
+	return 0
+}
+
+// userListCommand liste les comptes
+func userListCommand(name string, argv []string) int {
+	var asJSON bool
+	cfg, rest, code := parseCommand(name, "", argv, func(fs *flag.FlagSet) {
+		fs.BoolVar(&asJSON, "json", false, "sortie JSON")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) > 0 {
+		return usageError(name, "")
+	}
+
+	bc, err := openData(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer closeData(bc)
+
+	views := handlers.ListUsers()
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(views); err != nil {
+			return failed(name, err)
+		}
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NOM\tRÔLE\tÉTAT\t2FA\tCONNEXIONS\tCRÉÉ LE\tDERNIÈRE CONNEXION")
+	for _, view := range views {
+		state := "actif"
+		if view.Disabled {
+			state = "désactivé"
+		}
+		twoFactor := "non"
+		if view.TwoFactor {
+			twoFactor = "oui"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			view.Username, view.Role, state, twoFactor, view.Devices, formatDate(view.CreatedAt), formatDate(view.LastLogin))
+	}
+	tw.Flush()
+	return 0
+}
+
+// formatDate affiche une date de compte, "-" si elle est inconnue
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("02/01/2006 15:04")
+}
+
+// userDisableCommand désactive ou réactive un compte
+func userDisableCommand(name string, argv []string) int {
+	const args = "[-enable] nom"
+	var enable bool
+	cfg, rest, code := parseCommand(name, "nom", argv, func(fs *flag.FlagSet) {
+		fs.BoolVar(&enable, "enable", false, "réactiver le compte")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) != 1 {
+		return usageError(name, args)
+	}
+
+	release, err := lockDataDir(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer release()
+
+	bc, err := openData(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer closeData(bc)
+
+	if err := handlers.SetUserDisabled(cliActor, rest[0], !enable); err != nil {
+		return failed(name, fmt.Errorf("%s : %v", rest[0], err))
+	}
+	if enable {
+		fmt.Printf("✅ Compte %s réactivé\n", rest[0])
+	} else {
+		fmt.Printf("✅ Compte %s désactivé\n", rest[0])
+	}
+	return 0
+}
+
+// userResetPasswordCommand remplace le mot de passe d'un compte
+func userResetPasswordCommand(name string, argv []string) int {
+	const args = "nom"
+	cfg, rest, code := parseCommand(name, args, argv, nil)
+	if cfg == nil {
+		return code
+	}
+	if len(rest) != 1 {
+		return usageError(name, args)
+	}
+
+	password, err := readPassword("Nouveau mot de passe : ")
+	if err != nil {
+		return failed(name, err)
+	}
+
+	release, err := lockDataDir(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer release()
+
+	bc, err := openData(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer closeData(bc)
+
+	if err := handlers.ResetUserPassword(cliActor, rest[0], password); err != nil {
+		return failed(name, fmt.Errorf("%s : %v", rest[0], err))
+	}
+	fmt.Printf("✅ Mot de passe de %s modifié, connexions fermées et jetons d'API révoqués\n", rest[0])
+	return 0
+}
+
+// statsOutput est la sortie JSON de bkc stats
+type statsOutput struct {
+	handlers.Stats
+	Blocks int                   `json:"blocks"`
+	Miners []handlers.MinerStats `json:"miners"`
+}
+
+// statsCommand affiche les statistiques du serveur
+func statsCommand(name string, argv []string) int {
+	var asJSON bool
+	cfg, rest, code := parseCommand(name, "", argv, func(fs *flag.FlagSet) {
+		fs.BoolVar(&asJSON, "json", false, "sortie JSON (format de /api/v1/stats)")
+	})
+	if cfg == nil {
+		return code
+	}
+	if len(rest) > 0 {
+		return usageError(name, "")
+	}
+
+	bc, err := openData(cfg)
+	if err != nil {
+		return failed(name, err)
+	}
+	defer closeData(bc)
+
+	_, total := bc.GetBlocks(0, 1)
+	out := statsOutput{
+		Stats:  handlers.CollectStats(bc),
+		Blocks: total,
+		Miners: handlers.CollectMinerStats(bc),
+	}
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			return failed(name, err)
+		}
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Blocs\t%d\n", out.Blocks)
+	if out.LastBlock != nil {
+		fmt.Fprintf(tw, "Dernier bloc\t#%d %s\n", out.LastBlock.Index, out.LastBlock.Hash)
+	}
+	fmt.Fprintf(tw, "Comptes\t%d\n", out.RegisteredUsers)
+	fmt.Fprintf(tw, "Sessions actives\t%d\n", out.ActiveSessions)
+	fmt.Fprintf(tw, "Visiteurs\t%d\n", out.VisitorCount)
+	fmt.Fprintf(tw, "Transactions du jour\t%d\n", out.DailyTransactions)
+	fmt.Fprintf(tw, "Blocages en cours\t%d\n", out.ActiveLockouts)
+	for i, miner := range out.Miners {
+		if i == 0 {
+			fmt.Fprintf(tw, "\nMineur\tBlocs\n")
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", miner.Username, miner.BlocksMined)
+	}
+	tw.Flush()
+	return 0
+}
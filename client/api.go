@@ -0,0 +1,228 @@
+package client
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Session décrit la session du client (GET et POST /api/v1/session)
+type Session struct {
+	Authenticated bool       `json:"authenticated"`
+	Username      string     `json:"username,omitempty"`
+	Role          utils.Role `json:"role,omitempty"`
+	CSRFToken     string     `json:"csrfToken"`
+}
+
+// Token est un jeton d'API personnel
+type Token struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Scopes    []utils.TokenScope `json:"scopes"`
+	CreatedAt time.Time          `json:"createdAt"`
+	LastUsed  time.Time          `json:"lastUsed"`
+	Token     string             `json:"token,omitempty"` // Jeton en clair, renvoyé uniquement par CreateToken
+}
+
+// BlockPage est une page de blocs
+type BlockPage struct {
+	Total  int                 `json:"total"`
+	Offset int                 `json:"offset"`
+	Limit  int                 `json:"limit"`
+	Blocks []*blockchain.Block `json:"blocks"`
+}
+
+// Stats sont les statistiques du serveur
+type Stats struct {
+	VisitorCount       int                `json:"visitorCount"`
+	ActiveSessions     int                `json:"activeSessions"`
+	RegisteredUsers    int                `json:"registeredUsers"`
+	DailyTransactions  int                `json:"dailyTransactions"`
+	LastBlock          *blockchain.Block  `json:"lastBlock"`
+	ActiveLockouts     int                `json:"activeLockouts"`
+	OnlineUsers        []string           `json:"onlineUsers"`
+	RecentConnections  []RecentConnection `json:"recentConnections"`
+	Countries          []GeoCount         `json:"countries"`
+	ISPs               []GeoCount         `json:"isps"`
+	TransactionHistory struct {
+		Dates  []string `json:"dates"`
+		Counts []int    `json:"counts"`
+	} `json:"transactionHistory"`
+}
+
+// RecentConnection est une connexion récente des statistiques
+type RecentConnection struct {
+	Username   string    `json:"username"`
+	Timestamp  time.Time `json:"timestamp"`
+	Country    string    `json:"country"`
+	UserAgent  string    `json:"userAgent"`
+	LastAction string    `json:"lastAction"`
+}
+
+// GeoCount est une entrée des répartitions par pays ou par fournisseur
+type GeoCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// MinerStats est l'activité d'un mineur
+type MinerStats struct {
+	Username       string `json:"username"`
+	BlocksMined    int    `json:"blocksMined"`
+	LastMiningTime int64  `json:"lastMiningTime"`
+}
+
+// Session retourne l'état de la session et mémorise le jeton CSRF
+func (c *Client) Session(ctx context.Context) (*Session, error) {
+	var session Session
+	if err := c.do(ctx, http.MethodGet, "/session", nil, nil, &session); err != nil {
+		return nil, err
+	}
+	c.setCSRFToken(session.CSRFToken)
+	return &session, nil
+}
+
+// Login ouvre une session. code est le code de double authentification (ou un code de secours),
+// à laisser vide si le compte ne l'a pas activée ; sinon l'erreur porte le code
+// CodeSecondFactorRequired.
+func (c *Client) Login(ctx context.Context, username, password, code string) (*Session, error) {
+	// Le formulaire de connexion est protégé par le jeton CSRF du cookie des visiteurs
+	if _, err := c.Session(ctx); err != nil {
+		return nil, err
+	}
+
+	var session Session
+	body := map[string]string{"username": username, "password": password, "code": code}
+	if err := c.do(ctx, http.MethodPost, "/session", nil, body, &session); err != nil {
+		return nil, err
+	}
+	c.setCSRFToken(session.CSRFToken)
+	return &session, nil
+}
+
+// Logout ferme la session ouverte par Login
+func (c *Client) Logout(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/logout", nil), nil)
+	if err != nil {
+		return err
+	}
+	c.authHeaders(req.Header, req.Method)
+	req.Header.Del("Authorization") // La déconnexion ne concerne que la session
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return decodeError(resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	c.setCSRFToken("")
+	return nil
+}
+
+// Tokens liste les jetons d'API de l'utilisateur (session uniquement)
+func (c *Client) Tokens(ctx context.Context) ([]Token, error) {
+	var tokens []Token
+	if err := c.do(ctx, http.MethodGet, "/tokens", nil, nil, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// CreateToken crée un jeton d'API (session uniquement). Le jeton en clair (champ Token) n'est
+// renvoyé qu'à cette occasion.
+func (c *Client) CreateToken(ctx context.Context, name string, scopes ...utils.TokenScope) (*Token, error) {
+	var token Token
+	body := map[string]interface{}{"name": name, "scopes": scopes}
+	if err := c.do(ctx, http.MethodPost, "/tokens", nil, body, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeToken révoque un jeton d'API (session uniquement)
+func (c *Client) RevokeToken(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/tokens/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Blocks retourne une page de blocs, du plus ancien au plus récent (limit 0 : taille par défaut)
+func (c *Client) Blocks(ctx context.Context, offset, limit int) (*BlockPage, error) {
+	query := url.Values{"offset": {strconv.Itoa(offset)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var page BlockPage
+	if err := c.do(ctx, http.MethodGet, "/blocks", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Block retourne le bloc d'index donné
+func (c *Client) Block(ctx context.Context, index int) (*blockchain.Block, error) {
+	var block blockchain.Block
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/blocks/%d", index), nil, nil, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Mine mine un bloc signé par l'utilisateur ; l'appel attend la fin du minage
+func (c *Client) Mine(ctx context.Context, data string) (*blockchain.Block, error) {
+	var block blockchain.Block
+	if err := c.do(ctx, http.MethodPost, "/blocks", nil, map[string]string{"data": data}, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Messages liste les messages envoyés et reçus, limités à la conversation avec with s'il n'est pas vide
+func (c *Client) Messages(ctx context.Context, with string) ([]blockchain.Message, error) {
+	var query url.Values
+	if with != "" {
+		query = url.Values{"with": {with}}
+	}
+	var messages []blockchain.Message
+	if err := c.do(ctx, http.MethodGet, "/messages", query, nil, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// SendMessage envoie un message ; il est ajouté à la chaîne une fois son bloc miné
+// (événement NewMessageEvent pour le destinataire)
+func (c *Client) SendMessage(ctx context.Context, recipient, content string) (*blockchain.Message, error) {
+	var message blockchain.Message
+	body := map[string]string{"recipient": recipient, "content": content}
+	if err := c.do(ctx, http.MethodPost, "/messages", nil, body, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Stats retourne les statistiques du serveur
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	if err := c.do(ctx, http.MethodGet, "/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Miners retourne les mineurs classés par nombre de blocs minés
+func (c *Client) Miners(ctx context.Context) ([]MinerStats, error) {
+	var miners []MinerStats
+	if err := c.do(ctx, http.MethodGet, "/miners", nil, nil, &miners); err != nil {
+		return nil, err
+	}
+	return miners, nil
+}
@@ -0,0 +1,210 @@
+// Package client est le client Go des API HTTP (/api/v1) et WebSocket (/ws) de BkC.
+//
+// Un client s'authentifie soit avec un jeton d'API personnel (SetToken), soit par une session
+// ouverte avec Login, dont le cookie et le jeton CSRF sont conservés par le client :
+//
+//	c, err := client.New("http://localhost:8080", nil)
+//	if _, err := c.Login(ctx, "alice", "motdepasse", ""); err != nil { ... }
+//	token, err := c.CreateToken(ctx, "service", utils.ScopeReadChain, utils.ScopeSendMessages)
+//	c.SetToken(token.Token)
+package client
+
+import (
+	"BkC/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// apiPrefix est le préfixe des routes de l'API versionnée
+const apiPrefix = "/api/v1"
+
+// Codes d'erreur renvoyés par l'API (champ Code de APIError)
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeTooManyRequests      = "too_many_requests"
+	CodeSecondFactorRequired = "second_factor_required" // Login : le compte demande un code de double authentification
+	CodeInternal             = "internal_error"
+)
+
+// APIError est une erreur renvoyée par le serveur
+type APIError struct {
+	Status    int                      `json:"-"` // Code HTTP de la réponse
+	Code      string                   `json:"code"`
+	Message   string                   `json:"message"`
+	RequestID string                   `json:"requestId,omitempty"`
+	Details   []*utils.ValidationError `json:"details,omitempty"` // Erreurs par champ
+}
+
+// Error implémente l'interface error
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("bkc: HTTP %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("bkc: %s (%d): %s", e.Code, e.Status, e.Message)
+}
+
+// IsCode indique si err est une erreur de l'API portant ce code
+func IsCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Client appelle l'API d'un serveur BkC. Il peut être utilisé par plusieurs goroutines.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	token     string // Jeton d'API (Authorization: Bearer), prioritaire sur la session
+	csrfToken string // Jeton CSRF de la session, pour les requêtes modifiant l'état
+	language  string // Langue des messages d'erreur (Accept-Language)
+}
+
+// New crée un client pour le serveur baseURL (ex: "https://bkc.exemple.fr"). httpClient peut être
+// nil ; le client en utilise une copie, dotée d'un stockage de cookies s'il n'en a pas, qui ne suit
+// pas les redirections.
+func New(baseURL string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("adresse du serveur invalide: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("adresse du serveur invalide: schéma %q non supporté", u.Scheme)
+	}
+
+	var hc http.Client
+	if httpClient != nil {
+		hc = *httpClient
+	}
+	if hc.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		hc.Jar = jar
+	}
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Client{baseURL: u, httpClient: &hc}, nil
+}
+
+// SetToken choisit le jeton d'API utilisé pour authentifier les requêtes ("" pour utiliser la session)
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// SetLanguage choisit la langue des messages d'erreur et des événements ("fr" ou "en")
+func (c *Client) SetLanguage(lang string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.language = lang
+}
+
+// setCSRFToken mémorise le jeton CSRF de la session
+func (c *Client) setCSRFToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.csrfToken = token
+}
+
+// authHeaders ajoute l'authentification et la langue à une requête
+func (c *Client) authHeaders(header http.Header, method string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	} else if c.csrfToken != "" && method != http.MethodGet && method != http.MethodHead {
+		header.Set("X-CSRF-Token", c.csrfToken)
+	}
+	if c.language != "" {
+		header.Set("Accept-Language", c.language)
+	}
+}
+
+// url retourne l'URL absolue d'un chemin du serveur
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// do envoie une requête à l'API v1 et décode la réponse JSON dans out (qui peut être nil)
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(apiPrefix+path, query), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authHeaders(req.Header, method)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("réponse illisible (%s %s): %v", method, path, err)
+	}
+	return nil
+}
+
+// decodeError lit l'enveloppe d'erreur de l'API ; les autres réponses (proxy, routes hors de
+// l'API) donnent une APIError sans code
+func decodeError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var envelope struct {
+		Error *APIError `json:"error"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Error != nil {
+		envelope.Error.Status = resp.StatusCode
+		return envelope.Error
+	}
+
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{Status: resp.StatusCode, Message: message}
+}
@@ -0,0 +1,270 @@
+package client
+
+import (
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordedRequest est une requête reçue par le serveur de test
+type recordedRequest struct {
+	Method, Path, Query string
+	Header              http.Header
+	Body                string
+}
+
+// testServer répond à chaque requête avec status et body, et enregistre les requêtes reçues
+func testServer(t *testing.T, status int, body string) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Clone(), string(data)})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		ok      bool
+	}{
+		{"http://localhost:8080", true},
+		{"https://bkc.exemple.fr/", true},
+		{"ftp://bkc.exemple.fr", false},
+		{"localhost:8080", false},
+		{"http://[::1", false},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.baseURL, nil); (err == nil) != tt.ok {
+			t.Errorf("New(%q) : erreur %v", tt.baseURL, err)
+		}
+	}
+}
+
+func TestClientRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  string
+		call   func(ctx context.Context, c *Client) (interface{}, error)
+		method string
+		path   string
+		query  string
+		body   string // Corps JSON attendu, "" si aucun
+		want   string // Résultat décodé, au format JSON
+	}{
+		{"blocs", `{"total":3,"offset":1,"limit":2,"blocks":[]}`,
+			func(ctx context.Context, c *Client) (interface{}, error) { return c.Blocks(ctx, 1, 2) },
+			http.MethodGet, "/api/v1/blocks", "limit=2&offset=1", "", `{"total":3,"offset":1,"limit":2,"blocks":[]}`},
+		{"blocs sans limite", `{"total":0}`,
+			func(ctx context.Context, c *Client) (interface{}, error) { return c.Blocks(ctx, 0, 0) },
+			http.MethodGet, "/api/v1/blocks", "offset=0", "", `{"total":0,"offset":0,"limit":0,"blocks":null}`},
+		{"messages d'une conversation", `[]`,
+			func(ctx context.Context, c *Client) (interface{}, error) { return c.Messages(ctx, "bob") },
+			http.MethodGet, "/api/v1/messages", "with=bob", "", `[]`},
+		{"envoi de message", `{"id":"m1","sender":"alice","recipient":"bob","content":"salut"}`,
+			func(ctx context.Context, c *Client) (interface{}, error) {
+				m, err := c.SendMessage(ctx, "bob", "salut")
+				if err != nil {
+					return nil, err
+				}
+				return []string{m.ID, m.Recipient, m.Content}, nil
+			},
+			http.MethodPost, "/api/v1/messages", "", `{"content":"salut","recipient":"bob"}`, `["m1","bob","salut"]`},
+		{"création de jeton", `{"id":"t1","name":"service","scopes":["chain:read"],"token":"bkc_secret"}`,
+			func(ctx context.Context, c *Client) (interface{}, error) {
+				token, err := c.CreateToken(ctx, "service", utils.ScopeReadChain)
+				if err != nil {
+					return nil, err
+				}
+				return token.Token, nil
+			},
+			http.MethodPost, "/api/v1/tokens", "", `{"name":"service","scopes":["chain:read"]}`, `"bkc_secret"`},
+		{"révocation de jeton", ``,
+			func(ctx context.Context, c *Client) (interface{}, error) { return nil, c.RevokeToken(ctx, "a/b") },
+			http.MethodDelete, "/api/v1/tokens/a%2Fb", "", "", `null`},
+		{"appel JSON-RPC", `{"jsonrpc":"2.0","result":42,"id":1}`,
+			func(ctx context.Context, c *Client) (interface{}, error) {
+				var height int
+				err := c.Call(ctx, RPCChainGetHeight, nil, &height)
+				return height, err
+			},
+			http.MethodPost, "/api/v1/rpc", "", "", `42`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := testServer(t, http.StatusOK, tt.reply)
+			c, err := New(server.URL+"/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetToken("bkc_jeton")
+			c.SetLanguage("en")
+
+			result, err := tt.call(context.Background(), c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := json.Marshal(result); string(got) != tt.want {
+				t.Errorf("résultat %s, attendu %s", got, tt.want)
+			}
+
+			if len(*requests) != 1 {
+				t.Fatalf("%d requêtes, attendu 1", len(*requests))
+			}
+			r := (*requests)[0]
+			if r.Method != tt.method || r.Path != tt.path || r.Query != tt.query {
+				t.Errorf("requête %s %s?%s, attendu %s %s?%s", r.Method, r.Path, r.Query, tt.method, tt.path, tt.query)
+			}
+			if r.Header.Get("Authorization") != "Bearer bkc_jeton" || r.Header.Get("Accept-Language") != "en" {
+				t.Errorf("en-têtes %v", r.Header)
+			}
+			if tt.body != "" && strings.TrimSpace(r.Body) != tt.body {
+				t.Errorf("corps %s, attendu %s", r.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestClientSessionCSRF(t *testing.T) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, recordedRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone()})
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/session":
+			http.SetCookie(w, &http.Cookie{Name: "visiteur", Value: "v1", Path: "/"})
+			io.WriteString(w, `{"authenticated":false,"csrfToken":"csrf-visiteur"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/session":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+			io.WriteString(w, `{"authenticated":true,"username":"alice","role":"member","csrfToken":"csrf-session"}`)
+		case r.URL.Path == "/logout":
+			w.WriteHeader(http.StatusSeeOther)
+		default:
+			io.WriteString(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	session, err := c.Login(ctx, "alice", "motdepasse", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.Authenticated || session.Username != "alice" {
+		t.Errorf("session %+v", session)
+	}
+	if _, err := c.Tokens(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		request string
+		csrf    string // Jeton CSRF attendu
+		cookie  string // Cookie attendu
+	}{
+		{"GET /api/v1/session", "", ""},
+		{"POST /api/v1/session", "csrf-visiteur", "visiteur=v1"},
+		{"GET /api/v1/tokens", "", "session=s1"}, // Pas de jeton CSRF sur une lecture
+		{"POST /logout", "csrf-session", "session=s1"},
+	}
+	if len(requests) != len(tests) {
+		t.Fatalf("%d requêtes, attendu %d", len(requests), len(tests))
+	}
+	for i, tt := range tests {
+		r := requests[i]
+		if got := r.Method + " " + r.Path; got != tt.request {
+			t.Errorf("requête %d : %s, attendu %s", i, got, tt.request)
+			continue
+		}
+		if csrf := r.Header.Get("X-CSRF-Token"); csrf != tt.csrf {
+			t.Errorf("%s : jeton CSRF %q, attendu %q", tt.request, csrf, tt.csrf)
+		}
+		if cookie := r.Header.Get("Cookie"); !strings.Contains(cookie, tt.cookie) {
+			t.Errorf("%s : cookies %q sans %q", tt.request, cookie, tt.cookie)
+		}
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   APIError
+		fields []string // Champs des erreurs de validation
+	}{
+		{"enveloppe", http.StatusNotFound,
+			`{"error":{"code":"not_found","message":"bloc introuvable","requestId":"req-1"}}`,
+			APIError{Status: 404, Code: CodeNotFound, Message: "bloc introuvable", RequestID: "req-1"}, nil},
+		{"erreurs de validation", http.StatusBadRequest,
+			`{"error":{"code":"validation_failed","message":"message invalide","details":[{"field":"recipient","code":"required","message":"destinataire obligatoire"},{"field":"content","code":"too_long","message":"message trop long"}]}}`,
+			APIError{Status: 400, Code: CodeValidation, Message: "message invalide"}, []string{"recipient", "content"}},
+		{"limitation", http.StatusTooManyRequests,
+			`{"error":{"code":"too_many_requests","message":"réessayez dans 3 secondes"}}`,
+			APIError{Status: 429, Code: CodeTooManyRequests, Message: "réessayez dans 3 secondes"}, nil},
+		{"texte brut", http.StatusBadGateway, "proxy indisponible\n",
+			APIError{Status: 502, Message: "proxy indisponible"}, nil},
+		{"corps vide", http.StatusServiceUnavailable, "",
+			APIError{Status: 503, Message: "Service Unavailable"}, nil},
+		{"JSON sans enveloppe", http.StatusInternalServerError, `{"message":"erreur"}`,
+			APIError{Status: 500, Message: `{"message":"erreur"}`}, nil},
+	}
+	for _, tt := range tests {
+		server, _ := testServer(t, tt.status, tt.body)
+		c, err := New(server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.Block(context.Background(), 7)
+		apiErr, ok := err.(*APIError)
+		if !ok {
+			t.Errorf("%s : erreur %T %v, attendu *APIError", tt.name, err, err)
+			continue
+		}
+		if apiErr.Status != tt.want.Status || apiErr.Code != tt.want.Code || apiErr.Message != tt.want.Message || apiErr.RequestID != tt.want.RequestID {
+			t.Errorf("%s : %+v, attendu %+v", tt.name, apiErr, tt.want)
+		}
+		if len(apiErr.Details) != len(tt.fields) {
+			t.Errorf("%s : %d erreurs de validation, attendu %d", tt.name, len(apiErr.Details), len(tt.fields))
+		} else {
+			for i, field := range tt.fields {
+				if apiErr.Details[i].Field != field {
+					t.Errorf("%s : champ %q, attendu %q", tt.name, apiErr.Details[i].Field, field)
+				}
+			}
+		}
+		if tt.want.Code != "" && !IsCode(err, tt.want.Code) {
+			t.Errorf("%s : IsCode(%q) faux", tt.name, tt.want.Code)
+		}
+	}
+}
+
+func TestClientRPCError(t *testing.T) {
+	server, _ := testServer(t, http.StatusOK,
+		`{"jsonrpc":"2.0","error":{"code":-32004,"message":"bloc introuvable","data":{"code":"not_found"}},"id":1}`)
+	c, err := New(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Call(context.Background(), RPCChainGetBlockByIndex, map[string]int{"index": 99}, nil)
+	rpcErr, ok := err.(*RPCError)
+	if !ok || rpcErr.Code != RPCCodeNotFound || rpcErr.Data == nil || rpcErr.Data.Code != CodeNotFound {
+		t.Errorf("erreur %#v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package client
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Reconnexion du flux WebSocket
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+	eventsBuffer      = 64
+	wsPongWait        = 75 * time.Second // Le serveur envoie un ping toutes les 30 secondes
+	wsWriteWait       = 10 * time.Second
+)
+
+// ErrNotConnected est retournée par Subscription.SendMessage pendant une reconnexion
+var ErrNotConnected = errors.New("bkc: flux WebSocket non connecté")
+
+// Event est un événement reçu par WebSocket : *ConnectedEvent, *NewMessageEvent,
+// *MessageSentEvent, *BlockchainUpdateEvent, *ErrorEvent, *DisconnectedEvent ou *RawEvent
+type Event interface {
+	EventType() string
+}
+
+// ConnectedEvent est envoyé par le serveur à chaque connexion, y compris après une reconnexion
+type ConnectedEvent struct {
+	Message string
+	Time    time.Time
+}
+
+// NewMessageEvent annonce un message envoyé ou reçu par l'utilisateur, une fois son bloc miné
+// (sauf le dernier message envoyé par cette connexion)
+type NewMessageEvent struct {
+	Message blockchain.Message
+	Time    time.Time
+}
+
+// MessageSentEvent confirme la prise en compte d'un message envoyé par Subscription.SendMessage
+type MessageSentEvent struct {
+	ID   string // Identifiant du message
+	Time time.Time
+}
+
+// BlockchainUpdateEvent annonce un nouveau bloc
+type BlockchainUpdateEvent struct {
+	Index     int    `json:"block_index"`
+	Hash      string `json:"block_hash"`
+	Timestamp string `json:"timestamp"`
+	Time      time.Time
+}
+
+// ErrorEvent est une erreur signalée par le serveur ("error" ou "validation_error")
+type ErrorEvent struct {
+	Message string
+	Details []*utils.ValidationError // Erreurs par champ d'un message refusé
+	Time    time.Time
+}
+
+// DisconnectedEvent signale la perte de la connexion (ou l'échec d'une tentative) ; le client
+// se reconnecte après Retry
+type DisconnectedEvent struct {
+	Err   error
+	Retry time.Duration
+}
+
+// RawEvent est un événement d'un type non décodé par ce client (présence par exemple)
+type RawEvent struct {
+	Type string
+	Data json.RawMessage
+	Time time.Time
+}
+
+func (*ConnectedEvent) EventType() string        { return "connected" }
+func (*NewMessageEvent) EventType() string       { return "new_message" }
+func (*MessageSentEvent) EventType() string      { return "message_sent" }
+func (*BlockchainUpdateEvent) EventType() string { return "blockchain_update" }
+func (*ErrorEvent) EventType() string            { return "error" }
+func (*DisconnectedEvent) EventType() string     { return "disconnected" }
+func (e *RawEvent) EventType() string            { return e.Type }
+
+// serverMessage est un message du serveur, avant décodage de ses données
+type serverMessage struct {
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+	Time    time.Time       `json:"time"`
+	Success bool            `json:"success"`
+}
+
+// decodeEvent décode un message du serveur selon son type
+func decodeEvent(raw []byte) (Event, error) {
+	var msg serverMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch msg.Type {
+	case "connected":
+		event := &ConnectedEvent{Time: msg.Time}
+		err = json.Unmarshal(msg.Data, &event.Message)
+		return event, err
+
+	case "new_message":
+		event := &NewMessageEvent{Time: msg.Time}
+		err = json.Unmarshal(msg.Data, &event.Message)
+		return event, err
+
+	case "message_sent":
+		event := &MessageSentEvent{Time: msg.Time}
+		err = json.Unmarshal(msg.Data, &event.ID)
+		return event, err
+
+	case "blockchain_update":
+		event := &BlockchainUpdateEvent{}
+		err = json.Unmarshal(msg.Data, event)
+		event.Time = msg.Time
+		return event, err
+
+	case "error":
+		event := &ErrorEvent{Time: msg.Time}
+		err = json.Unmarshal(msg.Data, &event.Message)
+		return event, err
+
+	case "validation_error":
+		event := &ErrorEvent{Time: msg.Time}
+		if err = json.Unmarshal(msg.Data, &event.Details); err == nil {
+			messages := make([]string, len(event.Details))
+			for i, detail := range event.Details {
+				messages[i] = detail.Message
+			}
+			event.Message = strings.Join(messages, " ; ")
+		}
+		return event, err
+	}
+	return &RawEvent{Type: msg.Type, Data: msg.Data, Time: msg.Time}, nil
+}
+
+// Subscription reçoit les événements du flux WebSocket et se reconnecte automatiquement,
+// avec un délai doublé à chaque échec (de 1 à 30 secondes). Les événements doivent être lus
+// sans tarder : la lecture du flux attend que le canal ait de la place.
+type Subscription struct {
+	client *Client
+	events chan Event
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	conn *websocket.Conn // Connexion en cours (nil pendant une reconnexion)
+	err  error           // Erreur définitive, connue une fois Events fermé
+}
+
+// Subscribe ouvre le flux WebSocket avec l'authentification du client (jeton d'API ou session).
+// Il s'arrête à l'annulation de ctx, à l'appel de Close, ou si le serveur refuse
+// l'authentification (voir Err).
+func (c *Client) Subscribe(ctx context.Context) *Subscription {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Subscription{
+		client: c,
+		events: make(chan Event, eventsBuffer),
+		cancel: cancel,
+	}
+	go s.run(ctx)
+	return s
+}
+
+// Events retourne le canal des événements, fermé à l'arrêt de l'abonnement
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err retourne la cause de l'arrêt une fois Events fermé : nil après Close ou l'annulation du
+// contexte, une *APIError si le serveur a refusé la connexion
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close arrête l'abonnement
+func (s *Subscription) Close() {
+	s.cancel()
+}
+
+// SendMessage envoie un message par le flux ; la confirmation arrive dans un MessageSentEvent,
+// ou un ErrorEvent en cas de refus
+func (s *Subscription) SendMessage(recipient, content string) error {
+	data, err := json.Marshal(map[string]string{"recipient": recipient, "content": content})
+	if err != nil {
+		return err
+	}
+	return s.write(map[string]string{"type": "send_message", "data": string(data)})
+}
+
+// write envoie un message du client sur la connexion en cours
+func (s *Subscription) write(message interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return s.conn.WriteJSON(message)
+}
+
+// run maintient la connexion jusqu'à l'arrêt de l'abonnement
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.events)
+	defer s.cancel()
+
+	delay := minReconnectDelay
+	for {
+		conn, err := s.client.dialWebSocket(ctx)
+		if err == nil {
+			delay = minReconnectDelay
+			err = s.read(ctx, conn)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.Status == http.StatusUnauthorized || apiErr.Status == http.StatusForbidden) {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			return
+		}
+
+		if !s.emit(ctx, &DisconnectedEvent{Err: err, Retry: delay}) {
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// read transmet les événements d'une connexion jusqu'à sa fermeture
+func (s *Subscription) read(ctx context.Context, conn *websocket.Conn) error {
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer func() {
+		stop()
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(wsWriteWait))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		event, err := decodeEvent(raw)
+		if err != nil {
+			continue // Message illisible : ignoré, comme le fait le serveur
+		}
+		if !s.emit(ctx, event) {
+			return ctx.Err()
+		}
+	}
+}
+
+// emit transmet un événement, sauf si l'abonnement est arrêté entre-temps
+func (s *Subscription) emit(ctx context.Context, event Event) bool {
+	select {
+	case s.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dialWebSocket ouvre une connexion à /ws
+func (c *Client) dialWebSocket(ctx context.Context) (*websocket.Conn, error) {
+	u := *c.baseURL
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1) // http -> ws, https -> wss
+	u.Path += "/ws"
+
+	header := http.Header{}
+	c.authHeaders(header, http.MethodGet)
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 15 * time.Second,
+		Jar:              c.httpClient.Jar,
+	}
+	if transport, ok := c.httpClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode >= 400 {
+			defer resp.Body.Close()
+			return nil, decodeError(resp)
+		}
+		return nil, fmt.Errorf("connexion WebSocket impossible: %v", err)
+	}
+	return conn, nil
+}
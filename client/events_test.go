@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// nextEvent attend le prochain événement de l'abonnement
+func nextEvent(t *testing.T, s *Subscription) Event {
+	t.Helper()
+	select {
+	case event, ok := <-s.Events():
+		if !ok {
+			t.Fatalf("abonnement arrêté : %v", s.Err())
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("aucun événement reçu")
+		return nil
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // Type et contenu de l'événement décodé
+	}{
+		{`{"type":"connected","data":"bienvenue"}`, "connected bienvenue"},
+		{`{"type":"message_sent","data":"m1"}`, "message_sent m1"},
+		{`{"type":"new_message","data":{"id":"m2","sender":"bob","recipient":"alice","content":"salut"}}`, "new_message bob salut"},
+		{`{"type":"blockchain_update","data":{"block_index":4,"block_hash":"00ab"}}`, "blockchain_update 4 00ab"},
+		{`{"type":"error","data":"refusé"}`, "error refusé"},
+		{`{"type":"validation_error","data":[{"field":"recipient","message":"destinataire inconnu"},{"field":"content","message":"vide"}]}`, "error destinataire inconnu ; vide"},
+		{`{"type":"presence","data":{"online":["bob"]}}`, `presence {"online":["bob"]}`},
+	}
+	for _, tt := range tests {
+		event, err := decodeEvent([]byte(tt.raw))
+		if err != nil {
+			t.Errorf("%s : %v", tt.raw, err)
+			continue
+		}
+		var got string
+		switch e := event.(type) {
+		case *ConnectedEvent:
+			got = e.Message
+		case *MessageSentEvent:
+			got = e.ID
+		case *NewMessageEvent:
+			got = e.Message.Sender + " " + e.Message.Content
+		case *BlockchainUpdateEvent:
+			got = strconv.Itoa(e.Index) + " " + e.Hash
+		case *ErrorEvent:
+			got = e.Message
+		case *RawEvent:
+			got = string(e.Data)
+		}
+		if got = event.EventType() + " " + got; got != tt.want {
+			t.Errorf("%s : %q, attendu %q", tt.raw, got, tt.want)
+		}
+	}
+	if _, err := decodeEvent([]byte(`{"type":`)); err == nil {
+		t.Error("message illisible accepté")
+	}
+}
+
+func TestSubscriptionReconnects(t *testing.T) {
+	var upgrader websocket.Upgrader
+	var connections atomic.Int32
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws" || r.Header.Get("Authorization") != "Bearer bkc_jeton" {
+			http.Error(w, "requête inattendue", http.StatusBadRequest)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		n := connections.Add(1)
+		conn.WriteJSON(map[string]interface{}{"type": "connected", "data": "connexion"})
+		if n == 1 {
+			// Première connexion : un événement, puis coupure par le serveur
+			conn.WriteJSON(map[string]interface{}{"type": "new_message", "data": map[string]string{"id": "m1", "sender": "bob", "content": "salut"}})
+			return
+		}
+		// Connexion suivante : le client peut écrire
+		var message struct{ Type, Data string }
+		if conn.ReadJSON(&message) == nil {
+			received <- message.Type + " " + message.Data
+		}
+		conn.ReadMessage() // Jusqu'à la fermeture par le client
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetToken("bkc_jeton")
+	s := c.Subscribe(context.Background())
+	defer s.Close()
+
+	if event, ok := nextEvent(t, s).(*ConnectedEvent); !ok || event.Message != "connexion" {
+		t.Fatalf("premier événement %+v", event)
+	}
+	if event, ok := nextEvent(t, s).(*NewMessageEvent); !ok || event.Message.ID != "m1" {
+		t.Fatalf("deuxième événement %+v", event)
+	}
+	disconnected, ok := nextEvent(t, s).(*DisconnectedEvent)
+	if !ok || disconnected.Err == nil || disconnected.Retry != minReconnectDelay {
+		t.Fatalf("événement de déconnexion attendu, reçu %+v", disconnected)
+	}
+	if err := s.SendMessage("bob", "pendant la coupure"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("envoi pendant la reconnexion : %v", err)
+	}
+
+	if _, ok := nextEvent(t, s).(*ConnectedEvent); !ok {
+		t.Fatal("pas de reconnexion")
+	}
+	if err := s.SendMessage("bob", "de retour"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if want := `send_message {"content":"de retour","recipient":"bob"}`; got != want {
+			t.Errorf("message reçu %q, attendu %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message non reçu par le serveur")
+	}
+	if n := connections.Load(); n != 2 {
+		t.Errorf("%d connexions, attendu 2", n)
+	}
+
+	s.Close()
+	for range s.Events() {
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Err() = %v après Close", err)
+	}
+}
+
+func TestSubscriptionStopsWhenRefused(t *testing.T) {
+	tests := []struct {
+		status int
+		stops  bool // Abonnement arrêté sans reconnexion
+	}{
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, true},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tt.status)
+			io.WriteString(w, `{"error":{"code":"refus","message":"connexion refusée"}}`)
+		}))
+		c, err := New(server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := c.Subscribe(context.Background())
+
+		event, open := <-s.Events()
+		if tt.stops {
+			var apiErr *APIError
+			if open || !errors.As(s.Err(), &apiErr) || apiErr.Status != tt.status || apiErr.Code != "refus" {
+				t.Errorf("%d : événement %+v, Err() = %v", tt.status, event, s.Err())
+			}
+		} else if disconnected, ok := event.(*DisconnectedEvent); !ok || !IsCode(disconnected.Err, "refus") {
+			t.Errorf("%d : événement %+v, attendu une déconnexion", tt.status, event)
+		}
+		s.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Méthodes JSON-RPC du serveur (POST /api/v1/rpc, ou WebSocket /ws)
+const (
+	RPCChainGetHeight       = "chain_getHeight"       // Sans paramètre ; résultat : nombre de blocs
+	RPCChainGetBlockByIndex = "chain_getBlockByIndex" // {"index"} ; résultat : blockchain.Block
+	RPCChainGetBlockByHash  = "chain_getBlockByHash"  // {"hash"} ; résultat : blockchain.Block
+	RPCMsgSend              = "msg_send"              // {"recipient", "content"} ; résultat : blockchain.Message
+	RPCMsgList              = "msg_list"              // {"with"} (facultatif) ; résultat : []blockchain.Message
+	RPCMiningSubmit         = "mining_submit"         // {"data"} ; résultat : blockchain.Block, une fois miné
+	RPCNodeStats            = "node_stats"            // Sans paramètre ; résultat : Stats
+)
+
+// Codes d'erreur JSON-RPC (champ Code de RPCError)
+const (
+	RPCCodeParseError     = -32700
+	RPCCodeInvalidRequest = -32600
+	RPCCodeMethodNotFound = -32601
+	RPCCodeInvalidParams  = -32602
+	RPCCodeInternalError  = -32603
+	RPCCodeForbidden      = -32003
+	RPCCodeNotFound       = -32004
+	RPCCodeServerBusy     = -32005 // WebSocket : trop d'appels en cours, réessayer après les réponses attendues
+)
+
+// RPCError est une erreur JSON-RPC renvoyée par le serveur
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *struct {
+		Code    string                   `json:"code"` // Code d'erreur de l'API (CodeNotFound, ...)
+		Details []*utils.ValidationError `json:"details,omitempty"`
+	} `json:"data,omitempty"`
+}
+
+// Error implémente l'interface error
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("bkc: JSON-RPC %d: %s", e.Code, e.Message)
+}
+
+// rpcID numérote les appels du client
+var rpcID atomic.Int64
+
+// Call appelle une méthode JSON-RPC et décode son résultat dans result (qui peut être nil).
+// params est un objet (paramètres nommés), un tableau (positionnels) ou nil.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	request := map[string]interface{}{"jsonrpc": "2.0", "method": method, "id": rpcID.Add(1)}
+	if params != nil {
+		request["params"] = params
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/rpc", nil, request, &response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("résultat illisible (%s): %v", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Événements des webhooks
+const (
+	WebhookBlockNew   = "block.new"   // Données : le bloc (blockchain.Block)
+	WebhookMessageNew = "message.new" // Données : le message (blockchain.Message)
+	WebhookChainReorg = "chain.reorg" // Données : ChainReorg
+	WebhookPing       = "ping"        // Données : {"webhookId": ...}
+)
+
+// En-têtes des requêtes envoyées par le serveur aux webhooks
+const (
+	WebhookEventHeader     = "X-BkC-Event"
+	WebhookDeliveryHeader  = "X-BkC-Delivery"
+	WebhookSignatureHeader = "X-BkC-Signature"
+)
+
+// DefaultWebhookTolerance est l'écart accepté par ParseWebhook entre l'horodatage de la
+// signature et l'horloge locale
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// Erreurs de vérification des requêtes reçues par un webhook
+var (
+	ErrWebhookSignature = errors.New("bkc: signature du webhook invalide")
+	ErrWebhookExpired   = errors.New("bkc: signature du webhook expirée")
+)
+
+// Webhook est un webhook enregistré
+type Webhook struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	Global      bool      `json:"global"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	CreatedAt   time.Time `json:"createdAt"`
+	DeadLetters int       `json:"deadLetters"`
+	Secret      string    `json:"secret,omitempty"` // Secret de signature, renvoyé uniquement par CreateWebhook
+}
+
+// WebhookDelivery est l'envoi d'un événement à un webhook
+type WebhookDelivery struct {
+	ID          string          `json:"id"`
+	WebhookID   string          `json:"webhookId"`
+	Event       string          `json:"event"`
+	State       string          `json:"state"` // pending, delivered ou failed
+	Attempts    int             `json:"attempts"`
+	StatusCode  int             `json:"statusCode,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	LastAttempt *time.Time      `json:"lastAttempt,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// WebhookEvent est le corps d'une requête reçue par un webhook
+type WebhookEvent struct {
+	ID        string          `json:"id"` // Identifiant de la livraison, identique pour toutes les tentatives
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ChainHead identifie le dernier bloc d'une chaîne
+type ChainHead struct {
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// ChainReorg sont les données d'un événement chain.reorg : la chaîne a été remplacée et doit
+// être relue
+type ChainReorg struct {
+	Previous ChainHead `json:"previous"`
+	Current  ChainHead `json:"current"`
+}
+
+// Webhooks liste les webhooks de l'utilisateur (tous les webhooks pour un administrateur)
+func (c *Client) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var hooks []Webhook
+	if err := c.do(ctx, http.MethodGet, "/webhooks", nil, nil, &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// CreateWebhook enregistre un webhook. Le secret de signature (champ Secret) n'est renvoyé
+// qu'à cette occasion. global est réservé aux administrateurs.
+func (c *Client) CreateWebhook(ctx context.Context, rawURL string, global bool, events ...string) (*Webhook, error) {
+	var hook Webhook
+	body := map[string]interface{}{"url": rawURL, "events": events, "global": global}
+	if err := c.do(ctx, http.MethodPost, "/webhooks", nil, body, &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteWebhook supprime un webhook
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/webhooks/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// PingWebhook envoie un événement de test au webhook
+func (c *Client) PingWebhook(ctx context.Context, id string) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := c.do(ctx, http.MethodPost, "/webhooks/"+url.PathEscape(id)+"/ping", nil, nil, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// WebhookDeliveries retourne les livraisons récentes d'un webhook, de la plus récente à la plus ancienne
+func (c *Client) WebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	return c.webhookDeliveryList(ctx, http.MethodGet, id, "/deliveries")
+}
+
+// WebhookDeadLetters retourne les livraisons abandonnées d'un webhook
+func (c *Client) WebhookDeadLetters(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	return c.webhookDeliveryList(ctx, http.MethodGet, id, "/dead-letters")
+}
+
+// RedeliverWebhook relance les livraisons abandonnées d'un webhook
+func (c *Client) RedeliverWebhook(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	return c.webhookDeliveryList(ctx, http.MethodPost, id, "/dead-letters/redeliver")
+}
+
+// webhookDeliveryList appelle une route retournant une liste de livraisons
+func (c *Client) webhookDeliveryList(ctx context.Context, method, id, suffix string) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	if err := c.do(ctx, method, "/webhooks/"+url.PathEscape(id)+suffix, nil, nil, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// VerifyWebhookSignature vérifie l'en-tête X-BkC-Signature ("t=<horodatage>,v1=<hmac>") d'une
+// requête reçue par un webhook. tolerance borne l'âge de la signature (0 : pas de limite).
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	valid := false
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		valid = valid || (err == nil && hmac.Equal(decoded, expected))
+	}
+	if !valid {
+		return ErrWebhookSignature
+	}
+	if tolerance > 0 && math.Abs(float64(time.Now().Unix()-unix)) > tolerance.Seconds() {
+		return ErrWebhookExpired
+	}
+	return nil
+}
+
+// ParseWebhook lit et vérifie une requête reçue par un webhook (signature de moins de
+// DefaultWebhookTolerance). Le destinataire doit répondre 2xx rapidement ; les livraisons déjà
+// traitées se reconnaissent à leur ID.
+func ParseWebhook(r *http.Request, secret string) (*WebhookEvent, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyWebhookSignature(secret, r.Header.Get(WebhookSignatureHeader), body, DefaultWebhookTolerance); err != nil {
+		return nil, err
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("bkc: corps du webhook illisible: %v", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,550 @@
+// Package config regroupe la configuration du serveur : valeurs par défaut, fichier JSON,
+// variables d'environnement puis options de la ligne de commande, par ordre de priorité croissante.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultFile est le fichier de configuration lu s'il existe et qu'aucun autre n'est indiqué
+const DefaultFile = "bkc.json"
+
+// Bornes acceptées pour les difficultés de minage
+const (
+	MinDifficulty = 1
+	MaxDifficulty = 8
+)
+
+// Bornes acceptées pour la livraison des webhooks
+const (
+	MaxWebhookAttempts = 20
+	MaxWebhookTimeout  = 120 // Secondes
+)
+
+// Config contient l'ensemble des réglages du serveur
+type Config struct {
+	Addr           string     `json:"addr"`           // Adresse d'écoute (ex: ":8080", "127.0.0.1:9000")
+	OpenBrowser    bool       `json:"openBrowser"`    // Ouvrir le navigateur au démarrage
+	DataDir        string     `json:"dataDir"`        // Répertoire des fichiers de données (chemins relatifs)
+	Dev            bool       `json:"dev"`            // Mode développement : modèles et fichiers statiques relus depuis le disque
+	TemplatesDir   string     `json:"templatesDir"`   // Répertoire des modèles HTML (mode développement)
+	StaticDir      string     `json:"staticDir"`      // Répertoire des fichiers statiques (mode développement)
+	Files          Files      `json:"files"`          // Noms des fichiers de données
+	Difficulty     Difficulty `json:"difficulty"`     // Difficultés de la preuve de travail
+	AllowedOrigins []string   `json:"allowedOrigins"` // Origines autorisées (par défaut, l'URL locale du serveur)
+	TrustedProxies []string   `json:"trustedProxies"` // Proxies dont l'en-tête de transfert est cru
+	ProxyHeader    string     `json:"proxyHeader"`    // En-tête de transfert écrit par ces proxies
+	GeoIPDatabases []string   `json:"geoipDatabases"` // Bases de géolocalisation (.mmdb ou CSV)
+	SMTP           SMTP       `json:"smtp"`           // Envoi des e-mails
+	Log            Log        `json:"log"`            // Journalisation
+	TLS            TLS        `json:"tls"`            // HTTPS
+	Webhooks       Webhooks   `json:"webhooks"`       // Notifications HTTP des événements de la chaîne
+}
+
+// Files contient les noms des fichiers de données, relatifs à DataDir sauf s'ils sont absolus
+type Files struct {
+	Blockchain   string `json:"blockchain"`
+	Users        string `json:"users"`
+	Sessions     string `json:"sessions"`
+	Tokens       string `json:"tokens"`
+	RetiredUsers string `json:"retiredUsers"`
+	Webhooks     string `json:"webhooks"`
+	Log          string `json:"log"`
+}
+
+// Difficulty contient le nombre de zéros exigés en tête du hash selon le type de bloc
+type Difficulty struct {
+	Genesis  int `json:"genesis"`  // Bloc genesis
+	Mining   int `json:"mining"`   // Blocs minés par les utilisateurs
+	Messages int `json:"messages"` // Messages entre utilisateurs
+	Signup   int `json:"signup"`   // Inscriptions
+	Audit    int `json:"audit"`    // Actions d'administration
+	Visits   int `json:"visits"`   // Connexions et déconnexions des visiteurs
+	Sessions int `json:"sessions"` // Sessions de visite prolongées
+}
+
+// SMTP contient les paramètres du serveur d'envoi des e-mails
+type SMTP struct {
+	Addr     string `json:"addr"`
+	From     string `json:"from"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Log contient les réglages de la journalisation. Le journal est écrit sur la sortie d'erreur
+// et, au format JSON, dans le fichier Files.Log.
+type Log struct {
+	Level      string `json:"level"`      // Niveau minimal : debug, info, warn ou error
+	Format     string `json:"format"`     // Format de la sortie d'erreur : text ou json
+	MaxSizeMB  int    `json:"maxSizeMB"`  // Taille au-delà de laquelle le fichier est archivé (0 : jamais)
+	MaxBackups int    `json:"maxBackups"` // Nombre d'archives conservées
+}
+
+// TLS contient les réglages HTTPS. Sans certificat fourni, un certificat autosigné est généré
+// et conservé dans CertFile / KeyFile pour un usage local.
+type TLS struct {
+	Enabled      bool     `json:"enabled"`      // Servir en HTTPS sur Addr
+	CertFile     string   `json:"certFile"`     // Certificat PEM (chaîne complète), relatif à DataDir
+	KeyFile      string   `json:"keyFile"`      // Clé privée PEM, relative à DataDir
+	SelfSigned   bool     `json:"selfSigned"`   // Générer un certificat autosigné si les fichiers sont absents
+	Hosts        []string `json:"hosts"`        // Noms et adresses du certificat autosigné
+	RedirectAddr string   `json:"redirectAddr"` // Adresse HTTP redirigeant vers HTTPS ("" : aucune)
+}
+
+// Webhooks contient les réglages de livraison des webhooks
+type Webhooks struct {
+	MaxAttempts    int  `json:"maxAttempts"`    // Tentatives avant de classer une livraison en échec
+	TimeoutSeconds int  `json:"timeoutSeconds"` // Délai de réponse du destinataire
+	AllowPrivate   bool `json:"allowPrivate"`   // Autoriser les adresses locales et privées pour tous les utilisateurs
+}
+
+// SlogLevel retourne le niveau minimal de journalisation (info si le niveau est invalide)
+func (l Log) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Default retourne la configuration par défaut, identique au comportement historique du serveur
+func Default() *Config {
+	return &Config{
+		Addr:         ":8080",
+		OpenBrowser:  true,
+		DataDir:      ".",
+		TemplatesDir: "templates",
+		StaticDir:    "static",
+		ProxyHeader:  "X-Forwarded-For",
+		Files: Files{
+			Blockchain:   "blockchain_data.json",
+			Users:        "users.json",
+			Sessions:     "sessions.json",
+			Tokens:       "tokens.json",
+			RetiredUsers: "retired_users.json",
+			Webhooks:     "webhooks.json",
+			Log:          "server.log",
+		},
+		Difficulty: Difficulty{
+			Genesis:  4,
+			Mining:   4,
+			Messages: 4,
+			Signup:   3,
+			Audit:    3,
+			Visits:   2,
+			Sessions: 4,
+		},
+		Log: Log{
+			Level:      "info",
+			Format:     "text",
+			MaxSizeMB:  10,
+			MaxBackups: 5,
+		},
+		TLS: TLS{
+			CertFile:   "tls/cert.pem",
+			KeyFile:    "tls/key.pem",
+			SelfSigned: true,
+			Hosts:      []string{"localhost", "127.0.0.1", "::1"},
+		},
+		Webhooks: Webhooks{
+			MaxAttempts:    6,
+			TimeoutSeconds: 10,
+		},
+	}
+}
+
+// Load construit la configuration à partir des valeurs par défaut, du fichier de configuration,
+// des variables d'environnement puis des options de args (sans le nom du programme).
+// La configuration obtenue est validée.
+func Load(args []string) (*Config, error) {
+	cfg, _, err := LoadCommand("bkc", args, nil)
+	return cfg, err
+}
+
+// LoadCommand fonctionne comme Load pour une sous-commande de la ligne de commande : bind déclare
+// ses options propres (qui peut être nil) à côté des options de configuration, et les arguments
+// restant après les options sont retournés.
+func LoadCommand(name string, args []string, bind func(fs *flag.FlagSet)) (*Config, []string, error) {
+	// Premier passage : trouver le fichier de configuration
+	configFile := os.Getenv("BKC_CONFIG")
+	explicit := configFile != ""
+	probe := flag.NewFlagSet(name, flag.ContinueOnError)
+	probe.SetOutput(discard{})
+	bindFlags(probe, Default(), &configFile)
+	if bind != nil {
+		bind(probe)
+	}
+	if err := probe.Parse(args); err != nil && !errors.Is(err, flag.ErrHelp) {
+		return nil, nil, err
+	}
+	probe.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+	if configFile == "" {
+		configFile = DefaultFile
+	}
+
+	cfg := Default()
+	if err := cfg.loadFile(configFile, explicit); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, nil, err
+	}
+
+	// Second passage : les options de la ligne de commande l'emportent
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	bindFlags(flags, cfg, &configFile)
+	if bind != nil {
+		bind(flags)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// discard ignore les messages du premier passage (affichés lors du second)
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+// listFlag est une option contenant une liste séparée par des virgules
+type listFlag struct{ values *[]string }
+
+func (l listFlag) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l listFlag) Set(value string) error {
+	*l.values = splitList(value)
+	return nil
+}
+
+// bindFlags déclare les options de la ligne de commande sur les champs de cfg
+func bindFlags(fs *flag.FlagSet, cfg *Config, configFile *string) {
+	fs.StringVar(configFile, "config", *configFile, "fichier de configuration JSON (défaut: "+DefaultFile+" s'il existe)")
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "adresse d'écoute du serveur")
+	fs.BoolVar(&cfg.OpenBrowser, "open-browser", cfg.OpenBrowser, "ouvrir le navigateur au démarrage")
+	fs.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "répertoire des fichiers de données")
+	fs.BoolVar(&cfg.Dev, "dev", cfg.Dev, "mode développement : relire les modèles et fichiers statiques depuis le disque")
+	fs.StringVar(&cfg.TemplatesDir, "templates-dir", cfg.TemplatesDir, "répertoire des modèles HTML")
+	fs.StringVar(&cfg.StaticDir, "static-dir", cfg.StaticDir, "répertoire des fichiers statiques")
+	fs.StringVar(&cfg.Files.Log, "log-file", cfg.Files.Log, "fichier journal (JSON, archivé au-delà de log.maxSizeMB)")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "niveau de journalisation (debug, info, warn, error)")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "format du journal sur la sortie d'erreur (text, json)")
+	fs.BoolVar(&cfg.TLS.Enabled, "tls", cfg.TLS.Enabled, "servir en HTTPS (certificat autosigné généré si tls.certFile est absent)")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert", cfg.TLS.CertFile, "certificat TLS (PEM)")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key", cfg.TLS.KeyFile, "clé privée TLS (PEM)")
+	fs.StringVar(&cfg.TLS.RedirectAddr, "tls-redirect-addr", cfg.TLS.RedirectAddr, "adresse HTTP redirigeant vers HTTPS (ex: \":80\")")
+	fs.IntVar(&cfg.Difficulty.Mining, "difficulty", cfg.Difficulty.Mining, "difficulté des blocs minés")
+	fs.IntVar(&cfg.Difficulty.Messages, "message-difficulty", cfg.Difficulty.Messages, "difficulté des blocs de messages")
+	fs.Var(listFlag{&cfg.AllowedOrigins}, "allowed-origins", "origines autorisées, séparées par des virgules")
+	fs.Var(listFlag{&cfg.TrustedProxies}, "trusted-proxies", "proxies de confiance (CIDR ou adresses), séparés par des virgules")
+	fs.StringVar(&cfg.ProxyHeader, "proxy-header", cfg.ProxyHeader, "en-tête de transfert écrit par les proxies de confiance (X-Forwarded-For, Forwarded, X-Real-IP...)")
+	fs.Var(listFlag{&cfg.GeoIPDatabases}, "geoip-db", "bases de géolocalisation (.mmdb ou CSV), séparées par des virgules")
+}
+
+// loadFile lit le fichier de configuration. Un fichier absent n'est une erreur que s'il a été demandé.
+func (c *Config) loadFile(path string, required bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return fmt.Errorf("fichier de configuration: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields() // Signaler les fautes de frappe dans les noms de réglages
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("fichier de configuration %s: %v", path, err)
+	}
+	return nil
+}
+
+// applyEnv applique les variables d'environnement BKC_*
+func (c *Config) applyEnv() error {
+	var errs []error
+	str := func(target *string, name string) {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+	list := func(target *[]string, name string) {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = splitList(value)
+		}
+	}
+	integer := func(target *int, name string) {
+		if value, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: entier attendu, %q reçu", name, value))
+				return
+			}
+			*target = n
+		}
+	}
+	boolean := func(target *bool, name string) {
+		if value, ok := os.LookupEnv(name); ok {
+			b, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: booléen attendu, %q reçu", name, value))
+				return
+			}
+			*target = b
+		}
+	}
+
+	str(&c.Addr, "BKC_ADDR")
+	boolean(&c.OpenBrowser, "BKC_OPEN_BROWSER")
+	str(&c.DataDir, "BKC_DATA_DIR")
+	boolean(&c.Dev, "BKC_DEV")
+	str(&c.TemplatesDir, "BKC_TEMPLATES_DIR")
+	str(&c.StaticDir, "BKC_STATIC_DIR")
+
+	str(&c.Files.Blockchain, "BKC_BLOCKCHAIN_FILE")
+	str(&c.Files.Users, "BKC_USERS_FILE")
+	str(&c.Files.Sessions, "BKC_SESSIONS_FILE")
+	str(&c.Files.Tokens, "BKC_TOKENS_FILE")
+	str(&c.Files.RetiredUsers, "BKC_RETIRED_USERS_FILE")
+	str(&c.Files.Webhooks, "BKC_WEBHOOKS_FILE")
+	str(&c.Files.Log, "BKC_LOG_FILE")
+	str(&c.Log.Level, "BKC_LOG_LEVEL")
+	str(&c.Log.Format, "BKC_LOG_FORMAT")
+	integer(&c.Log.MaxSizeMB, "BKC_LOG_MAX_SIZE_MB")
+	integer(&c.Log.MaxBackups, "BKC_LOG_MAX_BACKUPS")
+
+	boolean(&c.TLS.Enabled, "BKC_TLS")
+	str(&c.TLS.CertFile, "BKC_TLS_CERT")
+	str(&c.TLS.KeyFile, "BKC_TLS_KEY")
+	boolean(&c.TLS.SelfSigned, "BKC_TLS_SELF_SIGNED")
+	list(&c.TLS.Hosts, "BKC_TLS_HOSTS")
+	str(&c.TLS.RedirectAddr, "BKC_TLS_REDIRECT_ADDR")
+
+	integer(&c.Difficulty.Genesis, "BKC_GENESIS_DIFFICULTY")
+	integer(&c.Difficulty.Mining, "BKC_DIFFICULTY")
+	integer(&c.Difficulty.Messages, "BKC_MESSAGE_DIFFICULTY")
+	integer(&c.Difficulty.Signup, "BKC_SIGNUP_DIFFICULTY")
+	integer(&c.Difficulty.Audit, "BKC_AUDIT_DIFFICULTY")
+	integer(&c.Difficulty.Visits, "BKC_VISIT_DIFFICULTY")
+	integer(&c.Difficulty.Sessions, "BKC_SESSION_DIFFICULTY")
+
+	list(&c.AllowedOrigins, "BKC_ALLOWED_ORIGINS")
+	list(&c.TrustedProxies, "BKC_TRUSTED_PROXIES")
+	str(&c.ProxyHeader, "BKC_PROXY_HEADER")
+	list(&c.GeoIPDatabases, "BKC_GEOIP_DB")
+
+	str(&c.SMTP.Addr, "BKC_SMTP_ADDR")
+	str(&c.SMTP.From, "BKC_SMTP_FROM")
+	str(&c.SMTP.Username, "BKC_SMTP_USER")
+	str(&c.SMTP.Password, "BKC_SMTP_PASSWORD")
+
+	integer(&c.Webhooks.MaxAttempts, "BKC_WEBHOOK_MAX_ATTEMPTS")
+	integer(&c.Webhooks.TimeoutSeconds, "BKC_WEBHOOK_TIMEOUT")
+	boolean(&c.Webhooks.AllowPrivate, "BKC_WEBHOOK_ALLOW_PRIVATE")
+
+	return errors.Join(errs...)
+}
+
+// splitList découpe une liste séparée par des virgules en ignorant les entrées vides
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate vérifie la cohérence de la configuration et retourne toutes les erreurs trouvées
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, port, err := net.SplitHostPort(c.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr: adresse d'écoute invalide %q (ex: \":8080\")", c.Addr))
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		errs = append(errs, fmt.Errorf("addr: port invalide %q", port))
+	}
+
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("dataDir: répertoire obligatoire"))
+	}
+	// Hors mode développement, les modèles et fichiers statiques sont intégrés au binaire
+	if c.Dev {
+		for _, dir := range []struct{ name, path string }{
+			{"templatesDir", c.TemplatesDir},
+			{"staticDir", c.StaticDir},
+		} {
+			if info, err := os.Stat(dir.path); err != nil || !info.IsDir() {
+				errs = append(errs, fmt.Errorf("%s: répertoire introuvable %q", dir.name, dir.path))
+			}
+		}
+	}
+
+	for _, file := range []struct{ name, path string }{
+		{"files.blockchain", c.Files.Blockchain},
+		{"files.users", c.Files.Users},
+		{"files.sessions", c.Files.Sessions},
+		{"files.tokens", c.Files.Tokens},
+		{"files.retiredUsers", c.Files.RetiredUsers},
+		{"files.webhooks", c.Files.Webhooks},
+		{"files.log", c.Files.Log},
+	} {
+		if strings.TrimSpace(file.path) == "" {
+			errs = append(errs, fmt.Errorf("%s: nom de fichier obligatoire", file.name))
+		}
+	}
+
+	for _, d := range []struct {
+		name  string
+		value int
+	}{
+		{"difficulty.genesis", c.Difficulty.Genesis},
+		{"difficulty.mining", c.Difficulty.Mining},
+		{"difficulty.messages", c.Difficulty.Messages},
+		{"difficulty.signup", c.Difficulty.Signup},
+		{"difficulty.audit", c.Difficulty.Audit},
+		{"difficulty.visits", c.Difficulty.Visits},
+		{"difficulty.sessions", c.Difficulty.Sessions},
+	} {
+		if d.value < MinDifficulty || d.value > MaxDifficulty {
+			errs = append(errs, fmt.Errorf("%s: %d hors de l'intervalle [%d, %d]", d.name, d.value, MinDifficulty, MaxDifficulty))
+		}
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("allowedOrigins: origine invalide %q (ex: \"https://exemple.fr\")", origin))
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		entry := strings.TrimSpace(proxy)
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			errs = append(errs, fmt.Errorf("trustedProxies: CIDR ou adresse invalide %q (ex: \"10.0.0.0/8\")", proxy))
+		}
+	}
+
+	if c.SMTP.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.SMTP.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("smtp.addr: adresse invalide %q (ex: \"smtp.exemple.fr:587\")", c.SMTP.Addr))
+		}
+		if c.SMTP.From == "" {
+			errs = append(errs, errors.New("smtp.from: expéditeur obligatoire avec smtp.addr"))
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: niveau invalide %q (debug, info, warn ou error)", c.Log.Level))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("log.format: format invalide %q (text ou json)", c.Log.Format))
+	}
+	if c.Log.MaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("log.maxSizeMB: %d ne peut pas être négatif", c.Log.MaxSizeMB))
+	}
+	if c.Log.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log.maxBackups: %d ne peut pas être négatif", c.Log.MaxBackups))
+	}
+
+	if c.Webhooks.MaxAttempts < 1 || c.Webhooks.MaxAttempts > MaxWebhookAttempts {
+		errs = append(errs, fmt.Errorf("webhooks.maxAttempts: %d hors de l'intervalle [1, %d]", c.Webhooks.MaxAttempts, MaxWebhookAttempts))
+	}
+	if c.Webhooks.TimeoutSeconds < 1 || c.Webhooks.TimeoutSeconds > MaxWebhookTimeout {
+		errs = append(errs, fmt.Errorf("webhooks.timeoutSeconds: %d hors de l'intervalle [1, %d]", c.Webhooks.TimeoutSeconds, MaxWebhookTimeout))
+	}
+
+	if c.TLS.Enabled {
+		errs = append(errs, c.validateTLS()...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateTLS vérifie les réglages HTTPS
+func (c *Config) validateTLS() []error {
+	var errs []error
+
+	if strings.TrimSpace(c.TLS.CertFile) == "" || strings.TrimSpace(c.TLS.KeyFile) == "" {
+		errs = append(errs, errors.New("tls.certFile, tls.keyFile: fichiers obligatoires"))
+	} else if !c.TLS.SelfSigned {
+		for _, file := range []struct{ name, path string }{
+			{"tls.certFile", c.TLS.CertFile},
+			{"tls.keyFile", c.TLS.KeyFile},
+		} {
+			if _, err := os.Stat(c.DataPath(file.path)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: fichier introuvable %q (ou activer tls.selfSigned)", file.name, c.DataPath(file.path)))
+			}
+		}
+	}
+	if c.TLS.SelfSigned && len(c.TLS.Hosts) == 0 {
+		errs = append(errs, errors.New("tls.hosts: au moins un nom est nécessaire au certificat autosigné"))
+	}
+
+	if c.TLS.RedirectAddr != "" {
+		if _, port, err := net.SplitHostPort(c.TLS.RedirectAddr); err != nil {
+			errs = append(errs, fmt.Errorf("tls.redirectAddr: adresse invalide %q (ex: \":80\")", c.TLS.RedirectAddr))
+		} else if _, addrPort, _ := net.SplitHostPort(c.Addr); port == addrPort {
+			errs = append(errs, fmt.Errorf("tls.redirectAddr: le port %s est déjà celui de addr", port))
+		}
+	}
+	return errs
+}
+
+// DataPath retourne le chemin d'un fichier de données, relatif à DataDir s'il n'est pas absolu
+func (c *Config) DataPath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(c.DataDir, file)
+}
+
+// LocalURL retourne l'URL locale du serveur, utilisée pour ouvrir le navigateur
+// et comme origine autorisée par défaut
+func (c *Config) LocalURL() string {
+	scheme := "http"
+	if c.TLS.Enabled {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return scheme + "://localhost:8080"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// Origins retourne les origines autorisées, ou l'URL locale si aucune n'est configurée
+func (c *Config) Origins() []string {
+	if len(c.AllowedOrigins) > 0 {
+		return c.AllowedOrigins
+	}
+	return []string{c.LocalURL()}
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile crée un fichier de configuration dans dir et retourne son chemin
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// chdir place le test dans dir et rétablit le répertoire courant à la fin
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string            // Contenu de bkc.json, "" pour aucun fichier
+		env  map[string]string // Variables d'environnement ({dir} : répertoire du test)
+		args []string          // Options ({dir} : répertoire du test)
+		get  func(*Config) string
+		want string
+	}{
+		{"valeur par défaut", "", nil, nil,
+			func(c *Config) string { return c.Addr }, ":8080"},
+		{"fichier", `{"addr":":9000"}`, nil, nil,
+			func(c *Config) string { return c.Addr }, ":9000"},
+		{"environnement sur fichier", `{"addr":":9000"}`, map[string]string{"BKC_ADDR": ":9100"}, nil,
+			func(c *Config) string { return c.Addr }, ":9100"},
+		{"option sur environnement", `{"addr":":9000"}`, map[string]string{"BKC_ADDR": ":9100"}, []string{"-addr", ":9200"},
+			func(c *Config) string { return c.Addr }, ":9200"},
+		{"option sur fichier", `{"difficulty":{"mining":5}}`, nil, []string{"-difficulty", "6"},
+			func(c *Config) string { return strings.Repeat("0", c.Difficulty.Mining) }, "000000"},
+		{"fichier partiel", `{"difficulty":{"mining":5}}`, nil, nil,
+			func(c *Config) string { return strings.Repeat("0", c.Difficulty.Messages) }, "0000"},
+		{"booléen de l'environnement", `{"openBrowser":false}`, map[string]string{"BKC_OPEN_BROWSER": "true"}, nil,
+			func(c *Config) string { return map[bool]string{true: "oui", false: "non"}[c.OpenBrowser] }, "oui"},
+		{"booléen de l'option", `{"openBrowser":true}`, map[string]string{"BKC_OPEN_BROWSER": "true"}, []string{"-open-browser=false"},
+			func(c *Config) string { return map[bool]string{true: "oui", false: "non"}[c.OpenBrowser] }, "non"},
+		{"liste de l'environnement", `{"trustedProxies":["192.0.2.1"]}`, map[string]string{"BKC_TRUSTED_PROXIES": "10.0.0.0/8, ,127.0.0.1"}, nil,
+			func(c *Config) string { return strings.Join(c.TrustedProxies, "|") }, "10.0.0.0/8|127.0.0.1"},
+		{"liste de l'option", "", map[string]string{"BKC_TRUSTED_PROXIES": "10.0.0.0/8"}, []string{"-trusted-proxies", "::1"},
+			func(c *Config) string { return strings.Join(c.TrustedProxies, "|") }, "::1"},
+		{"fichier désigné par BKC_CONFIG", "", map[string]string{"BKC_CONFIG": "{dir}/autre.json"}, nil,
+			func(c *Config) string { return c.Addr }, ":7000"},
+		{"option -config sur BKC_CONFIG", "", map[string]string{"BKC_CONFIG": "{dir}/absent.json"}, []string{"-config", "{dir}/autre.json"},
+			func(c *Config) string { return c.Addr }, ":7000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chdir(t, dir) // bkc.json est lu dans le répertoire courant
+			writeFile(t, dir, "autre.json", `{"addr":":7000"}`)
+			if tt.file != "" {
+				writeFile(t, dir, DefaultFile, tt.file)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, strings.ReplaceAll(value, "{dir}", dir))
+			}
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = strings.ReplaceAll(arg, "{dir}", dir)
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.get(cfg); got != tt.want {
+				t.Errorf("%q, attendu %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string // Extrait attendu de l'erreur
+	}{
+		{"fichier demandé absent", "", nil, []string{"-config", "absent.json"}, "fichier de configuration"},
+		{"fichier de BKC_CONFIG absent", "", map[string]string{"BKC_CONFIG": "absent.json"}, nil, "fichier de configuration"},
+		{"réglage inconnu", `{"adr":":9000"}`, nil, nil, "adr"},
+		{"JSON invalide", `{"addr":`, nil, nil, DefaultFile},
+		{"entier invalide", "", map[string]string{"BKC_DIFFICULTY": "quatre"}, nil, "BKC_DIFFICULTY: entier attendu"},
+		{"booléen invalide", "", map[string]string{"BKC_TLS": "peut-être"}, nil, "BKC_TLS: booléen attendu"},
+		{"option inconnue", "", nil, []string{"-inconnue"}, "inconnue"},
+		{"configuration invalide", `{"difficulty":{"mining":12}}`, nil, nil, "difficulty.mining"},
+		{"option invalide", "", nil, []string{"-difficulty", "0"}, "difficulty.mining"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chdir(t, dir)
+			if tt.file != "" {
+				writeFile(t, dir, DefaultFile, tt.file)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			_, err := Load(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("erreur %v, attendu %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	chdir(t, t.TempDir())
+	var force bool
+	cfg, rest, err := LoadCommand("import", []string{"-force", "-data-dir", "données", "chaine.json"}, func(fs *flag.FlagSet) {
+		fs.BoolVar(&force, "force", false, "remplacer la chaîne")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !force || cfg.DataDir != "données" || len(rest) != 1 || rest[0] != "chaine.json" {
+		t.Errorf("force %v, dataDir %q, arguments %q", force, cfg.DataDir, rest)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "cert.pem", "certificat")
+	writeFile(t, dir, "key.pem", "clé")
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string // Réglages signalés, aucun pour une configuration valide
+	}{
+		{"configuration par défaut", func(c *Config) {}, nil},
+		{"adresse sans port", func(c *Config) { c.Addr = "localhost" }, []string{"addr"}},
+		{"port hors limites", func(c *Config) { c.Addr = ":70000" }, []string{"addr: port invalide"}},
+		{"répertoire de données vide", func(c *Config) { c.DataDir = "" }, []string{"dataDir"}},
+		{"répertoires de développement absents", func(c *Config) {
+			c.Dev, c.TemplatesDir, c.StaticDir = true, filepath.Join(dir, "absent"), dir
+		}, []string{"templatesDir"}},
+		{"fichier de données sans nom", func(c *Config) { c.Files.Users = " " }, []string{"files.users"}},
+		{"difficulté nulle", func(c *Config) { c.Difficulty.Mining = 0 }, []string{"difficulty.mining"}},
+		{"difficulté trop élevée", func(c *Config) { c.Difficulty.Genesis = MaxDifficulty + 1 }, []string{"difficulty.genesis"}},
+		{"difficultés multiples", func(c *Config) { c.Difficulty.Signup, c.Difficulty.Visits = -1, 9 },
+			[]string{"difficulty.signup", "difficulty.visits"}},
+		{"origine sans schéma", func(c *Config) { c.AllowedOrigins = []string{"exemple.fr"} }, []string{"allowedOrigins"}},
+		{"origine avec chemin", func(c *Config) { c.AllowedOrigins = []string{"https://exemple.fr/app"} }, []string{"allowedOrigins"}},
+		{"origines valides", func(c *Config) { c.AllowedOrigins = []string{"https://exemple.fr", "http://localhost:8080/"} }, nil},
+		{"proxies valides", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/8", "127.0.0.1", " ::1 ", "2001:db8::/32"} }, nil},
+		{"CIDR invalide", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/33"} }, []string{"trustedProxies"}},
+		{"adresse de proxy invalide", func(c *Config) { c.TrustedProxies = []string{"10.0.0.300"} }, []string{"trustedProxies"}},
+		{"nom de proxy", func(c *Config) { c.TrustedProxies = []string{"proxy.local"} }, []string{"trustedProxies"}},
+		{"SMTP sans port", func(c *Config) { c.SMTP.Addr, c.SMTP.From = "smtp.exemple.fr", "bkc@exemple.fr" }, []string{"smtp.addr"}},
+		{"SMTP sans expéditeur", func(c *Config) { c.SMTP.Addr = "smtp.exemple.fr:587" }, []string{"smtp.from"}},
+		{"niveau de journal inconnu", func(c *Config) { c.Log.Level = "bavard" }, []string{"log.level"}},
+		{"format de journal inconnu", func(c *Config) { c.Log.Format = "xml" }, []string{"log.format"}},
+		{"taille de journal négative", func(c *Config) { c.Log.MaxSizeMB, c.Log.MaxBackups = -1, -1 },
+			[]string{"log.maxSizeMB", "log.maxBackups"}},
+		{"tentatives de webhook", func(c *Config) { c.Webhooks.MaxAttempts = MaxWebhookAttempts + 1 }, []string{"webhooks.maxAttempts"}},
+		{"délai de webhook", func(c *Config) { c.Webhooks.TimeoutSeconds = 0 }, []string{"webhooks.timeoutSeconds"}},
+		{"TLS autosigné", func(c *Config) { c.TLS.Enabled = true }, nil},
+		{"TLS sans fichiers", func(c *Config) { c.TLS.Enabled, c.TLS.CertFile = true, "" }, []string{"tls.certFile"}},
+		{"certificat et clé absents", func(c *Config) { c.TLS.Enabled, c.TLS.SelfSigned = true, false },
+			[]string{"tls.certFile: fichier introuvable", "tls.keyFile: fichier introuvable"}},
+		{"certificat et clé présents", func(c *Config) {
+			c.TLS.Enabled, c.TLS.SelfSigned, c.TLS.CertFile, c.TLS.KeyFile = true, false, "cert.pem", "key.pem"
+		}, nil},
+		{"TLS autosigné sans nom", func(c *Config) { c.TLS.Enabled, c.TLS.Hosts = true, nil }, []string{"tls.hosts"}},
+		{"redirection sur le port HTTPS", func(c *Config) { c.TLS.Enabled, c.TLS.RedirectAddr = true, ":8080" }, []string{"tls.redirectAddr: le port"}},
+		{"redirection invalide", func(c *Config) { c.TLS.Enabled, c.TLS.RedirectAddr = true, "80" }, []string{"tls.redirectAddr: adresse invalide"}},
+		{"TLS désactivé non vérifié", func(c *Config) { c.TLS.SelfSigned, c.TLS.Hosts = false, nil }, nil},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.DataDir = dir
+		tt.change(cfg)
+		err := cfg.Validate()
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s : erreur inattendue %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s : configuration acceptée", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s : erreur %q sans %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+func TestLocalURL(t *testing.T) {
+	tests := []struct {
+		addr string
+		tls  bool
+		want string
+	}{
+		{":8080", false, "http://localhost:8080"},
+		{"0.0.0.0:80", false, "http://localhost:80"},
+		{"[::]:8443", true, "https://localhost:8443"},
+		{"127.0.0.1:9000", false, "http://127.0.0.1:9000"},
+		{"[::1]:9000", true, "https://[::1]:9000"},
+		{"invalide", false, "http://localhost:8080"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Addr, cfg.TLS.Enabled = tt.addr, tt.tls
+		if got := cfg.LocalURL(); got != tt.want {
+			t.Errorf("LocalURL(%q, tls %v) = %q, attendu %q", tt.addr, tt.tls, got, tt.want)
+		}
+		if origins := cfg.Origins(); len(origins) != 1 || origins[0] != tt.want {
+			t.Errorf("Origins(%q) = %q", tt.addr, origins)
+		}
+	}
+}
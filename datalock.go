@@ -0,0 +1,39 @@
+package main
+
+import (
+	"BkC/config"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dataLockFile est le verrou du répertoire de données : le serveur et les commandes qui modifient
+// les fichiers le prennent, pour ne jamais écrire à deux sur la même chaîne ou les mêmes comptes
+const dataLockFile = "bkc.lock"
+
+// errDataLocked signale un verrou déjà pris par un autre processus
+var errDataLocked = errors.New("verrou déjà pris")
+
+// lockDataDir prend le verrou exclusif du répertoire de données, sans attendre. La fonction
+// retournée le libère ; un verrou laissé par un processus arrêté brutalement est libéré par le
+// système (voir lockFile).
+func lockDataDir(cfg *config.Config) (func(), error) {
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("création du répertoire de données impossible : %v", err)
+	}
+
+	path := cfg.DataPath(dataLockFile)
+	release, err := lockFile(path)
+	switch {
+	case errors.Is(err, errDataLocked):
+		owner := "un autre processus bkc"
+		if pid, readErr := os.ReadFile(path); readErr == nil && strings.TrimSpace(string(pid)) != "" {
+			owner = "le processus bkc " + strings.TrimSpace(string(pid))
+		}
+		return nil, fmt.Errorf("le répertoire de données %s est utilisé par %s (serveur en cours d'exécution ?) : arrêtez-le avant de relancer la commande", cfg.DataDir, owner)
+	case err != nil:
+		return nil, fmt.Errorf("verrouillage du répertoire de données impossible : %v", err)
+	}
+	return release, nil
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// lockFile crée path de façon exclusive, avec le PID du processus, et le supprime à la libération.
+// Sans flock, un fichier laissé par un arrêt brutal doit être supprimé à la main (voir README).
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, errDataLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	return func() {
+		f.Close()
+		os.Remove(path)
+	}, nil
+}
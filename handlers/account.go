@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// deviceIDLength est la longueur de l'identifiant public d'une connexion
+// (préfixe de l'empreinte du jeton, qui ne permet pas de retrouver le jeton)
+const deviceIDLength = 12
+
+// retiredUsers contient les noms des comptes supprimés (protégé par mu).
+// Ils ne peuvent plus être réutilisés : les blocs et messages déjà inscrits dans la
+// blockchain restent ainsi attribués sans ambiguïté à l'ancien titulaire.
+var retiredUsers = make(map[string]time.Time)
+
+// DeviceView représente une connexion active dans les réponses JSON
+type DeviceView struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	Browser   string    `json:"browser"`
+	UserAgent string    `json:"userAgent"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Current   bool      `json:"current"` // Connexion utilisée pour cette requête
+}
+
+// SaveRetiredUsers sauvegarde les noms des comptes supprimés
+func SaveRetiredUsers() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	data, err := json.MarshalIndent(retiredUsers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(settings.DataPath(settings.Files.RetiredUsers), data, 0644)
+}
+
+// LoadRetiredUsers charge les noms des comptes supprimés
+func LoadRetiredUsers() error {
+	if _, err := os.Stat(settings.DataPath(settings.Files.RetiredUsers)); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(settings.DataPath(settings.Files.RetiredUsers))
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return json.Unmarshal(data, &retiredUsers)
+}
+
+// deleteAccount supprime un compte, ses connexions, ses jetons et ses webhooks, et retire son nom.
+// L'historique de la blockchain n'est pas modifié. L'appelant doit détenir mu.
+func deleteAccount(username string) {
+	revokeAllDevices(username)
+	revokeAPITokens(username)
+	removeUserWebhooks(username)
+	delete(sessions, username)
+	delete(users, username)
+	retiredUsers[username] = time.Now()
+}
+
+// persistAccounts sauvegarde les comptes, sessions, jetons et noms retirés
+func persistAccounts() {
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+	if err := SaveSessions(); err != nil {
+		slog.Error("Sauvegarde des sessions impossible", "error", err)
+	}
+	if err := SaveAPITokens(); err != nil {
+		slog.Error("Sauvegarde des jetons impossible", "error", err)
+	}
+	if err := SaveRetiredUsers(); err != nil {
+		slog.Error("Sauvegarde des comptes supprimés impossible", "error", err)
+	}
+}
+
+// currentDeviceKey retourne l'empreinte du jeton de session de la requête
+func currentDeviceKey(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return utils.HashToken(cookie.Value)
+}
+
+// AccountPageHandler affiche la page de gestion du compte
+func AccountPageHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	csrfToken := CSRFToken(w, r)
+
+	mu.Lock()
+	data := map[string]interface{}{
+		"Username":  user.Username,
+		"Role":      user.Role,
+		"CreatedAt": user.CreatedAt.Format("02/01/2006"),
+		"Email":     user.Email,
+		"TwoFactor": user.TOTPEnabled,
+		"CSRFToken": csrfToken,
+	}
+	mu.Unlock()
+
+	tmpl, err := loadTemplate(r, "account.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.account"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, data)
+}
+
+// ChangePasswordHandler change le mot de passe, déconnecte toutes les autres connexions et révoque
+// les jetons d'API : un jeton ou une session volés ne survivent pas au changement
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+		return
+	}
+
+	if req.NewPassword == "" || req.NewPassword != req.ConfirmPassword {
+		http.Error(w, tr(r, "error.password_mismatch"), http.StatusBadRequest)
+		return
+	}
+
+	clientIP := utils.GetVisitorIP(r)
+	if allowed, wait := checkLoginAllowed(user.Username, clientIP); !allowed {
+		http.Error(w, tr(r, "error.too_many_attempts", retrySeconds(wait)), http.StatusTooManyRequests)
+		return
+	}
+
+	current := currentDeviceKey(r)
+
+	mu.Lock()
+	if !user.CheckPassword(req.CurrentPassword) {
+		mu.Unlock()
+		recordLoginFailure(user.Username, clientIP)
+		http.Error(w, tr(r, "error.wrong_current_password"), http.StatusForbidden)
+		return
+	}
+
+	user.PasswordHash = utils.HashPassword(req.NewPassword)
+
+	// Conserver uniquement la connexion courante
+	revoked := 0
+	if session, exists := sessions[user.Username]; exists && session != nil {
+		for key := range session.Devices {
+			if key != current {
+				delete(session.Devices, key)
+				revoked++
+			}
+		}
+	}
+	revokedTokens := revokeAPITokens(user.Username)
+	mu.Unlock()
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+	if err := SaveSessions(); err != nil {
+		slog.Error("Sauvegarde des sessions impossible", "error", err)
+	}
+	if err := SaveAPITokens(); err != nil {
+		slog.Error("Sauvegarde des jetons impossible", "error", err)
+	}
+
+	slog.Info("Mot de passe changé", "user", user.Username, "revoked_sessions", revoked, "revoked_tokens", revokedTokens)
+	writeAdminSuccess(w, tr(r, "success.password_changed", revoked, revokedTokens))
+}
+
+// AccountSessionsHandler liste (GET) et révoque (DELETE ?id=) les connexions de l'utilisateur
+func AccountSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	session, _ := sessionFromRequest(r)
+	if session == nil {
+		http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+		return
+	}
+	current := currentDeviceKey(r)
+
+	switch r.Method {
+	case "GET":
+		mu.Lock()
+		views := make([]DeviceView, 0, len(session.Devices))
+		for key, device := range session.Devices {
+			views = append(views, DeviceView{
+				ID:        key[:deviceIDLength],
+				IP:        device.IP,
+				Browser:   simplifyUserAgent(device.UserAgent),
+				UserAgent: device.UserAgent,
+				CreatedAt: device.CreatedAt,
+				LastSeen:  device.LastSeen,
+				Current:   key == current,
+			})
+		}
+		mu.Unlock()
+
+		sort.Slice(views, func(i, j int) bool {
+			return views[i].LastSeen.After(views[j].LastSeen)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(views)
+
+	case "DELETE":
+		id := r.URL.Query().Get("id")
+		if len(id) != deviceIDLength {
+			http.Error(w, tr(r, "error.session_not_found"), http.StatusNotFound)
+			return
+		}
+		if strings.HasPrefix(current, id) {
+			http.Error(w, tr(r, "error.revoke_current_session"), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		found := false
+		for key := range session.Devices {
+			if strings.HasPrefix(key, id) {
+				delete(session.Devices, key)
+				found = true
+			}
+		}
+		mu.Unlock()
+
+		if !found {
+			http.Error(w, tr(r, "error.session_not_found"), http.StatusNotFound)
+			return
+		}
+
+		if err := SaveSessions(); err != nil {
+			slog.Error("Sauvegarde des sessions impossible", "error", err)
+		}
+		slog.Info("Connexion fermée", "device", id, "user", session.Username)
+		writeAdminSuccess(w, tr(r, "success.session_closed"))
+
+	default:
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+	}
+}
+
+// DeleteAccountHandler supprime le compte de l'utilisateur connecté après confirmation
+// du mot de passe (et du second facteur s'il est activé)
+func DeleteAccountHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := currentUser(r)
+		if !ok {
+			http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Password string `json:"password"`
+			Code     string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+			return
+		}
+
+		clientIP := utils.GetVisitorIP(r)
+		if allowed, wait := checkLoginAllowed(user.Username, clientIP); !allowed {
+			http.Error(w, tr(r, "error.too_many_attempts", retrySeconds(wait)), http.StatusTooManyRequests)
+			return
+		}
+
+		mu.Lock()
+		valid := user.CheckPassword(req.Password) &&
+			(!user.TOTPEnabled || user.VerifySecondFactor(req.Code, time.Now()))
+		if !valid {
+			mu.Unlock()
+			recordLoginFailure(user.Username, clientIP)
+			http.Error(w, tr(r, "error.wrong_password_or_code"), http.StatusForbidden)
+			return
+		}
+
+		// Un administrateur ne peut pas supprimer le dernier compte administrateur
+		if user.Role == utils.RoleAdmin {
+			admins := 0
+			for _, u := range users {
+				if u.Role == utils.RoleAdmin && !u.Disabled {
+					admins++
+				}
+			}
+			if admins <= 1 {
+				mu.Unlock()
+				http.Error(w, tr(r, "error.last_admin"), http.StatusConflict)
+				return
+			}
+		}
+
+		deleteAccount(user.Username)
+		mu.Unlock()
+
+		persistAccounts()
+		clearSessionCookie(w)
+
+		recordAudit(bc, user.Username, "delete_account", user.Username, "suppression par l'utilisateur")
+		writeAdminSuccess(w, tr(r, "success.account_deleted"))
+	}
+}
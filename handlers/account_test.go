@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChangePasswordRevokesSessionsAndTokens(t *testing.T) {
+	testSettings(t)
+	alice := &utils.User{Username: "alice", Role: utils.RoleMember, PasswordHash: utils.HashPassword("ancien-mot-de-passe")}
+	testUsers(t, alice)
+	current := testSession(t, "alice")
+	other := testSession(t, "alice")
+	t.Cleanup(func() {
+		mu.Lock()
+		revokeAPITokens("alice")
+		mu.Unlock()
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		kept   bool // Autre connexion et jeton d'API encore valides
+	}{
+		{"confirmation différente", `{"currentPassword":"ancien-mot-de-passe","newPassword":"nouveau-1","confirmPassword":"nouveau-2"}`, http.StatusBadRequest, true},
+		{"mot de passe actuel incorrect", `{"currentPassword":"faux","newPassword":"nouveau-1","confirmPassword":"nouveau-1"}`, http.StatusForbidden, true},
+		{"changement", `{"currentPassword":"ancien-mot-de-passe","newPassword":"nouveau-1","confirmPassword":"nouveau-1"}`, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		view, _, verr := createAPIToken(alice, "test", []string{string(utils.ScopeReadChain)})
+		if verr != nil {
+			t.Fatal(verr)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/api/account/password", strings.NewReader(tt.body))
+		r.AddCookie(current)
+		w := httptest.NewRecorder()
+		ChangePasswordHandler(w, r)
+		if w.Code != tt.status {
+			t.Fatalf("%s : statut %d, attendu %d (%s)", tt.name, w.Code, tt.status, w.Body)
+		}
+
+		bearer := httptest.NewRequest(http.MethodGet, "/api/v1/blocks", nil)
+		bearer.Header.Set("Authorization", "Bearer "+view.Token)
+		if _, ok := authenticateRequest(bearer); ok != tt.kept {
+			t.Errorf("%s : jeton d'API valide %v, attendu %v", tt.name, ok, tt.kept)
+		}
+		otherRequest := httptest.NewRequest(http.MethodGet, "/account", nil)
+		otherRequest.AddCookie(other)
+		if session, _ := sessionFromRequest(otherRequest); (session != nil) != tt.kept {
+			t.Errorf("%s : autre connexion ouverte %v, attendu %v", tt.name, session != nil, tt.kept)
+		}
+		currentRequest := httptest.NewRequest(http.MethodGet, "/account", nil)
+		currentRequest.AddCookie(current)
+		if session, _ := sessionFromRequest(currentRequest); session == nil {
+			t.Errorf("%s : connexion courante fermée", tt.name)
+		}
+	}
+	if !alice.CheckPassword("nouveau-1") {
+		t.Error("nouveau mot de passe non enregistré")
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// AdminUserView représente un compte dans les réponses de l'API d'administration
+type AdminUserView struct {
+	Username  string     `json:"username"`
+	Role      utils.Role `json:"role"`
+	Disabled  bool       `json:"disabled"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LastLogin time.Time  `json:"lastLogin"`
+	Online    bool       `json:"online"`
+	Devices   int        `json:"devices"`   // Nombre de connexions actives
+	Status    string     `json:"status"`    // Statut de présence ("offline", "online", "away", "busy")
+	TwoFactor bool       `json:"twoFactor"` // Double authentification activée
+}
+
+// adminRequest est le corps JSON commun aux actions d'administration
+type adminRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// hasRole indique si un utilisateur possède au moins le rôle indiqué
+func hasRole(username string, role utils.Role) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	user, exists := users[username]
+	return exists && !user.Disabled && user.Role.Includes(role)
+}
+
+// recordAudit enregistre une action d'administration dans la blockchain
+func recordAudit(bc *blockchain.Blockchain, actor, action, target, details string) {
+	event := blockchain.CreateAuditEvent(actor, action, target, details)
+	bc.AddAuditBlockAsync(event, settings.Difficulty.Audit)
+	slog.Info("Audit", "actor", actor, "action", action, "target", target, "details", details)
+}
+
+// decodeAdminRequest décode la requête et vérifie que la cible n'est pas l'administrateur lui-même
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request) (admin string, req adminRequest, ok bool) {
+	if r.Method != "POST" {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return "", req, false
+	}
+
+	admin, _ = getLoggedInUser(r)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+		return "", req, false
+	}
+
+	if req.Username == admin {
+		http.Error(w, tr(r, "error.self_action"), http.StatusBadRequest)
+		return "", req, false
+	}
+
+	mu.Lock()
+	_, exists := users[req.Username]
+	mu.Unlock()
+	if !exists {
+		http.Error(w, tr(r, "error.user_not_found"), http.StatusNotFound)
+		return "", req, false
+	}
+
+	return admin, req, true
+}
+
+// writeAdminSuccess répond avec un statut de succès
+func writeAdminSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// AdminPageHandler affiche la page d'administration
+func AdminPageHandler(w http.ResponseWriter, r *http.Request) {
+	username, _ := getLoggedInUser(r)
+
+	tmpl, err := loadTemplate(r, "admin.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.admin"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, map[string]string{
+		"Username":  username,
+		"CSRFToken": CSRFToken(w, r),
+	})
+}
+
+// AdminUsersHandler liste les comptes enregistrés
+func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListUsers())
+}
+
+// AdminSetRoleHandler modifie le rôle d'un compte et l'enregistre dans la blockchain
+func AdminSetRoleHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, req, ok := decodeAdminRequest(w, r)
+		if !ok {
+			return
+		}
+
+		role, valid := utils.ParseRole(req.Role)
+		if !valid {
+			http.Error(w, tr(r, "error.unknown_role"), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		user := users[req.Username]
+		previous := user.Role
+		user.Role = role
+		mu.Unlock()
+
+		if err := SaveUsers(); err != nil {
+			slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+		}
+
+		recordAudit(bc, admin, "role_change", req.Username, fmt.Sprintf("%s -> %s", previous, role))
+		writeAdminSuccess(w, tr(r, "success.role_updated"))
+	}
+}
+
+// AdminDisableUserHandler active ou désactive un compte
+func AdminDisableUserHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, req, ok := decodeAdminRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if err := SetUserDisabled(admin, req.Username, req.Disabled); err != nil {
+			slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+		}
+		writeAdminSuccess(w, tr(r, "success.account_updated"))
+	}
+}
+
+// AdminDeleteUserHandler supprime un compte et ses sessions
+func AdminDeleteUserHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, req, ok := decodeAdminRequest(w, r)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		deleteAccount(req.Username)
+		mu.Unlock()
+
+		persistAccounts()
+
+		recordAudit(bc, admin, "delete", req.Username, "")
+		writeAdminSuccess(w, tr(r, "success.account_deleted"))
+	}
+}
+
+// AdminForceLogoutHandler déconnecte toutes les sessions d'un compte
+func AdminForceLogoutHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, req, ok := decodeAdminRequest(w, r)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		revokeAllDevices(req.Username)
+		mu.Unlock()
+
+		if err := SaveSessions(); err != nil {
+			slog.Error("Sauvegarde des sessions impossible", "error", err)
+		}
+
+		recordAudit(bc, admin, "force_logout", req.Username, "")
+		writeAdminSuccess(w, tr(r, "success.sessions_revoked"))
+	}
+}
+
+// AdminAuditHandler renvoie la piste d'audit enregistrée dans la blockchain
+func AdminAuditHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events := bc.GetAuditEvents()
+		if events == nil {
+			events = []blockchain.AuditEvent{}
+		}
+
+		// Les événements les plus récents en premier
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Timestamp.After(events[j].Timestamp)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// LoginRequest est le corps de POST /api/v1/session
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Code     string `json:"code,omitempty"` // Code TOTP ou code de secours, si la double authentification est activée
+}
+
+// SessionView décrit la session du client (GET et POST /api/v1/session)
+type SessionView struct {
+	Authenticated bool       `json:"authenticated"`
+	Username      string     `json:"username,omitempty"`
+	Role          utils.Role `json:"role,omitempty"`
+	CSRFToken     string     `json:"csrfToken"` // À renvoyer dans l'en-tête X-CSRF-Token des requêtes modifiant l'état
+}
+
+// apiGetSession retourne l'état de la session et le jeton CSRF à utiliser, posé dans un cookie
+// pour les visiteurs non connectés
+func apiGetSession(w http.ResponseWriter, r *http.Request) {
+	view := SessionView{CSRFToken: CSRFToken(w, r)}
+	if user, ok := currentUser(r); ok {
+		view.Authenticated = true
+		view.Username = user.Username
+		view.Role = user.Role
+	}
+	writeAPIJSON(w, http.StatusOK, view)
+}
+
+// apiCreateSession ouvre une session, comme le formulaire de connexion. Le second facteur est
+// fourni dans la même requête ; sans lui, la réponse 401 porte le code second_factor_required.
+func apiCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	clientIP := utils.GetVisitorIP(r)
+
+	// Les tentatives sont limitées comme celles du formulaire
+	if allowed, wait := checkLoginAllowed(req.Username, clientIP); !allowed {
+		loginAttempts.Inc("blocked")
+		slog.Warn("Tentative de connexion bloquée", "user", req.Username, "ip", clientIP, "retry_in", wait.Round(time.Second))
+		w.Header().Set("Retry-After", strconv.Itoa(retrySeconds(wait)))
+		writeAPIError(w, r, http.StatusTooManyRequests, errTooManyRequests, tr(r, "error.too_many_attempts", retrySeconds(wait)))
+		return
+	}
+
+	mu.Lock()
+	user, ok := users[req.Username]
+	valid := ok && !user.Disabled && user.CheckPassword(req.Password)
+	missingCode := valid && user.TOTPEnabled && req.Code == ""
+	if valid && user.TOTPEnabled && !missingCode {
+		valid = user.VerifySecondFactor(req.Code, time.Now())
+	}
+	var role utils.Role
+	if ok {
+		role = user.Role
+	}
+	mu.Unlock()
+
+	if missingCode {
+		loginAttempts.Inc("second_factor")
+		writeAPIError(w, r, http.StatusUnauthorized, errSecondFactor, tr(r, "error.second_factor_required"))
+		return
+	}
+	if !valid {
+		recordLoginFailure(req.Username, clientIP)
+		loginAttempts.Inc("failure")
+		slog.Warn("Échec d'authentification", "user", req.Username, "ip", clientIP)
+		writeAPIError(w, r, http.StatusUnauthorized, errUnauthorized, tr(r, "login.invalid_credentials"))
+		return
+	}
+
+	device, err := openLoginSession(w, r, req.Username, clientIP)
+	if err != nil {
+		writeAPIError(w, r, http.StatusInternalServerError, errInternal, tr(r, "error.session_creation"))
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, SessionView{
+		Authenticated: true,
+		Username:      req.Username,
+		Role:          role,
+		CSRFToken:     device.CSRFToken,
+	})
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiTokenPrefix permet de reconnaître facilement un jeton BkC (ex: dans un dépôt de code)
+const apiTokenPrefix = "bkc_"
+
+// apiTokens stocke les jetons d'API, indexés par leur empreinte (protégé par mu)
+var apiTokens = make(map[string]*utils.APIToken)
+
+// authContextKey est la clé du contexte portant l'authentification de la requête
+type authContextKey struct{}
+
+// requestAuth décrit comment une requête a été authentifiée
+type requestAuth struct {
+	Username string
+	Token    *utils.APIToken // nil pour une session navigateur
+}
+
+// Allows indique si l'authentification permet la portée demandée.
+// Une session navigateur dispose de toutes les portées de son rôle.
+func (a *requestAuth) Allows(scope utils.TokenScope) bool {
+	return a.Token == nil || a.Token.HasScope(scope)
+}
+
+// APITokenView représente un jeton dans les réponses JSON (sans l'empreinte)
+type APITokenView struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Scopes    []utils.TokenScope `json:"scopes"`
+	CreatedAt time.Time          `json:"createdAt"`
+	LastUsed  time.Time          `json:"lastUsed"`
+	Token     string             `json:"token,omitempty"` // Jeton en clair, renvoyé uniquement à la création
+}
+
+// SaveAPITokens sauvegarde les jetons d'API dans un fichier
+func SaveAPITokens() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tokens := make([]*utils.APIToken, 0, len(apiTokens))
+	for _, token := range apiTokens {
+		tokens = append(tokens, token)
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation des jetons: %v", err)
+	}
+
+	return ioutil.WriteFile(settings.DataPath(settings.Files.Tokens), data, 0600)
+}
+
+// LoadAPITokens charge les jetons d'API depuis un fichier
+func LoadAPITokens() error {
+	if _, err := os.Stat(settings.DataPath(settings.Files.Tokens)); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(settings.DataPath(settings.Files.Tokens))
+	if err != nil {
+		return fmt.Errorf("erreur lors de la lecture du fichier de jetons: %v", err)
+	}
+
+	var tokens []*utils.APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("erreur lors de la désérialisation des jetons: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, token := range tokens {
+		apiTokens[token.TokenHash] = token
+	}
+	return nil
+}
+
+// revokeAPITokens supprime tous les jetons d'un utilisateur.
+// L'appelant doit détenir mu.
+func revokeAPITokens(username string) int {
+	revoked := 0
+	for hash, token := range apiTokens {
+		if token.Owner == username {
+			delete(apiTokens, hash)
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// bearerToken extrait le jeton de l'en-tête Authorization
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// hasBearerToken indique si la requête s'authentifie par jeton d'API
+func hasBearerToken(r *http.Request) bool {
+	_, ok := bearerToken(r)
+	return ok
+}
+
+// authenticateRequest authentifie une requête par jeton d'API ou par cookie de session
+func authenticateRequest(r *http.Request) (*requestAuth, bool) {
+	if auth, ok := r.Context().Value(authContextKey{}).(*requestAuth); ok {
+		return auth, true
+	}
+
+	if token, ok := bearerToken(r); ok {
+		mu.Lock()
+		defer mu.Unlock()
+
+		apiToken, exists := apiTokens[utils.HashToken(token)]
+		if !exists {
+			return nil, false
+		}
+		if user, exists := users[apiToken.Owner]; !exists || user.Disabled {
+			return nil, false
+		}
+		apiToken.LastUsed = time.Now()
+		setRequestUser(r, apiToken.Owner)
+		return &requestAuth{Username: apiToken.Owner, Token: apiToken}, true
+	}
+
+	session, _ := sessionFromRequest(r)
+	if session == nil {
+		return nil, false
+	}
+	return &requestAuth{Username: session.Username}, true
+}
+
+// RequireAccess restreint un handler aux utilisateurs ayant le rôle indiqué, authentifiés
+// par session ou par un jeton d'API disposant de la portée demandée
+func RequireAccess(role utils.Role, scope utils.TokenScope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth, ok := authenticateRequest(r)
+		if !ok {
+			http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+			return
+		}
+
+		if !hasRole(auth.Username, role) {
+			slog.Warn("Accès refusé (rôle insuffisant)", "path", r.URL.Path, "user", auth.Username)
+			http.Error(w, tr(r, "error.forbidden"), http.StatusForbidden)
+			return
+		}
+
+		if !auth.Allows(scope) {
+			slog.Warn("Portée du jeton insuffisante", "token", auth.Token.ID, "scope", scope, "path", r.URL.Path)
+			http.Error(w, tr(r, "error.insufficient_scope"), http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, auth)))
+	}
+}
+
+// allowedScopes retourne les portées qu'un rôle peut accorder à un jeton
+func allowedScopes(role utils.Role) []utils.TokenScope {
+	if role.Includes(utils.RoleMember) {
+		return utils.AllScopes
+	}
+	return []utils.TokenScope{utils.ScopeReadChain}
+}
+
+// TokensPageHandler affiche la page de gestion des jetons d'API
+func TokensPageHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	tmpl, err := loadTemplate(r, "tokens.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.tokens"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, map[string]interface{}{
+		"Username":  user.Username,
+		"Scopes":    allowedScopes(user.Role),
+		"CSRFToken": CSRFToken(w, r),
+	})
+}
+
+// listAPITokens retourne les jetons d'un utilisateur, du plus ancien au plus récent
+func listAPITokens(username string) []APITokenView {
+	mu.Lock()
+	views := make([]APITokenView, 0)
+	for _, token := range apiTokens {
+		if token.Owner == username {
+			views = append(views, APITokenView{
+				ID:        token.ID,
+				Name:      token.Name,
+				Scopes:    token.Scopes,
+				CreatedAt: token.CreatedAt,
+				LastUsed:  token.LastUsed,
+			})
+		}
+	}
+	mu.Unlock()
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].CreatedAt.Before(views[j].CreatedAt)
+	})
+	return views
+}
+
+// createAPIToken crée un jeton pour l'utilisateur après avoir vérifié son nom et ses portées.
+// En cas de refus, l'erreur est accompagnée du code HTTP à renvoyer.
+func createAPIToken(user *utils.User, name string, scopeNames []string) (APITokenView, int, *utils.ValidationError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return APITokenView{}, http.StatusBadRequest, &utils.ValidationError{
+			Field: "name", Code: "required", Message: "Nom ou portées manquants",
+		}
+	}
+	if len(scopeNames) == 0 {
+		return APITokenView{}, http.StatusBadRequest, &utils.ValidationError{
+			Field: "scopes", Code: "required", Message: "Nom ou portées manquants",
+		}
+	}
+
+	// Vérifier que chaque portée existe et est autorisée pour le rôle
+	permitted := allowedScopes(user.Role)
+	scopes := make([]utils.TokenScope, 0, len(scopeNames))
+	for _, s := range scopeNames {
+		scope, valid := utils.ParseScope(s)
+		if !valid {
+			return APITokenView{}, http.StatusBadRequest, &utils.ValidationError{
+				Field: "scopes", Code: "unknown_scope", Message: fmt.Sprintf("Portée inconnue: %s", s), Args: []interface{}{s},
+			}
+		}
+		allowed := false
+		for _, p := range permitted {
+			allowed = allowed || p == scope
+		}
+		if !allowed {
+			return APITokenView{}, http.StatusForbidden, &utils.ValidationError{
+				Field: "scopes", Code: "scope_not_allowed", Message: fmt.Sprintf("Portée non autorisée pour votre rôle: %s", s), Args: []interface{}{s},
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+
+	// L'identifiant, affiché et journalisé, est tiré séparément pour ne rien révéler du secret
+	secret, err := generateToken()
+	var id string
+	if err == nil {
+		id, err = generateToken()
+	}
+	if err != nil {
+		return APITokenView{}, http.StatusInternalServerError, &utils.ValidationError{
+			Field: "token", Code: "internal_error", Message: "Erreur lors de la création du jeton",
+		}
+	}
+	plain := apiTokenPrefix + secret
+
+	token := &utils.APIToken{
+		ID:        id[:12],
+		Name:      name,
+		Owner:     user.Username,
+		TokenHash: utils.HashToken(plain),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	mu.Lock()
+	apiTokens[token.TokenHash] = token
+	mu.Unlock()
+
+	if err := SaveAPITokens(); err != nil {
+		slog.Error("Sauvegarde des jetons impossible", "error", err)
+	}
+
+	slog.Info("Nouveau jeton d'API", "token", token.ID, "name", token.Name, "user", user.Username)
+
+	return APITokenView{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+		Token:     plain,
+	}, http.StatusCreated, nil
+}
+
+// revokeAPIToken supprime le jeton id de l'utilisateur et indique s'il existait
+func revokeAPIToken(username, id string) bool {
+	mu.Lock()
+	found := false
+	for hash, token := range apiTokens {
+		if token.ID == id && token.Owner == username {
+			delete(apiTokens, hash)
+			found = true
+		}
+	}
+	mu.Unlock()
+
+	if !found {
+		return false
+	}
+
+	if err := SaveAPITokens(); err != nil {
+		slog.Error("Sauvegarde des jetons impossible", "error", err)
+	}
+
+	slog.Info("Jeton d'API révoqué", "token", id, "user", username)
+	return true
+}
+
+// APITokensHandler liste (GET), crée (POST) et révoque (DELETE ?id=) les jetons de l'utilisateur.
+// La gestion des jetons n'est possible que depuis une session navigateur.
+func APITokensHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(listAPITokens(user.Username))
+
+	case "POST":
+		var req struct {
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+			return
+		}
+
+		view, status, verr := createAPIToken(user, req.Name, req.Scopes)
+		if verr != nil {
+			http.Error(w, localizeError(r, verr).Message, status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(view)
+
+	case "DELETE":
+		if !revokeAPIToken(user.Username, r.URL.Query().Get("id")) {
+			http.Error(w, tr(r, "error.token_not_found"), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "success",
+			"message": tr(r, "success.token_revoked"),
+		})
+
+	default:
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+	}
+}
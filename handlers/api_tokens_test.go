@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"strings"
+	"testing"
+)
+
+func TestAPITokenIDRevealsNothingOfTheSecret(t *testing.T) {
+	testSettings(t)
+	user := &utils.User{Username: "alice", Role: utils.RoleMember}
+	t.Cleanup(func() {
+		mu.Lock()
+		revokeAPITokens(user.Username)
+		mu.Unlock()
+	})
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		view, _, verr := createAPIToken(user, "test", []string{string(utils.ScopeReadChain)})
+		if verr != nil {
+			t.Fatal(verr)
+		}
+		secret := strings.TrimPrefix(view.Token, apiTokenPrefix)
+		if len(view.ID) != 12 || strings.Contains(secret, view.ID) {
+			t.Fatalf("identifiant %q tiré du jeton %q", view.ID, view.Token)
+		}
+		if seen[view.ID] {
+			t.Fatalf("identifiant %q en double", view.ID)
+		}
+		seen[view.ID] = true
+	}
+}
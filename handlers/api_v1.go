@@ -0,0 +1,643 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// apiV1Prefix est le préfixe des routes de l'API versionnée
+const apiV1Prefix = "/api/v1"
+
+// Pagination de GET /api/v1/blocks
+const (
+	defaultBlocksLimit = 50
+	maxBlocksLimit     = 500
+)
+
+// Codes d'erreur de l'API, stables pour les clients (le message peut évoluer)
+const (
+	errBadRequest       = "bad_request"
+	errInvalidJSON      = "invalid_json"
+	errValidation       = "validation_failed"
+	errUnauthorized     = "unauthorized"
+	errForbidden        = "forbidden"
+	errNotFound         = "not_found"
+	errMethodNotAllowed = "method_not_allowed"
+	errTooManyRequests  = "too_many_requests"
+	errSecondFactor     = "second_factor_required" // Connexion : code de double authentification manquant
+	errInternal         = "internal_error"
+)
+
+// APIError décrit une erreur de l'API
+type APIError struct {
+	Code      string                   `json:"code"`
+	Message   string                   `json:"message"`
+	RequestID string                   `json:"requestId,omitempty"` // Identifiant à rappeler au support (X-Request-ID)
+	Details   []*utils.ValidationError `json:"details,omitempty"`   // Erreurs par champ
+}
+
+// APIErrorResponse est l'enveloppe commune à toutes les erreurs de l'API
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// writeAPIJSON répond avec un document JSON
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError répond avec l'enveloppe d'erreur de l'API
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, code, message string, details ...*utils.ValidationError) {
+	writeAPIJSON(w, status, APIErrorResponse{Error: APIError{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID(r),
+		Details:   localizeErrors(r, details),
+	}})
+}
+
+// httpError répond avec l'enveloppe de l'API pour les routes /api/v1 et en texte brut ailleurs,
+// pour les middlewares communes aux deux
+func httpError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if r.URL.Path == apiV1Prefix || strings.HasPrefix(r.URL.Path, apiV1Prefix+"/") {
+		writeAPIError(w, r, status, code, message)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// decodeAPIBody décode le corps JSON de la requête (taille limitée) et répond 400 en cas d'échec
+func decodeAPIBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMessageBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		detail := &utils.ValidationError{Field: "body", Code: errInvalidJSON, Message: "Données JSON invalides"}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			detail = &utils.ValidationError{
+				Field: "body", Code: "too_large", Args: []interface{}{tooLarge.Limit},
+				Message: fmt.Sprintf("Corps de la requête trop volumineux (%d octets au maximum)", tooLarge.Limit),
+			}
+		}
+		writeAPIError(w, r, http.StatusBadRequest, errInvalidJSON, localizeError(r, detail).Message, detail)
+		return false
+	}
+	return true
+}
+
+// apiAccess indique comment une route de l'API est protégée
+type apiAccess int
+
+const (
+	accessPublic  apiAccess = iota // Sans authentification
+	accessToken                    // Session navigateur ou jeton d'API disposant de la portée
+	accessSession                  // Session navigateur uniquement
+)
+
+// apiParam décrit un paramètre de chemin ou de requête, pour la description OpenAPI
+type apiParam struct {
+	Name        string
+	In          string // "path", "query" ou "header"
+	Type        string // "integer" ou "string"
+	Required    bool
+	Description string
+}
+
+// apiRoute décrit une route de l'API. La même table sert à l'enregistrement des handlers
+// et à la génération de la description OpenAPI, qui ne peut donc pas s'en écarter.
+type apiRoute struct {
+	Method   string
+	Path     string // Relatif à /api/v1, avec les paramètres entre accolades ("/blocks/{index}")
+	ID       string // operationId
+	Summary  string
+	Tag      string
+	Access   apiAccess
+	Role     utils.Role
+	Scope    utils.TokenScope
+	Params   []apiParam
+	Request  interface{} // Valeur du type attendu dans le corps (nil : pas de corps)
+	Response interface{} // Valeur du type renvoyé (nil : pas de corps)
+	Media    string      // Type du corps renvoyé ("" : application/json)
+	Status   int         // Code de succès
+	Errors   []int       // Codes d'erreur possibles en plus de 401/403 pour les routes protégées
+	Handler  http.HandlerFunc
+}
+
+// MineRequest est le corps de POST /api/v1/blocks
+type MineRequest struct {
+	Data string `json:"data"`
+}
+
+// BlockPage est une page de blocs (GET /api/v1/blocks)
+type BlockPage struct {
+	Total  int                 `json:"total"`
+	Offset int                 `json:"offset"`
+	Limit  int                 `json:"limit"`
+	Blocks []*blockchain.Block `json:"blocks"`
+}
+
+// PresenceRequest est le corps de PUT /api/v1/presence
+type PresenceRequest struct {
+	Status string `json:"status"` // "online", "away" ou "busy"
+}
+
+// TokenRequest est le corps de POST /api/v1/tokens
+type TokenRequest struct {
+	Name   string             `json:"name"`
+	Scopes []utils.TokenScope `json:"scopes"`
+}
+
+// webhookIDParam est le paramètre de chemin des routes /webhooks/{id}
+var webhookIDParam = apiParam{Name: "id", In: "path", Type: "string", Required: true, Description: "Identifiant du webhook"}
+
+// apiV1Routes retourne la table des routes de l'API v1
+func apiV1Routes(bc *blockchain.Blockchain) []apiRoute {
+	return []apiRoute{
+		{
+			Method: "GET", Path: "/session", ID: "getSession", Tag: "session",
+			Summary:  "État de la session et jeton CSRF à envoyer dans l'en-tête " + csrfHeaderName,
+			Access:   accessPublic,
+			Response: SessionView{}, Status: http.StatusOK,
+			Handler: apiGetSession,
+		},
+		{
+			Method: "POST", Path: "/session", ID: "createSession", Tag: "session",
+			Summary: "Ouvre une session (cookie), avec le jeton CSRF de GET /session ; la réponse contient le nouveau jeton CSRF",
+			Access:  accessPublic,
+			Request: LoginRequest{}, Response: SessionView{}, Status: http.StatusCreated,
+			Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError},
+			Handler: apiCreateSession,
+		},
+		{
+			Method: "GET", Path: "/blocks", ID: "listBlocks", Tag: "blocks",
+			Summary: "Liste les blocs de la chaîne, du plus ancien au plus récent",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Params: []apiParam{
+				{Name: "offset", In: "query", Type: "integer", Description: "Index du premier bloc (0 par défaut)"},
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Nombre de blocs (%d par défaut, %d au maximum)", defaultBlocksLimit, maxBlocksLimit)},
+			},
+			Response: BlockPage{}, Status: http.StatusOK, Errors: []int{http.StatusBadRequest},
+			Handler: apiListBlocks(bc),
+		},
+		{
+			Method: "GET", Path: "/blocks/{index}", ID: "getBlock", Tag: "blocks",
+			Summary: "Retourne un bloc",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Params: []apiParam{
+				{Name: "index", In: "path", Type: "integer", Required: true, Description: "Index du bloc"},
+			},
+			Response: blockchain.Block{}, Status: http.StatusOK, Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+			Handler: apiGetBlock(bc),
+		},
+		{
+			Method: "POST", Path: "/blocks", ID: "mineBlock", Tag: "blocks",
+			Summary: "Mine un bloc signé par l'utilisateur (la réponse attend la fin du minage)",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeMine,
+			Request: MineRequest{}, Response: blockchain.Block{}, Status: http.StatusCreated,
+			Errors:  []int{http.StatusBadRequest},
+			Handler: apiMineBlock(bc),
+		},
+		{
+			Method: "GET", Path: "/messages", ID: "listMessages", Tag: "messages",
+			Summary: "Liste les messages envoyés et reçus par l'utilisateur",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Params: []apiParam{
+				{Name: "with", In: "query", Type: "string", Description: "Limite la liste à la conversation avec cet utilisateur"},
+			},
+			Response: []blockchain.Message{}, Status: http.StatusOK,
+			Handler: apiListMessages(bc),
+		},
+		{
+			Method: "POST", Path: "/messages", ID: "sendMessage", Tag: "messages",
+			Summary: "Envoie un message ; il est ajouté à la chaîne une fois le bloc miné",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeSendMessages,
+			Request: MessageData{}, Response: blockchain.Message{}, Status: http.StatusAccepted,
+			Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+			Handler: apiSendMessage(bc),
+		},
+		{
+			Method: "GET", Path: "/events", ID: "streamEvents", Tag: "events",
+			Summary: "Flux Server-Sent Events des nouveaux blocs (blockchain_update) et des messages de l'utilisateur (new_message), identifiés par l'index du bloc",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Params: []apiParam{
+				{Name: "Last-Event-ID", In: "header", Type: "integer", Description: "Reprend le flux après ce bloc (envoyé par le navigateur à la reconnexion)"},
+				{Name: "lastEventId", In: "query", Type: "integer", Description: "Équivalent de l'en-tête Last-Event-ID"},
+			},
+			Response: "", Media: "text/event-stream", Status: http.StatusOK, Errors: []int{http.StatusBadRequest},
+			Handler: apiEvents(bc),
+		},
+		{
+			Method: "POST", Path: "/rpc", ID: "callRPC", Tag: "rpc",
+			Summary: "Requête ou lot de requêtes JSON-RPC 2.0 (chain_getHeight, chain_getBlockByIndex, chain_getBlockByHash, msg_send, msg_list, mining_submit, node_stats) ; les erreurs sont retournées dans la réponse JSON-RPC",
+			Access:  accessToken, Role: utils.RoleReadOnly,
+			Request: RPCRequest{}, Response: RPCResponse{}, Status: http.StatusOK,
+			Handler: apiRPC(bc),
+		},
+		{
+			Method: "GET", Path: "/stats", ID: "getStats", Tag: "stats",
+			Summary: "Statistiques du serveur",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Response: Stats{}, Status: http.StatusOK,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				writeAPIJSON(w, http.StatusOK, CollectStats(bc))
+			},
+		},
+		{
+			Method: "GET", Path: "/miners", ID: "listMiners", Tag: "stats",
+			Summary: "Mineurs classés par nombre de blocs minés",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Response: []MinerStats{}, Status: http.StatusOK,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				writeAPIJSON(w, http.StatusOK, CollectMinerStats(bc))
+			},
+		},
+		{
+			Method: "GET", Path: "/presence", ID: "getPresence", Tag: "presence",
+			Summary: "Présence de l'utilisateur et de ses contacts",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Response: PresenceView{}, Status: http.StatusOK,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				writeAPIJSON(w, http.StatusOK, presenceView(apiUser(r)))
+			},
+		},
+		{
+			Method: "PUT", Path: "/presence", ID: "setPresence", Tag: "presence",
+			Summary: "Choisit le statut affiché aux contacts",
+			Access:  accessToken, Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+			Request: PresenceRequest{}, Response: PresenceView{}, Status: http.StatusOK,
+			Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+			Handler: apiSetPresence,
+		},
+		{
+			Method: "GET", Path: "/tokens", ID: "listTokens", Tag: "tokens",
+			Summary: "Liste les jetons d'API de l'utilisateur (session navigateur uniquement)",
+			Access:  accessSession, Role: utils.RoleReadOnly,
+			Response: []APITokenView{}, Status: http.StatusOK,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				writeAPIJSON(w, http.StatusOK, listAPITokens(apiUser(r)))
+			},
+		},
+		{
+			Method: "POST", Path: "/tokens", ID: "createToken", Tag: "tokens",
+			Summary: "Crée un jeton d'API ; le jeton en clair n'est renvoyé qu'une fois",
+			Access:  accessSession, Role: utils.RoleReadOnly,
+			Request: TokenRequest{}, Response: APITokenView{}, Status: http.StatusCreated,
+			Errors:  []int{http.StatusBadRequest, http.StatusInternalServerError},
+			Handler: apiCreateToken,
+		},
+		{
+			Method: "DELETE", Path: "/tokens/{id}", ID: "revokeToken", Tag: "tokens",
+			Summary: "Révoque un jeton d'API",
+			Access:  accessSession, Role: utils.RoleReadOnly,
+			Params: []apiParam{
+				{Name: "id", In: "path", Type: "string", Required: true, Description: "Identifiant du jeton"},
+			},
+			Status: http.StatusNoContent, Errors: []int{http.StatusNotFound},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				if !revokeAPIToken(apiUser(r), r.PathValue("id")) {
+					writeAPIError(w, r, http.StatusNotFound, errNotFound, tr(r, "error.token_not_found"))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			Method: "GET", Path: "/webhooks", ID: "listWebhooks", Tag: "webhooks",
+			Summary: "Liste les webhooks de l'utilisateur (tous les webhooks pour un administrateur)",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Response: []WebhookView{}, Status: http.StatusOK,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				writeAPIJSON(w, http.StatusOK, listWebhooks(apiUser(r)))
+			},
+		},
+		{
+			Method: "POST", Path: "/webhooks", ID: "createWebhook", Tag: "webhooks",
+			Summary: "Enregistre un webhook ; le secret de signature n'est renvoyé qu'une fois",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Request: WebhookRequest{}, Response: WebhookView{}, Status: http.StatusCreated,
+			Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+			Handler: apiCreateWebhook(bc),
+		},
+		{
+			Method: "DELETE", Path: "/webhooks/{id}", ID: "deleteWebhook", Tag: "webhooks",
+			Summary: "Supprime un webhook",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Params: []apiParam{webhookIDParam},
+			Status: http.StatusNoContent, Errors: []int{http.StatusNotFound},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				if !deleteWebhook(bc, apiUser(r), r.PathValue("id")) {
+					writeAPIError(w, r, http.StatusNotFound, errNotFound, tr(r, "error.webhook_not_found"))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			Method: "POST", Path: "/webhooks/{id}/ping", ID: "pingWebhook", Tag: "webhooks",
+			Summary: "Envoie un événement de test (ping) au webhook",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Params:   []apiParam{webhookIDParam},
+			Response: WebhookDelivery{}, Status: http.StatusAccepted, Errors: []int{http.StatusNotFound, http.StatusInternalServerError},
+			Handler: webhookHandler(func(w http.ResponseWriter, r *http.Request, hook *Webhook) {
+				delivery := pingWebhook(hook)
+				if delivery == nil {
+					writeAPIError(w, r, http.StatusInternalServerError, errInternal, tr(r, "error.webhook_creation"))
+					return
+				}
+				writeAPIJSON(w, http.StatusAccepted, delivery)
+			}),
+		},
+		{
+			Method: "GET", Path: "/webhooks/{id}/deliveries", ID: "listWebhookDeliveries", Tag: "webhooks",
+			Summary: fmt.Sprintf("Livraisons récentes du webhook (%d au plus), de la plus récente à la plus ancienne", webhookLogSize),
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Params:   []apiParam{webhookIDParam},
+			Response: []WebhookDelivery{}, Status: http.StatusOK, Errors: []int{http.StatusNotFound},
+			Handler: webhookHandler(func(w http.ResponseWriter, r *http.Request, hook *Webhook) {
+				writeAPIJSON(w, http.StatusOK, recentDeliveries(hook.ID))
+			}),
+		},
+		{
+			Method: "GET", Path: "/webhooks/{id}/dead-letters", ID: "listWebhookDeadLetters", Tag: "webhooks",
+			Summary: "Livraisons du webhook abandonnées après la dernière tentative",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Params:   []apiParam{webhookIDParam},
+			Response: []WebhookDelivery{}, Status: http.StatusOK, Errors: []int{http.StatusNotFound},
+			Handler: webhookHandler(func(w http.ResponseWriter, r *http.Request, hook *Webhook) {
+				writeAPIJSON(w, http.StatusOK, deadLetters(hook.ID))
+			}),
+		},
+		{
+			Method: "POST", Path: "/webhooks/{id}/dead-letters/redeliver", ID: "redeliverWebhook", Tag: "webhooks",
+			Summary: "Relance les livraisons abandonnées du webhook, avec leur identifiant d'origine",
+			Access:  accessToken, Role: utils.RoleMember, Scope: utils.ScopeWebhooks,
+			Params:   []apiParam{webhookIDParam},
+			Response: []WebhookDelivery{}, Status: http.StatusAccepted, Errors: []int{http.StatusNotFound},
+			Handler: webhookHandler(func(w http.ResponseWriter, r *http.Request, hook *Webhook) {
+				writeAPIJSON(w, http.StatusAccepted, redeliver(hook.ID))
+			}),
+		},
+	}
+}
+
+// APIv1Router retourne le routeur de l'API v1, à monter sur /api/v1/. Les routes sont
+// enregistrées avec des motifs « méthode chemin » ; toute autre requête reçoit l'enveloppe
+// d'erreur (404, ou 405 avec l'en-tête Allow). La description OpenAPI est servie sur
+// /api/v1/openapi.json.
+func APIv1Router(bc *blockchain.Blockchain) http.Handler {
+	routes := apiV1Routes(bc)
+	spec, err := json.MarshalIndent(buildOpenAPI(routes), "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("description OpenAPI invalide: %v", err))
+	}
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.Handle(route.Method+" "+apiV1Prefix+route.Path, apiAccessHandler(route))
+	}
+	mux.HandleFunc("GET "+apiV1Prefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+
+	catchAll := apiV1Prefix + "/"
+	mux.HandleFunc(catchAll, func(w http.ResponseWriter, r *http.Request) {
+		// Le chemin existe-t-il pour une autre méthode ?
+		var allowed []string
+		for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" && pattern != catchAll {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			if allowed[0] == "GET" {
+				allowed = append(allowed[:1], append([]string{"HEAD"}, allowed[1:]...)...)
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed, tr(r, "error.method_not_allowed"))
+			return
+		}
+		writeAPIError(w, r, http.StatusNotFound, errNotFound, tr(r, "error.not_found"))
+	})
+	return mux
+}
+
+// apiAccessHandler applique le contrôle d'accès d'une route et répond avec l'enveloppe d'erreur
+func apiAccessHandler(route apiRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var auth *requestAuth
+		switch route.Access {
+		case accessPublic:
+			route.Handler(w, r)
+			return
+
+		case accessSession:
+			if hasBearerToken(r) {
+				writeAPIError(w, r, http.StatusForbidden, errForbidden, tr(r, "error.tokens_session_only"))
+				return
+			}
+			user, ok := currentUser(r)
+			if !ok {
+				writeAPIError(w, r, http.StatusUnauthorized, errUnauthorized, tr(r, "error.not_logged_in"))
+				return
+			}
+			auth = &requestAuth{Username: user.Username}
+
+		default:
+			var ok bool
+			if auth, ok = authenticateRequest(r); !ok {
+				writeAPIError(w, r, http.StatusUnauthorized, errUnauthorized, tr(r, "error.not_logged_in"))
+				return
+			}
+			// Sans portée (JSON-RPC), chaque méthode vérifie la sienne
+			if route.Scope != "" && !auth.Allows(route.Scope) {
+				slog.Warn("Portée du jeton insuffisante", "token", auth.Token.ID, "scope", route.Scope, "path", r.URL.Path)
+				writeAPIError(w, r, http.StatusForbidden, errForbidden, tr(r, "error.insufficient_scope"))
+				return
+			}
+		}
+
+		if !hasRole(auth.Username, route.Role) {
+			slog.Warn("Accès refusé (rôle insuffisant)", "path", r.URL.Path, "user", auth.Username)
+			writeAPIError(w, r, http.StatusForbidden, errForbidden, tr(r, "error.forbidden"))
+			return
+		}
+
+		route.Handler(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, auth)))
+	}
+}
+
+// apiUser retourne l'utilisateur authentifié par apiAccessHandler
+func apiUser(r *http.Request) string {
+	if auth, ok := r.Context().Value(authContextKey{}).(*requestAuth); ok {
+		return auth.Username
+	}
+	return ""
+}
+
+// queryInt lit un paramètre entier positif ou nul de la requête
+func queryInt(r *http.Request, name string, fallback int) (int, *utils.ValidationError) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, &utils.ValidationError{Field: name, Code: "invalid", Message: fmt.Sprintf("%s doit être un entier positif", name), Args: []interface{}{name}}
+	}
+	return value, nil
+}
+
+// apiListBlocks retourne une page de blocs
+func apiListBlocks(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, offsetErr := queryInt(r, "offset", 0)
+		limit, limitErr := queryInt(r, "limit", defaultBlocksLimit)
+		if limitErr == nil && (limit == 0 || limit > maxBlocksLimit) {
+			limitErr = &utils.ValidationError{
+				Field: "limit", Code: "out_of_range",
+				Message: fmt.Sprintf("limit doit être compris entre 1 et %d", maxBlocksLimit),
+				Args:    []interface{}{maxBlocksLimit},
+			}
+		}
+		var errs []*utils.ValidationError
+		for _, verr := range []*utils.ValidationError{offsetErr, limitErr} {
+			if verr != nil {
+				errs = append(errs, verr)
+			}
+		}
+		if len(errs) > 0 {
+			writeAPIError(w, r, http.StatusBadRequest, errBadRequest, tr(r, "error.invalid_pagination"), errs...)
+			return
+		}
+
+		blocks, total := bc.GetBlocks(offset, limit)
+		writeAPIJSON(w, http.StatusOK, BlockPage{Total: total, Offset: offset, Limit: limit, Blocks: blocks})
+	}
+}
+
+// apiGetBlock retourne le bloc dont l'index est donné dans le chemin
+func apiGetBlock(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		index, err := strconv.Atoi(r.PathValue("index"))
+		if err != nil {
+			writeAPIError(w, r, http.StatusBadRequest, errBadRequest, tr(r, "error.invalid_block_index"),
+				&utils.ValidationError{Field: "index", Code: "invalid", Message: "L'index doit être un entier"})
+			return
+		}
+		block, ok := bc.GetBlock(index)
+		if !ok {
+			writeAPIError(w, r, http.StatusNotFound, errNotFound, tr(r, "error.block_not_found", index))
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, block)
+	}
+}
+
+// apiMineBlock mine un bloc pour l'utilisateur
+func apiMineBlock(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MineRequest
+		if !decodeAPIBody(w, r, &req) {
+			return
+		}
+		writeAPIJSON(w, http.StatusCreated, MineBlock(bc, apiUser(r), req.Data))
+	}
+}
+
+// apiListMessages retourne les messages de l'utilisateur, éventuellement limités à une conversation
+func apiListMessages(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := apiUser(r)
+		with := r.URL.Query().Get("with")
+
+		messages := make([]blockchain.Message, 0)
+		for _, message := range bc.GetUserMessages(username) {
+			if with == "" || message.Sender == with || message.Recipient == with {
+				messages = append(messages, message)
+			}
+		}
+		writeAPIJSON(w, http.StatusOK, messages)
+	}
+}
+
+// apiSendMessage valide un message et lance son ajout à la chaîne
+func apiSendMessage(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MessageData
+		if !decodeAPIBody(w, r, &req) {
+			return
+		}
+
+		recipient, errs := validateNewMessage(req.Recipient, req.Content)
+		if len(errs) > 0 {
+			writeAPIError(w, r, http.StatusUnprocessableEntity, errValidation, tr(r, "error.message_rejected"), errs...)
+			return
+		}
+
+		message := blockchain.CreateMessage(apiUser(r), recipient, req.Content)
+		bc.AddMessageBlockAsync(message, settings.Difficulty.Messages)
+		writeAPIJSON(w, http.StatusAccepted, message)
+	}
+}
+
+// apiSetPresence enregistre le statut choisi par l'utilisateur
+func apiSetPresence(w http.ResponseWriter, r *http.Request) {
+	var req PresenceRequest
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	status, valid := parseManualStatus(req.Status)
+	if !valid {
+		writeAPIError(w, r, http.StatusUnprocessableEntity, errValidation, tr(r, "error.invalid_status"),
+			&utils.ValidationError{Field: "status", Code: "invalid", Message: "Statut invalide (online, away ou busy)"})
+		return
+	}
+
+	username := apiUser(r)
+	presence.SetManual(username, status)
+	writeAPIJSON(w, http.StatusOK, presenceView(username))
+}
+
+// apiCreateToken crée un jeton d'API pour l'utilisateur de la session
+func apiCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req TokenRequest
+	if !decodeAPIBody(w, r, &req) {
+		return
+	}
+	user, ok := currentUser(r)
+	if !ok {
+		writeAPIError(w, r, http.StatusUnauthorized, errUnauthorized, tr(r, "error.not_logged_in"))
+		return
+	}
+
+	scopes := make([]string, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		scopes[i] = string(scope)
+	}
+	view, status, verr := createAPIToken(user, req.Name, scopes)
+	if verr != nil {
+		code := errValidation
+		switch status {
+		case http.StatusForbidden:
+			code = errForbidden
+		case http.StatusInternalServerError:
+			writeAPIError(w, r, status, errInternal, localizeError(r, verr).Message)
+			return
+		}
+		writeAPIError(w, r, status, code, localizeError(r, verr).Message, verr)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, view)
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxWebhookURLLength borne la longueur de l'URL d'un webhook
+const maxWebhookURLLength = 2048
+
+// WebhookRequest est le corps de POST /api/v1/webhooks
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // block.new, message.new, chain.reorg
+	Global bool     `json:"global"` // Administrateurs : recevoir les messages de tous les utilisateurs
+}
+
+// WebhookView décrit un webhook. Le secret de signature n'est renvoyé qu'à la création.
+type WebhookView struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	Global      bool      `json:"global"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	CreatedAt   time.Time `json:"createdAt"`
+	DeadLetters int       `json:"deadLetters"` // Livraisons en échec, à relivrer
+	Secret      string    `json:"secret,omitempty"`
+}
+
+// viewLocked décrit un webhook. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) viewLocked(hook *Webhook) WebhookView {
+	view := WebhookView{
+		ID:        hook.ID,
+		Owner:     hook.Owner,
+		Global:    hook.Global,
+		URL:       hook.URL,
+		Events:    hook.Events,
+		CreatedAt: hook.CreatedAt,
+	}
+	for _, delivery := range d.deadLetters {
+		if delivery.WebhookID == hook.ID {
+			view.DeadLetters++
+		}
+	}
+	return view
+}
+
+// listWebhooks retourne les webhooks de l'utilisateur, ou tous les webhooks pour un administrateur
+func listWebhooks(username string) []WebhookView {
+	admin := hasRole(username, utils.RoleAdmin)
+
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	views := []WebhookView{}
+	for _, hook := range webhooks.hooks {
+		if admin || hook.Owner == username {
+			views = append(views, webhooks.viewLocked(hook))
+		}
+	}
+	slices.SortFunc(views, func(a, b WebhookView) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return views
+}
+
+// findWebhook retourne un webhook accessible à l'utilisateur (le sien, ou n'importe lequel pour
+// un administrateur). L'appelant ne doit pas détenir webhooks.mu.
+func findWebhook(username, id string) (*Webhook, bool) {
+	admin := hasRole(username, utils.RoleAdmin)
+
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	hook, exists := webhooks.hooks[id]
+	if !exists || (!admin && hook.Owner != username) {
+		return nil, false
+	}
+	return hook, true
+}
+
+// validateWebhookURL vérifie l'URL d'un webhook. Sauf pour les administrateurs (ou avec
+// webhooks.allowPrivate), les adresses locales et privées sont refusées ; les noms de domaine
+// sont vérifiés à nouveau à chaque connexion, après leur résolution.
+func validateWebhookURL(raw string, restricted bool) *utils.ValidationError {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return &utils.ValidationError{Field: "url", Code: "required", Message: "L'URL est obligatoire"}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil || len(raw) > maxWebhookURLLength {
+		return &utils.ValidationError{Field: "url", Code: "invalid", Message: "URL invalide (http:// ou https:// attendu)"}
+	}
+	if restricted {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+			return &utils.ValidationError{Field: "url", Code: "private_address", Message: "Adresse locale ou privée non autorisée"}
+		}
+	}
+	return nil
+}
+
+// validateWebhookEvents vérifie les événements demandés et les retourne sans doublons
+func validateWebhookEvents(events []string) ([]string, *utils.ValidationError) {
+	if len(events) == 0 {
+		return nil, &utils.ValidationError{Field: "events", Code: "required", Message: "Au moins un événement est requis"}
+	}
+	valid := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(webhookEvents, event) {
+			return nil, &utils.ValidationError{Field: "events", Code: "unknown_event", Message: fmt.Sprintf("Événement inconnu: %s", event), Args: []interface{}{event}}
+		}
+		if !slices.Contains(valid, event) {
+			valid = append(valid, event)
+		}
+	}
+	return valid, nil
+}
+
+// createWebhook enregistre un webhook et retourne sa description avec le secret de signature
+func createWebhook(bc *blockchain.Blockchain, username string, req WebhookRequest) (WebhookView, int, []*utils.ValidationError) {
+	admin := hasRole(username, utils.RoleAdmin)
+	if req.Global && !admin {
+		return WebhookView{}, http.StatusForbidden, nil
+	}
+
+	var errs []*utils.ValidationError
+	if err := validateWebhookURL(req.URL, !admin && !settings.Webhooks.AllowPrivate); err != nil {
+		errs = append(errs, err)
+	}
+	events, err := validateWebhookEvents(req.Events)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return WebhookView{}, http.StatusUnprocessableEntity, errs
+	}
+
+	id, idErr := generateToken()
+	secret, secretErr := generateToken()
+	if idErr != nil || secretErr != nil {
+		return WebhookView{}, http.StatusInternalServerError, nil
+	}
+	hook := &Webhook{
+		ID:        id[:12],
+		Owner:     username,
+		Global:    req.Global,
+		URL:       strings.TrimSpace(req.URL),
+		Events:    events,
+		Secret:    "whsec_" + secret,
+		CreatedAt: time.Now(),
+	}
+
+	webhooks.mu.Lock()
+	count := 0
+	for _, existing := range webhooks.hooks {
+		if existing.Owner == username {
+			count++
+		}
+	}
+	if count >= maxWebhooksPerUser {
+		webhooks.mu.Unlock()
+		return WebhookView{}, http.StatusUnprocessableEntity, []*utils.ValidationError{{
+			Field: "webhooks", Code: "too_many", Args: []interface{}{maxWebhooksPerUser},
+			Message: fmt.Sprintf("Nombre maximal de webhooks atteint (%d)", maxWebhooksPerUser),
+		}}
+	}
+	webhooks.hooks[hook.ID] = hook
+	view := webhooks.viewLocked(hook)
+	if err := webhooks.saveLocked(); err != nil {
+		slog.Error("Sauvegarde des webhooks impossible", "error", err)
+	}
+	webhooks.mu.Unlock()
+
+	slog.Info("Nouveau webhook", "webhook", hook.ID, "user", username, "url", hook.URL, "events", hook.Events, "global", hook.Global)
+	if hook.Global {
+		recordAudit(bc, username, "webhook_create", hook.ID, hook.URL)
+	}
+
+	view.Secret = hook.Secret
+	return view, http.StatusCreated, nil
+}
+
+// deleteWebhook supprime un webhook, ses livraisons en échec et son journal
+func deleteWebhook(bc *blockchain.Blockchain, username, id string) bool {
+	hook, ok := findWebhook(username, id)
+	if !ok {
+		return false
+	}
+
+	webhooks.mu.Lock()
+	delete(webhooks.hooks, id)
+	delete(webhooks.logs, id)
+	webhooks.deadLetters = slices.DeleteFunc(webhooks.deadLetters, func(delivery *WebhookDelivery) bool {
+		return delivery.WebhookID == id
+	})
+	if err := webhooks.saveLocked(); err != nil {
+		slog.Error("Sauvegarde des webhooks impossible", "error", err)
+	}
+	webhooks.mu.Unlock()
+
+	slog.Info("Webhook supprimé", "webhook", id, "user", username)
+	if hook.Global || hook.Owner != username {
+		recordAudit(bc, username, "webhook_delete", id, hook.URL)
+	}
+	return true
+}
+
+// pingWebhook envoie un événement de test au webhook
+func pingWebhook(hook *Webhook) *WebhookDelivery {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	delivery := webhooks.enqueueLocked(hook, WebhookPing, map[string]string{"webhookId": hook.ID})
+	if delivery == nil {
+		return nil
+	}
+	copied := *delivery
+	return &copied
+}
+
+// recentDeliveries retourne les livraisons récentes d'un webhook, de la plus récente à la plus ancienne
+func recentDeliveries(id string) []WebhookDelivery {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	log := webhooks.logs[id]
+	deliveries := make([]WebhookDelivery, len(log))
+	for i, delivery := range log {
+		deliveries[len(log)-1-i] = delivery
+	}
+	return deliveries
+}
+
+// deadLetters retourne les livraisons en échec d'un webhook
+func deadLetters(id string) []WebhookDelivery {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	deliveries := []WebhookDelivery{}
+	for _, delivery := range webhooks.deadLetters {
+		if delivery.WebhookID == id {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries
+}
+
+// redeliver relance les livraisons en échec d'un webhook, avec le même identifiant et le même
+// corps, et retourne leur état
+func redeliver(id string) []WebhookDelivery {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	var retried []*WebhookDelivery
+	webhooks.deadLetters = slices.DeleteFunc(webhooks.deadLetters, func(delivery *WebhookDelivery) bool {
+		if delivery.WebhookID != id {
+			return false
+		}
+		retried = append(retried, delivery)
+		return true
+	})
+
+	deliveries := make([]WebhookDelivery, 0, len(retried))
+	for _, delivery := range retried {
+		delivery.State = deliveryPending
+		delivery.Attempts = 0
+		webhooks.scheduleLocked(delivery)
+		deliveries = append(deliveries, *delivery)
+	}
+	if len(retried) > 0 {
+		if err := webhooks.saveLocked(); err != nil {
+			slog.Error("Sauvegarde des webhooks impossible", "error", err)
+		}
+		slog.Info("Livraisons relancées", "webhook", id, "count", len(retried))
+	}
+	return deliveries
+}
+
+// apiCreateWebhook enregistre un webhook pour l'utilisateur authentifié
+func apiCreateWebhook(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req WebhookRequest
+		if !decodeAPIBody(w, r, &req) {
+			return
+		}
+
+		view, status, errs := createWebhook(bc, apiUser(r), req)
+		switch status {
+		case http.StatusCreated:
+			writeAPIJSON(w, status, view)
+		case http.StatusForbidden:
+			writeAPIError(w, r, status, errForbidden, tr(r, "error.forbidden"))
+		case http.StatusInternalServerError:
+			writeAPIError(w, r, status, errInternal, tr(r, "error.webhook_creation"))
+		default:
+			writeAPIError(w, r, status, errValidation, tr(r, "error.webhook_rejected"), errs...)
+		}
+	}
+}
+
+// webhookHandler résout le webhook {id} de la route avant d'appeler next, ou répond 404
+func webhookHandler(next func(w http.ResponseWriter, r *http.Request, hook *Webhook)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hook, ok := findWebhook(apiUser(r), r.PathValue("id"))
+		if !ok {
+			writeAPIError(w, r, http.StatusNotFound, errNotFound, tr(r, "error.webhook_not_found"))
+			return
+		}
+		next(w, r, hook)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"BkC/i18n"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// layoutTemplate est le gabarit commun des pages : chacune définit les blocs "title", "head",
+// "bodyClass" et "body"
+const layoutTemplate = "layout.html"
+
+// staticPrefix est le chemin sous lequel les fichiers statiques sont servis
+const staticPrefix = "/static/"
+
+// assetHashLength est le nombre de caractères de l'empreinte ajoutée aux URL des fichiers statiques
+const assetHashLength = 12
+
+// assets contient les modèles et les fichiers statiques servis (voir InitAssets)
+var assets struct {
+	mu        sync.RWMutex
+	templates fs.FS
+	static    fs.FS
+	dev       bool                                     // Relire les fichiers à chaque requête
+	pages     map[string]map[string]*template.Template // Pages analysées au démarrage, par langue puis par nom
+	hashes    map[string]string                        // Empreinte du contenu des fichiers statiques, par chemin
+}
+
+// templateFuncs retourne les fonctions disponibles dans les modèles d'une langue :
+// asset (URL d'un fichier statique), t (message traduit), lang et languages
+func templateFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		"asset": assetURL,
+		"t": func(key string, args ...interface{}) string {
+			return i18n.T(lang, key, args...)
+		},
+		"lang":      func() string { return lang },
+		"languages": func() []string { return i18n.Supported },
+	}
+}
+
+// InitAssets installe les modèles et les fichiers statiques. Les pages sont analysées une fois
+// (une erreur de syntaxe empêche le démarrage) et les empreintes des fichiers statiques calculées,
+// sauf en mode développement où tout est relu depuis le disque à chaque requête.
+func InitAssets(templates, static fs.FS, dev bool) error {
+	var pages map[string]map[string]*template.Template
+	var hashes map[string]string
+	var err error
+	if !dev {
+		pages = make(map[string]map[string]*template.Template, len(i18n.Supported))
+		for _, lang := range i18n.Supported {
+			if pages[lang], err = parsePages(templates, lang); err != nil {
+				return err
+			}
+		}
+		if hashes, err = hashFiles(static); err != nil {
+			return err
+		}
+	}
+
+	assets.mu.Lock()
+	defer assets.mu.Unlock()
+	assets.templates = templates
+	assets.static = static
+	assets.dev = dev
+	assets.pages = pages
+	assets.hashes = hashes
+	return nil
+}
+
+// parsePages analyse chaque page avec le gabarit commun, pour une langue
+func parsePages(fsys fs.FS, lang string) (map[string]*template.Template, error) {
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		if name == layoutTemplate {
+			continue
+		}
+		if pages[name], err = parsePage(fsys, name, lang); err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// parsePage analyse une page avec le gabarit commun, pour une langue
+func parsePage(fsys fs.FS, name, lang string) (*template.Template, error) {
+	page, err := template.New(layoutTemplate).Funcs(templateFuncs(lang)).ParseFS(fsys, layoutTemplate, name)
+	if err != nil {
+		return nil, fmt.Errorf("modèle %s: %v", name, err)
+	}
+	return page, nil
+}
+
+// loadTemplate retourne une page prête à être exécutée, dans la langue de la requête
+func loadTemplate(r *http.Request, name string) (*template.Template, error) {
+	lang := localeOf(r)
+
+	assets.mu.RLock()
+	defer assets.mu.RUnlock()
+
+	page, ok := assets.pages[lang][name]
+	var err error
+	if assets.dev {
+		page, err = parsePage(assets.templates, name, lang)
+	} else if !ok {
+		err = fmt.Errorf("modèle %s introuvable", name)
+	}
+	if err != nil {
+		slog.Error("Chargement du modèle impossible", "template", name, "error", err)
+		return nil, err
+	}
+	return page, nil
+}
+
+// hashFiles calcule l'empreinte du contenu de chaque fichier
+func hashFiles(fsys fs.FS) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hashes[name] = hex.EncodeToString(sum[:])[:assetHashLength]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fichiers statiques: %v", err)
+	}
+	return hashes, nil
+}
+
+// assetURL retourne l'URL d'un fichier statique, dont le nom porte l'empreinte de son contenu
+// ("/static/js/home.3f2a9c0d1e4b.js") pour que le navigateur puisse la conserver en cache
+func assetURL(name string) string {
+	assets.mu.RLock()
+	defer assets.mu.RUnlock()
+
+	if hash, ok := assets.hashes[name]; ok {
+		return staticPrefix + fingerprint(name, hash)
+	}
+	return staticPrefix + name
+}
+
+// fingerprint insère l'empreinte dans le nom d'un fichier, avant son extension
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// splitFingerprint retrouve le nom du fichier et l'empreinte d'un nom produit par fingerprint
+func splitFingerprint(name string) (file, hash string, ok bool) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	i := strings.LastIndexByte(base, '.')
+	if i < 0 || len(base)-i-1 != assetHashLength {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(base[i+1:]); err != nil {
+		return "", "", false
+	}
+	return base[:i] + ext, base[i+1:], true
+}
+
+// StaticHandler sert les fichiers statiques sous /static/. Un nom portant l'empreinte courante
+// du fichier (voir assetURL) est mis en cache un an, et une empreinte périmée donne 404 plutôt
+// qu'un contenu différent sous la même URL ; le nom simple est revalidé grâce à l'ETag.
+func StaticHandler() http.Handler {
+	return http.StripPrefix(staticPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean(r.URL.Path)
+		assets.mu.RLock()
+		static := assets.static
+		hash, hashed := assets.hashes[name]
+		file, requested, fingerprinted := splitFingerprint(name)
+		current, known := assets.hashes[file]
+		assets.mu.RUnlock()
+
+		switch {
+		case hashed:
+			w.Header().Set("ETag", `"`+hash+`"`)
+			w.Header().Set("Cache-Control", "no-cache")
+		case fingerprinted && known:
+			if requested != current {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("ETag", `"`+current+`"`)
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			u := *r.URL
+			u.Path = file
+			r2 := *r
+			r2.URL = &u
+			r = &r2
+		default:
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		http.FileServerFS(static).ServeHTTP(w, r)
+	}))
+}
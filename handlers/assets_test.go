@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// testAssets installe des modèles et fichiers statiques de test, rétablis à la fin du test
+func testAssets(t *testing.T, static fstest.MapFS, dev bool) {
+	t.Helper()
+	assets.mu.RLock()
+	templates, previousStatic, previousDev, pages, hashes := assets.templates, assets.static, assets.dev, assets.pages, assets.hashes
+	assets.mu.RUnlock()
+	t.Cleanup(func() {
+		assets.mu.Lock()
+		assets.templates, assets.static, assets.dev, assets.pages, assets.hashes = templates, previousStatic, previousDev, pages, hashes
+		assets.mu.Unlock()
+	})
+
+	layout := fstest.MapFS{layoutTemplate: {Data: []byte(`{{block "body" .}}{{end}}`)}}
+	if err := InitAssets(layout, static, dev); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// contentHash retourne l'empreinte attendue d'un contenu
+func contentHash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])[:assetHashLength]
+}
+
+func TestStaticAssets(t *testing.T) {
+	const script = "console.log('accueil');"
+	testAssets(t, fstest.MapFS{
+		"js/home.js":          {Data: []byte(script)},
+		"stat.js":             {Data: []byte("stats();")},
+		"lib.0123456789ab.js": {Data: []byte("bibliothèque();")}, // Nom réel ressemblant à une empreinte
+	}, false)
+	hash := contentHash(script)
+
+	urls := []struct{ name, want string }{
+		{"js/home.js", "/static/js/home." + hash + ".js"},
+		{"stat.js", "/static/stat." + contentHash("stats();") + ".js"},
+		{"js/absent.js", "/static/js/absent.js"},
+	}
+	for _, tt := range urls {
+		if got := assetURL(tt.name); got != tt.want {
+			t.Errorf("assetURL(%q) = %q, attendu %q", tt.name, got, tt.want)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		ifNoneMatch string
+		status      int
+		cache       string // Cache-Control attendu
+		etag        string
+		body        string
+	}{
+		{"empreinte courante", "/static/js/home." + hash + ".js", "", http.StatusOK, "public, max-age=31536000, immutable", `"` + hash + `"`, script},
+		{"empreinte périmée", "/static/js/home.0123456789ab.js", "", http.StatusNotFound, "", "", ""},
+		{"empreinte d'un autre fichier", "/static/stat." + hash + ".js", "", http.StatusNotFound, "", "", ""},
+		{"nom simple", "/static/js/home.js", "", http.StatusOK, "no-cache", `"` + hash + `"`, script},
+		{"nom simple en cache", "/static/js/home.js", `"` + hash + `"`, http.StatusNotModified, "no-cache", `"` + hash + `"`, ""},
+		{"nom réel ressemblant à une empreinte", "/static/lib.0123456789ab.js", "", http.StatusOK, "no-cache", `"` + contentHash("bibliothèque();") + `"`, "bibliothèque();"},
+		{"fichier absent", "/static/js/absent.js", "", http.StatusNotFound, "", "", ""},
+		{"empreinte d'un fichier absent", "/static/js/absent.0123456789ab.js", "", http.StatusNotFound, "", "", ""},
+	}
+	handler := StaticHandler()
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", tt.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s : statut %d, attendu %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if cache := w.Header().Get("Cache-Control"); cache != tt.cache {
+			t.Errorf("%s : Cache-Control %q, attendu %q", tt.name, cache, tt.cache)
+		}
+		if etag := w.Header().Get("ETag"); etag != tt.etag {
+			t.Errorf("%s : ETag %q, attendu %q", tt.name, etag, tt.etag)
+		}
+		if tt.status == http.StatusOK {
+			if body := w.Body.String(); body != tt.body {
+				t.Errorf("%s : contenu %q, attendu %q", tt.name, body, tt.body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/javascript; charset=utf-8" {
+				t.Errorf("%s : Content-Type %q", tt.name, contentType)
+			}
+		}
+	}
+}
+
+func TestStaticAssetsDev(t *testing.T) {
+	testAssets(t, fstest.MapFS{"js/home.js": {Data: []byte("accueil();")}}, true)
+
+	if got := assetURL("js/home.js"); got != "/static/js/home.js" {
+		t.Errorf("assetURL = %q, attendu le nom simple en mode développement", got)
+	}
+	w := httptest.NewRecorder()
+	StaticHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/js/home.js", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-cache" || w.Header().Get("ETag") != "" {
+		t.Errorf("statut %d, en-têtes %v", w.Code, w.Header())
+	}
+}
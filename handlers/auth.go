@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// sessionCookieName est le nom du cookie portant le jeton de session opaque
+const sessionCookieName = "session"
+
+// lookupDevice retrouve la session et la connexion associées à un jeton.
+// L'appelant doit détenir mu.
+func lookupDevice(token string) (*utils.UserSession, *utils.DeviceSession) {
+	if token == "" {
+		return nil, nil
+	}
+
+	key := utils.HashToken(token)
+	for _, session := range sessions {
+		device, ok := session.Devices[key]
+		if !ok {
+			continue
+		}
+
+		// Un compte supprimé ou désactivé ne peut plus utiliser ses sessions
+		if user, exists := users[session.Username]; !exists || user.Disabled {
+			return nil, nil
+		}
+		return session, device
+	}
+	return nil, nil
+}
+
+// sessionFromRequest retourne la session et la connexion de l'utilisateur connecté
+func sessionFromRequest(r *http.Request) (*utils.UserSession, *utils.DeviceSession) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, nil
+	}
+
+	mu.Lock()
+	session, device := lookupDevice(cookie.Value)
+	mu.Unlock()
+
+	// Chaque requête authentifiée par cookie compte comme une activité de l'utilisateur
+	if session != nil {
+		presence.Touch(session.Username)
+		setRequestUser(r, session.Username)
+	}
+	return session, device
+}
+
+// currentUser retourne le compte de l'utilisateur connecté
+func currentUser(r *http.Request) (*utils.User, bool) {
+	session, _ := sessionFromRequest(r)
+	if session == nil {
+		return nil, false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	user, exists := users[session.Username]
+	return user, exists
+}
+
+// startSession ouvre une nouvelle connexion pour l'utilisateur et pose le cookie de session
+func startSession(w http.ResponseWriter, r *http.Request, username string) (*utils.UserSession, *utils.DeviceSession, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	csrfToken, err := generateToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientIP := utils.GetVisitorIP(r)
+	userAgent := r.Header.Get("User-Agent")
+	now := time.Now()
+
+	mu.Lock()
+	session, exists := sessions[username]
+	if !exists || session == nil {
+		session = &utils.UserSession{
+			Username:       username,
+			IsRegistered:   true,
+			MiningActivity: make(map[string]int), // Initialiser l'activité de minage
+		}
+		sessions[username] = session
+	}
+
+	// Mettre à jour la session avec informations réseau détaillées
+	session.IP = clientIP
+	session.NetworkInfo = utils.NewNetworkInfo(clientIP)
+	session.StartTime = now
+	session.LastSeen = now
+	session.UserAgent = userAgent
+	session.Visits++
+
+	if session.Devices == nil {
+		session.Devices = make(map[string]*utils.DeviceSession)
+	}
+	device := &utils.DeviceSession{
+		IP:        clientIP,
+		UserAgent: userAgent,
+		CreatedAt: now,
+		LastSeen:  now,
+		CSRFToken: csrfToken,
+	}
+	session.Devices[utils.HashToken(token)] = device
+	mu.Unlock()
+
+	presence.Touch(username)
+	setRequestUser(r, username)
+
+	// Sauvegarder les sessions
+	if err := SaveSessions(); err != nil {
+		slog.Error("Sauvegarde des sessions impossible", "error", err)
+	}
+
+	// Définir un cookie qui dure longtemps (1 an)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   31536000, // 365 jours en secondes
+		Expires:  now.AddDate(1, 0, 0),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return session, device, nil
+}
+
+// isSecureRequest indique si la requête est arrivée en HTTPS : les cookies ne sont alors
+// jamais renvoyés par le navigateur sur une connexion en clair
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil
+}
+
+// clearSessionCookie supprime le cookie de session du navigateur
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+}
+
+// revokeAllDevices déconnecte toutes les connexions d'un utilisateur.
+// L'appelant doit détenir mu.
+func revokeAllDevices(username string) {
+	session, exists := sessions[username]
+	if !exists || session == nil {
+		return
+	}
+
+	session.Devices = nil
+	session.LastSeen = time.Now()
+	presence.Forget(username)
+}
+
+// RequireRole restreint l'accès à un handler aux utilisateurs ayant au moins le rôle indiqué
+func RequireRole(role utils.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUser(r)
+		if !ok {
+			http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+			return
+		}
+
+		if !user.Role.Includes(role) {
+			slog.Warn("Accès refusé (rôle insuffisant)", "path", r.URL.Path, "user", user.Username, "role", user.Role)
+			http.Error(w, tr(r, "error.forbidden"), http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
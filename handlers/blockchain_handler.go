@@ -4,7 +4,6 @@ import (
 	"BkC/blockchain"
 	"BkC/utils"
 	"encoding/json"
-	"html/template"
 	"net/http"
 	"sync"
 	"time"
@@ -17,51 +16,31 @@ type BlockchainPageData struct {
 	Username  string
 	Blocks    []*blockchain.Block
 	LastBlock *blockchain.Block
+	CSRFToken string
 }
 
-// BlockchainHandler gère les requêtes sur la blockchain.
+// BlockchainHandler affiche la blockchain (page HTML, ou JSON avec Accept: application/json).
+// Le minage par POST /blockchain est servi par MineBlockHandler, derrière RequireAccess.
 func BlockchainHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		utils.LogRequest(r)
 		clientIP := utils.GetVisitorIP(r)
 
 		sessionMutex.Lock()
-		utils.ManageSession(clientIP, sessions, bc)
+		utils.ManageSession(clientIP, sessions, bc, settings.Difficulty.Sessions)
 		sessionMutex.Unlock()
 
 		// Récupérer l'utilisateur connecté (s'il y en a un)
 		var username string
-		cookie, err := r.Cookie("session")
-		if err == nil {
+		if session, device := sessionFromRequest(r); session != nil {
 			mu.Lock()
-			if session, exists := sessions[cookie.Value]; exists && session != nil {
-				username = session.Username
-				// Mettre à jour la dernière activité de l'utilisateur
-				session.LastSeen = time.Now()
-			}
+			username = session.Username
+			// Mettre à jour la dernière activité de l'utilisateur
+			session.LastSeen = time.Now()
+			device.LastSeen = session.LastSeen
 			mu.Unlock()
 		}
 
-		if r.Method == "POST" {
-			// Rediriger vers MineBlockHandler pour avoir une meilleure traçabilité
-			// des hashs générés par les utilisateurs
-			if err != nil || username == "" {
-				http.Error(w, "Vous devez être connecté pour générer un hash", http.StatusUnauthorized)
-				return
-			}
-
-			var input struct {
-				Data string `json:"data"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-				http.Error(w, "Données JSON invalides", http.StatusBadRequest)
-				return
-			}
-
-			// Récupérer le handler de minage et l'exécuter
-			mineHandler := MineBlockHandler(bc)
-			mineHandler(w, r)
-		} else if r.Method == "GET" {
+		if r.Method == "GET" {
 			// Vérifier si le client accepte du HTML (navigateur)
 			acceptHeader := r.Header.Get("Accept")
 			if acceptHeader == "application/json" {
@@ -79,17 +58,18 @@ func BlockchainHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 					Username:  username,
 					Blocks:    bc.Blocks,
 					LastBlock: lastBlock,
+					CSRFToken: CSRFToken(w, r),
 				}
 
-				tmpl, err := template.ParseFiles("templates/blockchain.html")
+				tmpl, err := loadTemplate(r, "blockchain.html")
 				if err != nil {
-					http.Error(w, "Erreur lors du chargement de la page blockchain", http.StatusInternalServerError)
+					http.Error(w, tr(r, "error.page.blockchain"), http.StatusInternalServerError)
 					return
 				}
 				tmpl.Execute(w, pageData)
 			}
 		} else {
-			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
 		}
 	}
 }
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBlockchainPostAccess(t *testing.T) {
+	cfg := testSettings(t)
+	cfg.Difficulty.Mining = 1
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	alice := &utils.User{Username: "alice", Role: utils.RoleMember}
+	testUsers(t, alice, &utils.User{Username: "lecteur", Role: utils.RoleReadOnly})
+	aliceSession := testSession(t, "alice")
+	readerSession := testSession(t, "lecteur")
+	t.Cleanup(func() {
+		mu.Lock()
+		revokeAPITokens("alice")
+		mu.Unlock()
+	})
+	mineToken, _, verr := createAPIToken(alice, "minage", []string{string(utils.ScopeMine)})
+	if verr != nil {
+		t.Fatal(verr)
+	}
+	readToken, _, verr := createAPIToken(alice, "lecture", []string{string(utils.ScopeReadChain)})
+	if verr != nil {
+		t.Fatal(verr)
+	}
+
+	// Routes enregistrées comme dans main.go
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blockchain", BlockchainHandler(chain))
+	mux.HandleFunc("POST /blockchain", RequireAccess(utils.RoleMember, utils.ScopeMine, MineBlockHandler(chain)))
+
+	tests := []struct {
+		name   string
+		method string
+		bearer string
+		cookie *http.Cookie
+		status int
+		mined  bool
+	}{
+		{"sans authentification", http.MethodPost, "", nil, http.StatusUnauthorized, false},
+		{"jeton de minage", http.MethodPost, mineToken.Token, nil, http.StatusOK, true},
+		{"jeton de lecture", http.MethodPost, readToken.Token, nil, http.StatusForbidden, false},
+		{"jeton inconnu", http.MethodPost, "bkc_inconnu", nil, http.StatusUnauthorized, false},
+		{"session", http.MethodPost, "", aliceSession, http.StatusOK, true},
+		{"compte en lecture seule", http.MethodPost, "", readerSession, http.StatusForbidden, false},
+		{"autre méthode", http.MethodPut, mineToken.Token, nil, http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		_, before := chain.GetBlocks(0, 0)
+		r := httptest.NewRequest(tt.method, "/blockchain", strings.NewReader(`{"data":"bloc de test"}`))
+		r.RemoteAddr = "198.51.100.30:4000"
+		if tt.bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		if tt.cookie != nil {
+			r.AddCookie(tt.cookie)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s : statut %d, attendu %d (%s)", tt.name, w.Code, tt.status, strings.TrimSpace(w.Body.String()))
+		}
+		if _, after := chain.GetBlocks(0, 0); (after > before) != tt.mined {
+			t.Errorf("%s : %d blocs avant, %d après", tt.name, before, after)
+		}
+	}
+	if blocks := chain.GetBlocksByMiner("alice"); len(blocks) != 2 {
+		t.Errorf("%d blocs minés par alice, attendu 2", len(blocks))
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Noms utilisés pour transporter le jeton CSRF
+const (
+	csrfCookieName = "csrf_token"   // Cookie portant le jeton des visiteurs non connectés
+	csrfHeaderName = "X-CSRF-Token" // En-tête envoyé par les appels fetch()
+	csrfFormField  = "csrf_token"   // Champ caché des formulaires HTML
+)
+
+var (
+	allowedOrigins   []string     // Origines autorisées pour les requêtes cross-origin et WebSocket
+	allowedOriginsMu sync.RWMutex // Protection de la liste des origines
+)
+
+// SetAllowedOrigins configure la liste des origines autorisées (ex: "http://localhost:8080")
+func SetAllowedOrigins(origins []string) {
+	cleaned := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			cleaned = append(cleaned, origin)
+		}
+	}
+
+	allowedOriginsMu.Lock()
+	allowedOrigins = cleaned
+	allowedOriginsMu.Unlock()
+}
+
+// isAllowedOrigin vérifie qu'une origine correspond à l'hôte servi ou à la liste configurée
+func isAllowedOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	// Les requêtes de même origine sont toujours acceptées
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	allowedOriginsMu.RLock()
+	defer allowedOriginsMu.RUnlock()
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebSocketOrigin est utilisé par l'upgrader WebSocket pour refuser les connexions cross-site
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Les clients non navigateurs n'envoient pas d'en-tête Origin
+		return true
+	}
+	if !isAllowedOrigin(origin, r) {
+		slog.Warn("Connexion WebSocket refusée", "origin", origin)
+		return false
+	}
+	return true
+}
+
+// generateToken génère un jeton aléatoire encodé en hexadécimal
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// expectedCSRFToken retourne le jeton attendu pour la requête :
+// celui de la session si l'utilisateur est connecté, sinon celui du cookie.
+func expectedCSRFToken(r *http.Request) string {
+	if _, device := sessionFromRequest(r); device != nil && device.CSRFToken != "" {
+		return device.CSRFToken
+	}
+
+	if cookie, err := r.Cookie(csrfCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// CSRFToken retourne le jeton CSRF à injecter dans les templates, en le créant si nécessaire
+func CSRFToken(w http.ResponseWriter, r *http.Request) string {
+	if token := expectedCSRFToken(r); token != "" {
+		return token
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		slog.Error("Génération du jeton CSRF impossible", "error", err)
+		return ""
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// verifyCSRF compare le jeton envoyé (en-tête ou champ de formulaire) au jeton attendu
+func verifyCSRF(r *http.Request) bool {
+	expected := expectedCSRFToken(r)
+	if expected == "" {
+		return false
+	}
+
+	sent := r.Header.Get(csrfHeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(csrfFormField)
+	}
+
+	return subtle.ConstantTimeCompare([]byte(sent), []byte(expected)) == 1
+}
+
+// CSRFMiddleware vérifie le jeton CSRF et l'origine de toutes les requêtes modifiant l'état
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" && !isAllowedOrigin(origin, r) {
+			slog.Warn("Requête refusée pour son origine", "method", r.Method, "path", r.URL.Path, "origin", origin)
+			httpError(w, r, http.StatusForbidden, errForbidden, tr(r, "error.origin_forbidden"))
+			return
+		}
+
+		// Les requêtes authentifiées par jeton d'API ne reposent pas sur les cookies :
+		// un site tiers ne peut pas forger l'en-tête Authorization
+		if hasBearerToken(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !verifyCSRF(r) {
+			slog.Warn("Jeton CSRF invalide", "method", r.Method, "path", r.URL.Path)
+			httpError(w, r, http.StatusForbidden, errForbidden, tr(r, "error.csrf"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/metrics"
+	"BkC/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Flux Server-Sent Events (GET /api/v1/events)
+const (
+	sseHeartbeat  = 15 * time.Second // Commentaire envoyé sans événement, pour garder la connexion ouverte
+	sseRetry      = 3 * time.Second  // Délai de reconnexion indiqué au navigateur
+	sseReplayPage = 100              // Blocs lus à la fois lors d'une reprise
+)
+
+// eventStreams suit les flux ouverts, fermés à l'arrêt du serveur (http.Server.Shutdown
+// attendrait sinon leur fin)
+var eventStreams struct {
+	open      atomic.Int64
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func init() {
+	eventStreams.closing = make(chan struct{})
+	metrics.NewGaugeFunc("bkc_sse_clients", "Flux Server-Sent Events ouverts.", func() float64 {
+		return float64(eventStreams.open.Load())
+	})
+}
+
+// CloseEventStreams termine les flux Server-Sent Events ; à enregistrer avec
+// http.Server.RegisterOnShutdown
+func CloseEventStreams() {
+	eventStreams.closeOnce.Do(func() { close(eventStreams.closing) })
+}
+
+// blockMessage retourne le message porté par un bloc de messagerie
+func blockMessage(block *blockchain.Block) (blockchain.Message, bool) {
+	var message blockchain.Message
+	if err := json.Unmarshal([]byte(block.Data), &message); err != nil || message.ID == "" {
+		return blockchain.Message{}, false
+	}
+	return message, true
+}
+
+// eventStream écrit les événements d'un flux Server-Sent Events
+type eventStream struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	username string
+}
+
+// write envoie un événement ; l'identifiant est l'index du bloc, renvoyé par le navigateur dans
+// l'en-tête Last-Event-ID lors d'une reconnexion
+func (s *eventStream) write(id int, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, payload)
+	return err
+}
+
+// writeBlock envoie les événements d'un bloc, comme le WebSocket : new_message pour un message
+// envoyé ou reçu par l'utilisateur, puis blockchain_update
+func (s *eventStream) writeBlock(block *blockchain.Block) error {
+	if message, ok := blockMessage(block); ok && (message.Sender == s.username || message.Recipient == s.username) {
+		if err := s.write(block.Index, "new_message", message); err != nil {
+			return err
+		}
+	}
+	return s.write(block.Index, "blockchain_update", map[string]interface{}{
+		"block_index": block.Index,
+		"block_hash":  block.Hash,
+		"timestamp":   block.Timestamp,
+	})
+}
+
+// writeBlocks envoie les blocs de from à to inclus et retourne l'index du dernier bloc envoyé
+func (s *eventStream) writeBlocks(bc *blockchain.Blockchain, from, to int) (int, error) {
+	sent := from - 1
+	for sent < to {
+		blocks, _ := bc.GetBlocks(sent+1, min(sseReplayPage, to-sent))
+		if len(blocks) == 0 {
+			break
+		}
+		for _, block := range blocks {
+			if err := s.writeBlock(block); err != nil {
+				return sent, err
+			}
+			sent = block.Index
+		}
+	}
+	return sent, s.rc.Flush()
+}
+
+// lastEventID lit l'index du dernier bloc reçu par le client : en-tête Last-Event-ID (envoyé par
+// le navigateur à la reconnexion) ou paramètre lastEventId ; -1 pour un nouveau flux
+func lastEventID(r *http.Request) (int, *utils.ValidationError) {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		raw = r.URL.Query().Get("lastEventId")
+	}
+	if raw == "" {
+		return -1, nil
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id < 0 {
+		return 0, &utils.ValidationError{Field: "lastEventId", Code: "invalid", Message: "lastEventId doit être un entier positif"}
+	}
+	return id, nil
+}
+
+// apiEvents diffuse les nouveaux blocs et les messages de l'utilisateur en Server-Sent Events.
+// Avec Last-Event-ID, les blocs suivant celui-ci sont d'abord renvoyés ; un commentaire est
+// envoyé toutes les sseHeartbeat en l'absence d'événement.
+func apiEvents(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lastID, verr := lastEventID(r)
+		if verr != nil {
+			writeAPIError(w, r, http.StatusBadRequest, errBadRequest, localizeError(r, verr).Message, verr)
+			return
+		}
+
+		// Abonnement avant la lecture de la chaîne : aucun bloc n'est perdu entre les deux
+		updates := bc.Subscribe()
+		defer bc.Unsubscribe(updates)
+
+		eventStreams.open.Add(1)
+		defer eventStreams.open.Add(-1)
+
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no") // Pas de mise en tampon par un proxy nginx
+		w.WriteHeader(http.StatusOK)
+
+		stream := &eventStream{w: w, rc: http.NewResponseController(w), username: apiUser(r)}
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+
+		_, total := bc.GetBlocks(0, 0)
+		sent := total - 1
+		if lastID >= 0 && lastID < sent {
+			var err error
+			if sent, err = stream.writeBlocks(bc, lastID+1, total-1); err != nil {
+				return
+			}
+		} else if err := stream.rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			var err error
+			select {
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				if update.Type != "new" || update.Block.Index <= sent {
+					continue
+				}
+				// Les blocs précédents sont relus dans la chaîne s'ils ont été perdus par un
+				// abonnement saturé
+				sent, err = stream.writeBlocks(bc, sent+1, update.Block.Index)
+			case <-heartbeat.C:
+				if _, err = fmt.Fprint(w, ": ping\n\n"); err == nil {
+					err = stream.rc.Flush()
+				}
+			case <-r.Context().Done():
+				return
+			case <-eventStreams.closing:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent est un événement lu sur un flux Server-Sent Events
+type sseEvent struct {
+	ID, Event, Data string
+}
+
+// openEventStream ouvre le flux /api/v1/events au nom de username et retourne ses événements.
+// header est l'en-tête Last-Event-ID envoyé ("" pour aucun), query la requête de l'URL.
+func openEventStream(t *testing.T, bc *blockchain.Blockchain, username, header, query string) (<-chan sseEvent, int) {
+	t.Helper()
+	handler := apiEvents(bc)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), authContextKey{}, &requestAuth{Username: username})
+		handler(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel) // Avant server.Close : le flux se termine avec la requête
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/events"+query, nil)
+	if header != "" {
+		req.Header.Set("Last-Event-ID", header)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan sseEvent, 64)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.Data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event.Event != "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return events, resp.StatusCode
+}
+
+// readEventsUntil lit les événements jusqu'au blockchain_update du bloc index, et les retourne
+// au format "index événement"
+func readEventsUntil(t *testing.T, events <-chan sseEvent, index int) []string {
+	t.Helper()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("flux fermé après %q", got)
+			}
+			got = append(got, event.ID+" "+event.Event)
+			if event.Event == "blockchain_update" && event.ID == fmt.Sprint(index) {
+				return got
+			}
+		case <-timeout:
+			t.Fatalf("bloc %d non reçu, événements %q", index, got)
+		}
+	}
+}
+
+func TestEventStreamFiltersMessages(t *testing.T) {
+	cfg := testSettings(t)
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	alice, _ := openEventStream(t, chain, "alice", "", "")
+	carol, _ := openEventStream(t, chain, "carol", "", "")
+
+	chain.AddMessageBlock(blockchain.CreateMessage("alice", "bob", "pour bob"), 1)   // Bloc 1
+	chain.AddMessageBlock(blockchain.CreateMessage("carol", "dave", "pour dave"), 1) // Bloc 2
+	chain.AddMessageBlock(blockchain.CreateMessage("bob", "alice", "pour alice"), 1) // Bloc 3
+
+	tests := []struct {
+		name   string
+		events <-chan sseEvent
+		want   []string
+	}{
+		{"alice", alice, []string{"1 new_message", "1 blockchain_update", "2 blockchain_update", "3 new_message", "3 blockchain_update"}},
+		{"carol", carol, []string{"1 blockchain_update", "2 new_message", "2 blockchain_update", "3 blockchain_update"}},
+	}
+	for _, tt := range tests {
+		if got := readEventsUntil(t, tt.events, 3); strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("%s : événements %q, attendu %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventStreamResume(t *testing.T) {
+	cfg := testSettings(t)
+
+	tests := []struct {
+		name   string
+		header string // Last-Event-ID
+		query  string
+		want   []string // Événements jusqu'au bloc 5, ajouté après l'ouverture du flux
+	}{
+		{"nouveau flux", "", "", []string{"5 blockchain_update"}},
+		{"reprise", "2", "", []string{"3 blockchain_update", "4 new_message", "4 blockchain_update", "5 blockchain_update"}},
+		{"reprise par paramètre", "", "?lastEventId=1", []string{"2 new_message", "2 blockchain_update", "3 blockchain_update", "4 new_message", "4 blockchain_update", "5 blockchain_update"}},
+		{"en-tête prioritaire", "3", "?lastEventId=0", []string{"4 new_message", "4 blockchain_update", "5 blockchain_update"}},
+		{"dernier bloc déjà reçu", "4", "", []string{"5 blockchain_update"}},
+		{"identifiant au-delà de la chaîne", "99", "", []string{"5 blockchain_update"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Blocs 1 à 4 ; les blocs 2 et 4 portent un message d'alice
+			chain := blockchain.NewBlockchain(filepath.Join(t.TempDir(), cfg.Files.Blockchain), 1)
+			chain.AddBlock("données 1", 1)
+			chain.AddMessageBlock(blockchain.CreateMessage("alice", "bob", "premier"), 1)
+			chain.AddBlock("données 3", 1)
+			chain.AddMessageBlock(blockchain.CreateMessage("bob", "alice", "réponse"), 1)
+
+			events, status := openEventStream(t, chain, "alice", tt.header, tt.query)
+			if status != http.StatusOK {
+				t.Fatalf("statut %d", status)
+			}
+			chain.AddBlock("données 5", 1)
+			if got := readEventsUntil(t, events, 5); strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("événements %q, attendu %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventStreamInvalidLastEventID(t *testing.T) {
+	cfg := testSettings(t)
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	for _, header := range []string{"abc", "-2"} {
+		if _, status := openEventStream(t, chain, "alice", header, ""); status != http.StatusBadRequest {
+			t.Errorf("Last-Event-ID %q : statut %d, attendu 400", header, status)
+		}
+	}
+}
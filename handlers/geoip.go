@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+// GeoCount est une entrée des répartitions par pays ou par fournisseur
+type GeoCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// LoadGeoIP charge les bases de géolocalisation et met à jour la localisation des sessions existantes
+func LoadGeoIP(paths []string) error {
+	if err := utils.LoadGeoIP(paths); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	for _, session := range sessions {
+		if session.IP != "" {
+			session.NetworkInfo = utils.NewNetworkInfo(session.IP)
+		}
+	}
+	mu.Unlock()
+
+	if err := SaveSessions(); err != nil {
+		slog.Error("Sauvegarde des sessions impossible", "error", err)
+	}
+	return nil
+}
+
+// ReloadGeoIP relit les bases de géolocalisation actuellement chargées (après leur mise à jour sur disque)
+func ReloadGeoIP() error {
+	return LoadGeoIP(utils.CurrentGeoIPStatus().Files)
+}
+
+// sessionNetworkInfo retourne la localisation d'une session, en la calculant au besoin
+// (sessions anonymes ou antérieures à la géolocalisation). L'appelant doit détenir mu.
+func sessionNetworkInfo(session *utils.UserSession) *utils.NetworkInfo {
+	if session.NetworkInfo == nil && session.IP != "" {
+		session.NetworkInfo = utils.NewNetworkInfo(session.IP)
+	}
+	return session.NetworkInfo
+}
+
+// sortedCounts convertit un décompte en liste triée par effectif décroissant, limitée à limit entrées
+func sortedCounts(counts map[string]int, limit int) []GeoCount {
+	list := make([]GeoCount, 0, len(counts))
+	for label, count := range counts {
+		list = append(list, GeoCount{Label: label, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Label < list[j].Label
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// AdminGeoIPHandler retourne les bases de géolocalisation chargées (GET) ou les recharge (POST)
+func AdminGeoIPHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if err := ReloadGeoIP(); err != nil {
+			slog.Error("Rechargement de la géolocalisation impossible", "error", err)
+			http.Error(w, tr(r, "error.geoip_reload", err), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(utils.CurrentGeoIPStatus())
+}
@@ -2,13 +2,14 @@ package handlers
 
 import (
 	"BkC/blockchain"
+	"BkC/config"
 	"BkC/utils"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -18,25 +19,54 @@ import (
 
 // Gestion des utilisateurs et des sessions.
 var (
-	users       = map[string]string{"admin": "admin"} // Utilisateurs autorisés sans hash (admin/admin)
-	sessions    = make(map[string]*utils.UserSession) // Stocke les sessions actives
-	mu          sync.Mutex                            // Protection contre les accès concurrents
-	usersOnline = 0                                   // Nombre d'utilisateurs en ligne
-	bc          *blockchain.Blockchain                // Référence globale à la blockchain
+	users    = defaultUsers()                      // Comptes enregistrés, indexés par nom d'utilisateur
+	sessions = make(map[string]*utils.UserSession) // Stocke les sessions actives
+	mu       sync.Mutex                            // Protection contre les accès concurrents
+	bc       *blockchain.Blockchain                // Référence globale à la blockchain
+	settings = config.Default()                    // Configuration du serveur (voir InitGlobalBC)
 )
 
-// InitGlobalBC initialise la référence globale à la blockchain
-func InitGlobalBC(blockchain *blockchain.Blockchain) {
+// defaultUsers retourne les comptes utilisés lorsqu'aucun fichier n'existe (admin/admin)
+func defaultUsers() map[string]*utils.User {
+	return map[string]*utils.User{
+		"admin": {
+			Username:     "admin",
+			PasswordHash: utils.HashPassword("admin"),
+			CreatedAt:    time.Now(),
+			Role:         utils.RoleAdmin,
+		},
+	}
+}
+
+// InitGlobalBC initialise la référence globale à la blockchain et la configuration,
+// puis charge les comptes, sessions et jetons depuis les fichiers configurés
+func InitGlobalBC(blockchain *blockchain.Blockchain, cfg *config.Config) {
 	bc = blockchain
+	settings = cfg
 
 	// Charger les utilisateurs au démarrage
 	if err := LoadUsers(); err != nil {
-		log.Printf("Erreur lors du chargement des utilisateurs: %v", err)
+		slog.Error("Chargement des utilisateurs impossible", "error", err)
 	}
 
 	// Charger les sessions au démarrage
 	if err := LoadSessions(); err != nil {
-		log.Printf("Erreur lors du chargement des sessions: %v", err)
+		slog.Error("Chargement des sessions impossible", "error", err)
+	}
+
+	// Charger les jetons d'API au démarrage
+	if err := LoadAPITokens(); err != nil {
+		slog.Error("Chargement des jetons impossible", "error", err)
+	}
+
+	// Charger les noms des comptes supprimés
+	if err := LoadRetiredUsers(); err != nil {
+		slog.Error("Chargement des comptes supprimés impossible", "error", err)
+	}
+
+	// Charger les webhooks au démarrage
+	if err := LoadWebhooks(); err != nil {
+		slog.Error("Chargement des webhooks impossible", "error", err)
 	}
 }
 
@@ -50,17 +80,17 @@ func SaveUsers() error {
 		return err
 	}
 
-	return ioutil.WriteFile("users.json", data, 0644)
+	return ioutil.WriteFile(settings.DataPath(settings.Files.Users), data, 0600) // Contient les empreintes et les secrets TOTP
 }
 
 // Charge les utilisateurs depuis un fichier.
 func LoadUsers() error {
-	if _, err := os.Stat("users.json"); os.IsNotExist(err) {
+	if _, err := os.Stat(settings.DataPath(settings.Files.Users)); os.IsNotExist(err) {
 		// Le fichier n'existe pas, on utilise les utilisateurs par défaut
 		return nil
 	}
 
-	data, err := ioutil.ReadFile("users.json")
+	data, err := ioutil.ReadFile(settings.DataPath(settings.Files.Users))
 	if err != nil {
 		return err
 	}
@@ -68,7 +98,38 @@ func LoadUsers() error {
 	mu.Lock()
 	defer mu.Unlock()
 
-	return json.Unmarshal(data, &users)
+	loadedUsers := make(map[string]*utils.User)
+	if err := json.Unmarshal(data, &loadedUsers); err != nil {
+		// Ancien format : nom d'utilisateur -> mot de passe en clair
+		var legacyUsers map[string]string
+		if legacyErr := json.Unmarshal(data, &legacyUsers); legacyErr != nil {
+			return err
+		}
+
+		for username, password := range legacyUsers {
+			role := utils.RoleMember
+			if username == "admin" {
+				role = utils.RoleAdmin
+			}
+			loadedUsers[username] = &utils.User{
+				Username:     username,
+				PasswordHash: utils.HashPassword(password),
+				CreatedAt:    time.Now(),
+				Role:         role,
+			}
+		}
+		slog.Info("Migration des utilisateurs vers le nouveau format", "count", len(legacyUsers))
+	}
+
+	for username, user := range loadedUsers {
+		user.Username = username
+		if _, ok := utils.ParseRole(string(user.Role)); !ok {
+			user.Role = utils.RoleMember
+		}
+	}
+	users = loadedUsers
+
+	return nil
 }
 
 // SaveSessions sauvegarde les sessions dans un fichier
@@ -90,17 +151,17 @@ func SaveSessions() error {
 		return fmt.Errorf("erreur lors de la sérialisation des sessions: %v", err)
 	}
 
-	return ioutil.WriteFile("sessions.json", data, 0644)
+	return ioutil.WriteFile(settings.DataPath(settings.Files.Sessions), data, 0644)
 }
 
 // LoadSessions charge les sessions depuis un fichier
 func LoadSessions() error {
-	if _, err := os.Stat("sessions.json"); os.IsNotExist(err) {
+	if _, err := os.Stat(settings.DataPath(settings.Files.Sessions)); os.IsNotExist(err) {
 		// Le fichier n'existe pas, on commence avec des sessions vides
 		return nil
 	}
 
-	data, err := ioutil.ReadFile("sessions.json")
+	data, err := ioutil.ReadFile(settings.DataPath(settings.Files.Sessions))
 	if err != nil {
 		return fmt.Errorf("erreur lors de la lecture du fichier de sessions: %v", err)
 	}
@@ -124,33 +185,21 @@ func LoadSessions() error {
 		}
 	}
 
-	// Mettre à jour le compteur d'utilisateurs en ligne
-	recountOnlineUsers()
-
 	return nil
 }
 
-// recountOnlineUsers recalcule le nombre d'utilisateurs en ligne
-func recountOnlineUsers() {
-	count := 0
-	for _, session := range sessions {
-		if session.Status == utils.StatusOnline {
-			count++
-		}
-	}
-	usersOnline = count
-}
-
 // LoginHandler affiche la page de connexion.
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("templates/login.html")
+	tmpl, err := loadTemplate(r, "login.html")
 	if err != nil {
-		http.Error(w, "Erreur lors du chargement de la page de connexion", http.StatusInternalServerError)
+		http.Error(w, tr(r, "error.page.login"), http.StatusInternalServerError)
 		return
 	}
 	tmpl.Execute(w, map[string]string{
-		"Error":   r.URL.Query().Get("error"),
-		"Message": r.URL.Query().Get("message"),
+		"Error":     r.URL.Query().Get("error"),
+		"Message":   r.URL.Query().Get("message"),
+		"Retry":     r.URL.Query().Get("retry"),
+		"CSRFToken": CSRFToken(w, r),
 	})
 }
 
@@ -166,67 +215,104 @@ func LoginSubmitHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Récupérer l'IP réelle du client
 	clientIP := utils.GetVisitorIP(r)
-	userAgent := r.Header.Get("User-Agent")
 
-	// Vérification du mot de passe (sans hash)
-	if pass, ok := users[username]; ok && pass == password {
-		mu.Lock()
-		// Créer la session avec informations réseau détaillées
-		networkInfo := utils.NewNetworkInfo(clientIP)
-		sessions[username] = &utils.UserSession{
-			Username:       username,
-			IP:             clientIP,
-			NetworkInfo:    networkInfo,
-			StartTime:      time.Now(),
-			LastSeen:       time.Now(),
-			Status:         utils.StatusOnline,
-			IsRegistered:   true,
-			UserAgent:      userAgent,
-			Visits:         1,                    // Première visite
-			MiningActivity: make(map[string]int), // Initialiser l'activité de minage
-		}
-		usersOnline++
-		mu.Unlock()
+	// Refuser la tentative si le compte ou l'IP est temporairement bloqué
+	if allowed, wait := checkLoginAllowed(username, clientIP); !allowed {
+		loginAttempts.Inc("blocked")
+		slog.Warn("Tentative de connexion bloquée", "user", username, "ip", clientIP, "retry_in", wait.Round(time.Second))
+		http.Redirect(w, r, fmt.Sprintf("/login?error=locked&retry=%d", retrySeconds(wait)), http.StatusSeeOther)
+		return
+	}
 
-		// Sauvegarder les sessions
-		if err := SaveSessions(); err != nil {
-			log.Printf("Erreur lors de la sauvegarde des sessions: %v", err)
-		}
+	// Vérification du mot de passe
+	mu.Lock()
+	user, ok := users[username]
+	valid := ok && !user.Disabled && user.CheckPassword(password)
+	twoFactor := valid && user.TOTPEnabled
+	mu.Unlock()
 
-		// Traquer la connexion dans la blockchain
-		utils.TrackVisitor(clientIP, true, sessions, bc)
+	if valid && twoFactor {
+		// Le mot de passe est correct : demander le second facteur avant d'ouvrir la session
+		if err := beginSecondFactor(w, r, username); err != nil {
+			http.Error(w, tr(r, "error.session_creation"), http.StatusInternalServerError)
+			return
+		}
+		loginAttempts.Inc("second_factor")
+		slog.Info("Second facteur demandé", "user", username, "ip", clientIP)
+		http.Redirect(w, r, "/login-2fa", http.StatusSeeOther)
+		return
+	}
 
-		// Log de connexion
-		log.Printf("👤 Connexion utilisateur: %s depuis %s [%s]", username, clientIP, networkInfo.CountryCode)
+	if valid {
+		completeLogin(w, r, username, clientIP)
+		return
+	}
 
-		// Définir un cookie qui dure longtemps (1 an)
-		http.SetCookie(w, &http.Cookie{
-			Name:    "session",
-			Value:   username,
-			Path:    "/",
-			MaxAge:  31536000, // 365 jours en secondes
-			Expires: time.Now().AddDate(1, 0, 0),
-		})
+	// Log tentative échouée et temporisation des tentatives suivantes
+	recordLoginFailure(username, clientIP)
+	loginAttempts.Inc("failure")
+	slog.Warn("Échec d'authentification", "user", username, "ip", clientIP)
+	http.Redirect(w, r, "/login?error=1", http.StatusSeeOther)
+}
 
-		http.Redirect(w, r, "/home", http.StatusSeeOther)
+// completeLogin ouvre la session d'un utilisateur authentifié et le redirige vers l'accueil
+func completeLogin(w http.ResponseWriter, r *http.Request, username, clientIP string) {
+	if _, err := openLoginSession(w, r, username, clientIP); err != nil {
+		http.Error(w, tr(r, "error.session_creation"), http.StatusInternalServerError)
 		return
 	}
+	http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
 
-	// Log tentative échouée
-	log.Printf("⚠️ Échec d'authentification pour l'utilisateur: %s depuis %s", username, clientIP)
-	http.Redirect(w, r, "/login?error=1", http.StatusSeeOther)
+// openLoginSession ouvre la session d'un utilisateur authentifié (formulaire ou API) et
+// retourne la connexion créée
+func openLoginSession(w http.ResponseWriter, r *http.Request, username, clientIP string) (*utils.DeviceSession, error) {
+	recordLoginSuccess(username)
+	loginAttempts.Inc("success")
+
+	mu.Lock()
+	if user, ok := users[username]; ok {
+		user.LastLogin = time.Now()
+	}
+	mu.Unlock()
+
+	session, device, err := startSession(w, r, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+
+	// Traquer la connexion dans la blockchain
+	utils.TrackVisitor(clientIP, true, sessions, bc, settings.Difficulty.Visits)
+
+	// Log de connexion
+	slog.Info("Connexion utilisateur", "user", username, "ip", clientIP, "country", session.NetworkInfo.CountryCode)
+
+	return device, nil
 }
 
 // SigninHandler affiche la page d'inscription.
 func SigninHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("templates/signin.html")
+	tmpl, err := loadTemplate(r, "signin.html")
 	if err != nil {
-		http.Error(w, "Erreur lors du chargement de la page d'inscription", http.StatusInternalServerError)
+		http.Error(w, tr(r, "error.page.signin"), http.StatusInternalServerError)
 		return
 	}
-	tmpl.Execute(w, map[string]string{
-		"Error": r.URL.Query().Get("error"),
-	})
+	data := map[string]string{
+		"Error":     r.URL.Query().Get("error"),
+		"Retry":     r.URL.Query().Get("retry"),
+		"CSRFToken": CSRFToken(w, r),
+	}
+	// Retrouver le motif précis du refus d'un nom d'utilisateur
+	if data["Error"] == "invalid_username" {
+		if err := utils.ValidateUsername(r.URL.Query().Get("username")); err != nil {
+			data["ErrorMessage"] = localizeError(r, err).Message
+		}
+	}
+	tmpl.Execute(w, data)
 }
 
 // SigninSubmitHandler traite le formulaire d'inscription.
@@ -240,132 +326,134 @@ func SigninSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	password := r.FormValue("password")
 	confirmPassword := r.FormValue("confirm_password")
 
-	// Récupérer l'IP et l'User-Agent
+	// Récupérer l'IP du client
 	clientIP := utils.GetVisitorIP(r)
-	userAgent := r.Header.Get("User-Agent")
+
+	// Vérifier le nom d'utilisateur (longueur, caractères, noms réservés)
+	if err := utils.ValidateUsername(username); err != nil {
+		http.Redirect(w, r, "/signin?error=invalid_username&username="+url.QueryEscape(username), http.StatusSeeOther)
+		return
+	}
 
 	// Vérifier que le mot de passe et sa confirmation correspondent
+	if password == "" {
+		http.Redirect(w, r, "/signin?error=password_required", http.StatusSeeOther)
+		return
+	}
 	if password != confirmPassword {
 		http.Redirect(w, r, "/signin?error=password_mismatch", http.StatusSeeOther)
 		return
 	}
 
-	// Vérifier que le nom d'utilisateur n'existe pas déjà
+	// Vérifier que le nom d'utilisateur n'existe pas déjà, sans tenir compte de la casse.
+	// Les noms des comptes supprimés restent réservés.
 	mu.Lock()
-	_, exists := users[username]
-	if exists {
+	if usernameTaken(username) {
 		mu.Unlock()
 		http.Redirect(w, r, "/signin?error=username_exists", http.StatusSeeOther)
 		return
 	}
 
-	// Ajouter l'utilisateur (sans hash)
-	users[username] = password
+	// Limiter le nombre d'inscriptions par IP
+	if allowed, wait := signupLimiter.Allow(clientIP); !allowed {
+		mu.Unlock()
+		slog.Warn("Inscription bloquée", "ip", clientIP, "retry_in", wait.Round(time.Second))
+		http.Redirect(w, r, fmt.Sprintf("/signin?error=too_many_signups&retry=%d", retrySeconds(wait)), http.StatusSeeOther)
+		return
+	}
+
+	// Ajouter l'utilisateur avec le rôle par défaut
+	now := time.Now()
+	users[username] = &utils.User{
+		Username:     username,
+		PasswordHash: utils.HashPassword(password),
+		CreatedAt:    now,
+		LastLogin:    now,
+		Role:         utils.RoleMember,
+	}
 	mu.Unlock()
 
 	// Sauvegarder les utilisateurs dans un fichier
 	if err := SaveUsers(); err != nil {
-		log.Printf("Erreur lors de la sauvegarde des utilisateurs: %v", err)
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
 	}
 
 	// Créer automatiquement la session et connecter l'utilisateur
-	mu.Lock()
-	// Créer la session avec informations réseau détaillées
-	networkInfo := utils.NewNetworkInfo(clientIP)
-	sessions[username] = &utils.UserSession{
-		Username:       username,
-		IP:             clientIP,
-		NetworkInfo:    networkInfo,
-		StartTime:      time.Now(),
-		LastSeen:       time.Now(),
-		Status:         utils.StatusOnline,
-		IsRegistered:   true,
-		UserAgent:      userAgent,
-		Visits:         1,                    // Première visite
-		MiningActivity: make(map[string]int), // Initialiser l'activité de minage
-	}
-	usersOnline++
-	mu.Unlock()
-
-	// Sauvegarder les sessions
-	if err := SaveSessions(); err != nil {
-		log.Printf("Erreur lors de la sauvegarde des sessions: %v", err)
+	session, _, err := startSession(w, r, username)
+	if err != nil {
+		http.Error(w, tr(r, "error.session_creation"), http.StatusInternalServerError)
+		return
 	}
 
 	// Enregistrer un nouveau bloc pour l'inscription
 	signupData := fmt.Sprintf("Inscription de %s depuis %s à %v", username, clientIP, time.Now())
-	bc.AddBlockAsync(signupData, 3) // Difficulté 3 pour l'inscription
+	bc.AddBlockAsync(signupData, settings.Difficulty.Signup)
 
 	// Log de la nouvelle inscription
-	log.Printf("✅ Nouvel utilisateur: %s depuis %s [%s]", username, clientIP, networkInfo.CountryCode)
-
-	// Définir un cookie qui dure longtemps (1 an)
-	http.SetCookie(w, &http.Cookie{
-		Name:    "session",
-		Value:   username,
-		Path:    "/",
-		MaxAge:  31536000, // 365 jours en secondes
-		Expires: time.Now().AddDate(1, 0, 0),
-	})
+	slog.Info("Nouvel utilisateur", "user", username, "ip", clientIP, "country", session.NetworkInfo.CountryCode)
 
 	// Rediriger vers la page d'accueil
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
 // LogoutHandler supprime la session et redirige vers la page de connexion.
+// La déconnexion n'est acceptée qu'en POST afin d'être protégée par le jeton CSRF.
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+		return
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
 	clientIP := utils.GetVisitorIP(r)
 
 	if err == nil {
 		mu.Lock()
-		session, exists := sessions[cookie.Value]
-		if exists {
+		session, _ := lookupDevice(cookie.Value)
+		if session != nil {
 			// Récupérer le nom d'utilisateur avant de supprimer la session
 			username := session.Username
-			log.Printf("🚪 Déconnexion utilisateur: %s depuis %s", username, clientIP)
+			slog.Info("Déconnexion utilisateur", "user", username, "ip", clientIP)
 
 			// Traquer la déconnexion
-			utils.TrackVisitor(clientIP, false, sessions, bc)
+			utils.TrackVisitor(clientIP, false, sessions, bc, settings.Difficulty.Visits)
 
-			// Marquer la session comme déconnecté plutôt que de la supprimer
-			session.Status = utils.StatusOffline
+			// Révoquer uniquement cette connexion
+			delete(session.Devices, utils.HashToken(cookie.Value))
 			session.LastSeen = time.Now()
 
-			if usersOnline > 0 {
-				usersOnline--
+			// Marquer l'utilisateur hors ligne plutôt que de supprimer la session
+			if len(session.Devices) == 0 {
+				presence.Forget(username)
 			}
 		}
 		mu.Unlock()
 
 		// Sauvegarder les sessions après modification
 		if err := SaveSessions(); err != nil {
-			log.Printf("Erreur lors de la sauvegarde des sessions: %v", err)
+			slog.Error("Sauvegarde des sessions impossible", "error", err)
 		}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:   "session",
-			Value:  "",
-			Path:   "/",
-			MaxAge: -1,
-		})
+		clearSessionCookie(w)
 	}
 
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
-// HomeHandler affiche la page d'accueil uniquement si l'utilisateur est connecté.
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
+// WelcomeHandler affiche la page d'accueil publique (acceuil.html)
+func WelcomeHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := loadTemplate(r, "acceuil.html")
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		http.Error(w, tr(r, "error.page.home"), http.StatusInternalServerError)
 		return
 	}
+	tmpl.Execute(w, nil)
+}
 
-	mu.Lock()
-	session, exists := sessions[cookie.Value]
-	mu.Unlock()
-	if !exists || session == nil {
+// HomeHandler affiche la page d'accueil uniquement si l'utilisateur est connecté.
+func HomeHandler(w http.ResponseWriter, r *http.Request) {
+	session, device := sessionFromRequest(r)
+	if session == nil {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
@@ -373,18 +461,25 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	// Mettre à jour l'heure de la dernière visite
 	mu.Lock()
 	session.LastSeen = time.Now()
+	device.LastSeen = session.LastSeen
+	isAdmin := users[session.Username].Role.Includes(utils.RoleAdmin)
 	mu.Unlock()
 
-	tmpl, err := template.ParseFiles("templates/home.html")
+	tmpl, err := loadTemplate(r, "home.html")
 	if err != nil {
-		http.Error(w, "Erreur lors du chargement de la page d'accueil", http.StatusInternalServerError)
+		http.Error(w, tr(r, "error.page.home"), http.StatusInternalServerError)
 		return
 	}
-	tmpl.Execute(w, map[string]string{
-		"Username": session.Username,
+	tmpl.Execute(w, map[string]interface{}{
+		"Username":  session.Username,
+		"IsAdmin":   isAdmin,
+		"CSRFToken": CSRFToken(w, r),
 	})
 }
 
+// statsTopEntries est le nombre de pays et de fournisseurs affichés dans les répartitions
+const statsTopEntries = 10
+
 // Stats structure pour les statistiques.
 type Stats struct {
 	VisitorCount       int                `json:"visitorCount"`
@@ -392,8 +487,11 @@ type Stats struct {
 	RegisteredUsers    int                `json:"registeredUsers"`
 	DailyTransactions  int                `json:"dailyTransactions"`
 	LastBlock          *blockchain.Block  `json:"lastBlock"`
+	ActiveLockouts     int                `json:"activeLockouts"` // Comptes et IP temporairement bloqués
 	OnlineUsers        []string           `json:"onlineUsers"`
 	RecentConnections  []RecentConnection `json:"recentConnections"` // Connexions récentes
+	Countries          []GeoCount         `json:"countries"`         // Répartition des visiteurs par pays
+	ISPs               []GeoCount         `json:"isps"`              // Répartition des visiteurs par fournisseur
 	TransactionHistory struct {
 		Dates  []string `json:"dates"`
 		Counts []int    `json:"counts"`
@@ -418,16 +516,8 @@ func StatsHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 		// Pour les requêtes API (AJAX/fetch), nous ne vérifions pas l'authentification
 		if !isXHR {
 			// Vérification d'authentification seulement pour l'affichage de la page
-			cookie, err := r.Cookie("session")
-			if err != nil {
-				http.Redirect(w, r, "/login", http.StatusSeeOther)
-				return
-			}
-
-			mu.Lock()
-			session, exists := sessions[cookie.Value]
-			mu.Unlock()
-			if !exists || session == nil {
+			session, device := sessionFromRequest(r)
+			if session == nil {
 				http.Redirect(w, r, "/login", http.StatusSeeOther)
 				return
 			}
@@ -435,92 +525,11 @@ func StatsHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 			// Mettre à jour l'heure de la dernière visite
 			mu.Lock()
 			session.LastSeen = time.Now()
+			device.LastSeen = session.LastSeen
 			mu.Unlock()
 		}
 
-		var lastBlock *blockchain.Block
-		if len(bc.Blocks) > 0 {
-			lastBlock = bc.Blocks[len(bc.Blocks)-1]
-		} else {
-			lastBlock = &blockchain.Block{
-				Index:     0,
-				Timestamp: "N/A",
-				Data:      "Aucun bloc",
-				Hash:      "N/A",
-				PrevHash:  "N/A",
-			}
-		}
-
-		// Récupérer la liste des utilisateurs en ligne et préparer les données anonymisées
-		mu.Lock()
-		onlineUsersList := make([]string, 0, len(sessions))
-		recentConnections := make([]RecentConnection, 0)
-
-		// Counter pour les visiteurs anonymes
-		anonymousCounter := 1
-
-		// Collecter les données
-		for username, session := range sessions {
-			onlineUsersList = append(onlineUsersList, username)
-
-			// Préparation des données pour l'affichage
-			displayName := username
-			if displayName == "" {
-				displayName = fmt.Sprintf("Visiteur-%d", anonymousCounter)
-				anonymousCounter++
-			}
-
-			// Préparer les données de connexion
-			country := "FR" // Par défaut
-			userAgent := "Inconnu"
-
-			if session.NetworkInfo != nil && session.NetworkInfo.CountryCode != "" {
-				country = session.NetworkInfo.CountryCode
-			}
-
-			if session.UserAgent != "" {
-				// Simplifier l'user agent pour l'affichage
-				userAgent = simplifyUserAgent(session.UserAgent)
-			}
-
-			lastAction := "Navigation"
-			if time.Since(session.LastSeen) < 5*time.Minute {
-				lastAction = "Actif"
-			} else if time.Since(session.LastSeen) < 30*time.Minute {
-				lastAction = "Inactif"
-			} else {
-				lastAction = "Déconnecté"
-			}
-
-			recentConnections = append(recentConnections, RecentConnection{
-				Username:   displayName,
-				Timestamp:  session.LastSeen,
-				Country:    country,
-				UserAgent:  userAgent,
-				LastAction: lastAction,
-			})
-		}
-
-		registeredUsers := len(users)
-		visitorCount := len(sessions) // Nombre total de sessions
-		mu.Unlock()
-
-		stats := Stats{
-			VisitorCount:      visitorCount,
-			ActiveSessions:    usersOnline,
-			RegisteredUsers:   registeredUsers,
-			DailyTransactions: len(bc.Blocks) - 1, // Moins le bloc genesis
-			LastBlock:         lastBlock,
-			OnlineUsers:       onlineUsersList,
-			RecentConnections: recentConnections,
-			TransactionHistory: struct {
-				Dates  []string `json:"dates"`
-				Counts []int    `json:"counts"`
-			}{
-				Dates:  []string{"Lundi", "Mardi", "Mercredi", "Jeudi", "Vendredi"},
-				Counts: []int{len(bc.Blocks) / 5, len(bc.Blocks) / 4, len(bc.Blocks) / 3, len(bc.Blocks) / 2, len(bc.Blocks)},
-			},
-		}
+		stats := CollectStats(bc)
 
 		// Pour les requêtes API, renvoyer les données en JSON
 		if isXHR {
@@ -530,15 +539,106 @@ func StatsHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 		}
 
 		// Pour les requêtes normales, renvoyer la page HTML
-		tmpl, err := template.ParseFiles("templates/stats.html")
+		tmpl, err := loadTemplate(r, "stats.html")
 		if err != nil {
-			http.Error(w, "Erreur lors du chargement de la page des statistiques", http.StatusInternalServerError)
+			http.Error(w, tr(r, "error.page.stats"), http.StatusInternalServerError)
 			return
 		}
 		tmpl.Execute(w, stats)
 	}
 }
 
+// CollectStats calcule les statistiques du serveur, affichées par /stats, /api/v1/stats et bkc stats
+func CollectStats(bc *blockchain.Blockchain) Stats {
+	var lastBlock *blockchain.Block
+	if len(bc.Blocks) > 0 {
+		lastBlock = bc.Blocks[len(bc.Blocks)-1]
+	} else {
+		lastBlock = &blockchain.Block{
+			Index:     0,
+			Timestamp: "N/A",
+			Data:      "Aucun bloc",
+			Hash:      "N/A",
+			PrevHash:  "N/A",
+		}
+	}
+
+	// Récupérer la liste des utilisateurs en ligne et préparer les données anonymisées
+	mu.Lock()
+	recentConnections := make([]RecentConnection, 0)
+	countries := make(map[string]int)
+	isps := make(map[string]int)
+
+	// Counter pour les visiteurs anonymes
+	anonymousCounter := 1
+
+	// Collecter les données
+	for username, session := range sessions {
+		// Préparation des données pour l'affichage
+		displayName := username
+		if displayName == "" {
+			displayName = fmt.Sprintf("Visiteur-%d", anonymousCounter)
+			anonymousCounter++
+		}
+
+		// Préparer les données de connexion
+		country := utils.UnknownCountry
+		userAgent := "Inconnu"
+
+		if info := sessionNetworkInfo(session); info != nil {
+			country = info.CountryCode
+			isps[info.ISP]++
+		}
+		countries[country]++
+
+		if session.UserAgent != "" {
+			// Simplifier l'user agent pour l'affichage
+			userAgent = simplifyUserAgent(session.UserAgent)
+		}
+
+		lastAction := "Navigation"
+		if time.Since(session.LastSeen) < 5*time.Minute {
+			lastAction = "Actif"
+		} else if time.Since(session.LastSeen) < 30*time.Minute {
+			lastAction = "Inactif"
+		} else {
+			lastAction = "Déconnecté"
+		}
+
+		recentConnections = append(recentConnections, RecentConnection{
+			Username:   displayName,
+			Timestamp:  session.LastSeen,
+			Country:    country,
+			UserAgent:  userAgent,
+			LastAction: lastAction,
+		})
+	}
+
+	registeredUsers := len(users)
+	visitorCount := len(sessions) // Nombre total de sessions
+	mu.Unlock()
+
+	return Stats{
+		VisitorCount:      visitorCount,
+		ActiveSessions:    presence.OnlineCount(),
+		RegisteredUsers:   registeredUsers,
+		DailyTransactions: len(bc.Blocks) - 1, // Moins le bloc genesis
+		LastBlock:         lastBlock,
+		ActiveLockouts:    len(currentLockouts()),
+		OnlineUsers:       presence.OnlineUsers(),
+		Countries:         sortedCounts(countries, statsTopEntries),
+		ISPs:              sortedCounts(isps, statsTopEntries),
+		RecentConnections: recentConnections,
+		TransactionHistory: struct {
+			Dates  []string `json:"dates"`
+			Counts []int    `json:"counts"`
+		}{
+			Dates:  []string{"Lundi", "Mardi", "Mercredi", "Jeudi", "Vendredi"},
+			Counts: []int{len(bc.Blocks) / 5, len(bc.Blocks) / 4, len(bc.Blocks) / 3, len(bc.Blocks) / 2, len(bc.Blocks)},
+		},
+	}
+}
+
 // simplifyUserAgent simplifie la chaîne User-Agent pour l'affichage
 func simplifyUserAgent(ua string) string {
 	if strings.Contains(ua, "Chrome") {
@@ -559,23 +659,15 @@ func simplifyUserAgent(ua string) string {
 // MineBlockHandler permet aux utilisateurs de miner un nouveau bloc
 func MineBlockHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Vérifier si l'utilisateur est connecté
-		cookie, err := r.Cookie("session")
-		if err != nil {
-			http.Error(w, "Vous devez être connecté pour miner", http.StatusUnauthorized)
-			return
-		}
-
-		mu.Lock()
-		session, exists := sessions[cookie.Value]
-		mu.Unlock()
-		if !exists || session == nil {
-			http.Error(w, "Session non valide", http.StatusUnauthorized)
+		// Vérifier si l'utilisateur est connecté (session ou jeton d'API)
+		username, ok := getLoggedInUser(r)
+		if !ok {
+			http.Error(w, tr(r, "error.login_to_mine"), http.StatusUnauthorized)
 			return
 		}
 
 		if r.Method != "POST" {
-			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
 			return
 		}
 
@@ -584,56 +676,59 @@ func MineBlockHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 			Data string `json:"data"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&mineRequest); err != nil {
-			http.Error(w, "Données JSON invalides", http.StatusBadRequest)
+			http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
 			return
 		}
 
-		// Récupérer le nom du mineur
-		username := session.Username
+		newBlock := MineBlock(bc, username, mineRequest.Data)
 
-		// Créer un message formaté incluant les informations utilisateur
-		messageData := fmt.Sprintf("%s (par %s à %s)",
-			mineRequest.Data,
-			username,
-			time.Now().Format("15:04:05 02/01/2006"))
+		// Répondre avec un succès et les informations du bloc
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "success",
+			"message":  tr(r, "success.block_mined"),
+			"miner":    username,
+			"blockId":  newBlock.Index,
+			"hash":     newBlock.Hash,
+			"nonce":    newBlock.Nonce,
+			"prevHash": newBlock.PrevHash,
+		})
+	}
+}
 
-		// Difficulté du minage
-		difficulty := 4
+// MineBlock mine de façon synchrone un bloc signé par username et met à jour son activité de minage
+func MineBlock(bc *blockchain.Blockchain, username, data string) *blockchain.Block {
+	// Créer un message formaté incluant les informations utilisateur
+	messageData := fmt.Sprintf("%s (par %s à %s)",
+		data,
+		username,
+		time.Now().Format("15:04:05 02/01/2006"))
 
-		// Traçabilité: ajouter le bloc avec informations sur le mineur
-		newBlock := bc.AddBlockWithMiner(messageData, difficulty, username)
+	// Traçabilité: ajouter le bloc avec informations sur le mineur
+	newBlock := bc.AddBlockWithMiner(messageData, settings.Difficulty.Mining, username)
 
-		// Mettre à jour la dernière action de l'utilisateur
-		mu.Lock()
-		// Mettre à jour la session avec les informations de minage
+	// Mettre à jour la dernière action de l'utilisateur
+	mu.Lock()
+	// Mettre à jour la session avec les informations de minage
+	if session, exists := sessions[username]; exists {
 		session.LastSeen = time.Now()
 		if session.MiningActivity == nil {
 			session.MiningActivity = make(map[string]int)
 		}
 		session.MiningActivity["blocksMinés"] = session.MiningActivity["blocksMinés"] + 1
 		session.MiningActivity["dernierMinage"] = int(time.Now().Unix())
-		mu.Unlock()
+	}
+	mu.Unlock()
 
-		// Sauvegarder les sessions après le minage
-		if err := SaveSessions(); err != nil {
-			log.Printf("Erreur lors de la sauvegarde des sessions: %v", err)
-		}
+	// Sauvegarder les sessions après le minage
+	if err := SaveSessions(); err != nil {
+		slog.Error("Sauvegarde des sessions impossible", "error", err)
+	}
 
-		// Log dans la console
-		log.Printf("🔗 Nouveau hash généré par %s: %.8s... (bloc #%d)", username, newBlock.Hash, newBlock.Index)
+	// Log dans la console
+	slog.Info("Bloc miné", "user", username, "index", newBlock.Index, "hash", newBlock.Hash)
 
-		// Répondre avec un succès et les informations du bloc
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":   "success",
-			"message":  "Votre hash a été ajouté à la blockchain avec succès!",
-			"miner":    username,
-			"blockId":  newBlock.Index,
-			"hash":     newBlock.Hash,
-			"nonce":    newBlock.Nonce,
-			"prevHash": newBlock.PrevHash,
-		})
-	}
+	return newBlock
 }
 
 // MinerStats représente les statistiques d'un mineur pour le classement
@@ -646,43 +741,48 @@ type MinerStats struct {
 // MinersStatsHandler renvoie les statistiques de minage de tous les utilisateurs
 func MinersStatsHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Calculer les statistiques de minage à partir des sessions enregistrées
-		mu.Lock()
-		minerStats := make([]MinerStats, 0, len(sessions))
-
-		for _, session := range sessions {
-			if session.IsRegistered {
-				blocksMined := 0
-				lastMiningTime := int64(0)
-
-				if session.MiningActivity != nil {
-					blocksMined = session.MiningActivity["blocksMinés"]
-					lastMiningTime = int64(session.MiningActivity["dernierMinage"])
-				}
-
-				// Compter également les blocs minés dans la blockchain
-				userBlocks := bc.GetBlocksByMiner(session.Username)
-				blocksMined = len(userBlocks) // Utiliser le compte de la blockchain
-
-				// Ajouter seulement les utilisateurs qui ont miné des blocs
-				if blocksMined > 0 {
-					minerStats = append(minerStats, MinerStats{
-						Username:       session.Username,
-						BlocksMined:    blocksMined,
-						LastMiningTime: lastMiningTime,
-					})
-				}
+		// Renvoyer les données en JSON
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CollectMinerStats(bc))
+	}
+}
+
+// CollectMinerStats retourne les mineurs classés par nombre de blocs minés (décroissant)
+func CollectMinerStats(bc *blockchain.Blockchain) []MinerStats {
+	// Calculer les statistiques de minage à partir des sessions enregistrées
+	mu.Lock()
+	minerStats := make([]MinerStats, 0, len(sessions))
+
+	for _, session := range sessions {
+		if session.IsRegistered {
+			blocksMined := 0
+			lastMiningTime := int64(0)
+
+			if session.MiningActivity != nil {
+				blocksMined = session.MiningActivity["blocksMinés"]
+				lastMiningTime = int64(session.MiningActivity["dernierMinage"])
+			}
+
+			// Compter également les blocs minés dans la blockchain
+			userBlocks := bc.GetBlocksByMiner(session.Username)
+			blocksMined = len(userBlocks) // Utiliser le compte de la blockchain
+
+			// Ajouter seulement les utilisateurs qui ont miné des blocs
+			if blocksMined > 0 {
+				minerStats = append(minerStats, MinerStats{
+					Username:       session.Username,
+					BlocksMined:    blocksMined,
+					LastMiningTime: lastMiningTime,
+				})
 			}
 		}
-		mu.Unlock()
+	}
+	mu.Unlock()
 
-		// Trier les mineurs par nombre de blocs minés (décroissant)
-		sort.Slice(minerStats, func(i, j int) bool {
-			return minerStats[i].BlocksMined > minerStats[j].BlocksMined
-		})
+	// Trier les mineurs par nombre de blocs minés (décroissant)
+	sort.Slice(minerStats, func(i, j int) bool {
+		return minerStats[i].BlocksMined > minerStats[j].BlocksMined
+	})
 
-		// Renvoyer les données en JSON
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(minerStats)
-	}
+	return minerStats
 }
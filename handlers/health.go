@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// startedAt est la date de démarrage du processus, pour /healthz
+var startedAt = time.Now()
+
+// Résultats des vérifications
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// HealthCheck est le résultat d'une vérification de /readyz
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthReport est la réponse de /healthz et /readyz
+type HealthReport struct {
+	Status        string        `json:"status"`
+	UptimeSeconds int64         `json:"uptimeSeconds"`
+	Checks        []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthzHandler indique que le processus répond (sonde de vivacité)
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	writeHealthReport(w, HealthReport{
+		Status:        healthOK,
+		UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+	})
+}
+
+// ReadyzHandler indique si le nœud peut servir des requêtes (sonde de disponibilité) : chaîne chargée
+// et valide, stockage accessible en écriture, diffusion des mises à jour active, aucun minage bloqué
+// et pas d'arrêt en cours. La réponse est 503 si une vérification échoue.
+func ReadyzHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+			return
+		}
+
+		health := bc.Health()
+		report := HealthReport{
+			Status:        healthOK,
+			UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+			Checks: []HealthCheck{
+				checkChain(health),
+				checkStorage(),
+				checkDispatcher(health),
+				checkMining(health),
+				checkShutdown(health),
+			},
+		}
+		for _, check := range report.Checks {
+			if check.Status != healthOK {
+				report.Status = healthFail
+			}
+		}
+		writeHealthReport(w, report)
+	}
+}
+
+// checkChain vérifie que la chaîne a été lue et validée au démarrage
+func checkChain(health blockchain.Health) HealthCheck {
+	check := HealthCheck{Name: "chain", Status: healthOK, Detail: fmt.Sprintf("%d blocs", health.Height)}
+	switch {
+	case health.LoadError != nil:
+		check.Status = healthFail
+		check.Detail = "chargement impossible: " + health.LoadError.Error()
+	case health.ValidationError != nil:
+		check.Status = healthFail
+		check.Detail = "chaîne invalide: " + health.ValidationError.Error()
+	}
+	return check
+}
+
+// checkStorage vérifie que le répertoire des données est accessible en écriture
+func checkStorage() HealthCheck {
+	check := HealthCheck{Name: "storage", Status: healthOK, Detail: settings.DataDir}
+	file, err := os.CreateTemp(settings.DataDir, ".readyz-*")
+	if err == nil {
+		_, err = file.WriteString("ok")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		os.Remove(file.Name())
+	}
+	if err != nil {
+		check.Status = healthFail
+		check.Detail = "écriture impossible: " + err.Error()
+	}
+	return check
+}
+
+// checkDispatcher vérifie que les nouveaux blocs sont diffusés aux abonnés
+func checkDispatcher(health blockchain.Health) HealthCheck {
+	check := HealthCheck{
+		Name:   "dispatcher",
+		Status: healthOK,
+		Detail: fmt.Sprintf("%d/%d mises à jour en attente", health.PendingUpdates, health.UpdateQueueSize),
+	}
+	switch {
+	case !health.DispatcherRunning:
+		check.Status = healthFail
+		check.Detail = "diffusion des mises à jour arrêtée"
+	case health.PendingUpdates >= health.UpdateQueueSize:
+		check.Status = healthFail
+		check.Detail = "file des mises à jour pleine"
+	}
+	return check
+}
+
+// checkMining vérifie qu'aucun minage asynchrone n'est en attente depuis trop longtemps
+func checkMining(health blockchain.Health) HealthCheck {
+	check := HealthCheck{Name: "mining", Status: healthOK, Detail: fmt.Sprintf("%d minage(s) en attente", health.PendingJobs)}
+	if health.OldestJob > blockchain.StuckJobAfter {
+		check.Status = healthFail
+		check.Detail = fmt.Sprintf("minage bloqué depuis %v (%d en attente)", health.OldestJob.Round(time.Second), health.PendingJobs)
+	}
+	return check
+}
+
+// checkShutdown signale un arrêt en cours pour que le superviseur cesse d'envoyer du trafic
+func checkShutdown(health blockchain.Health) HealthCheck {
+	if health.Draining {
+		return HealthCheck{Name: "shutdown", Status: healthFail, Detail: "arrêt en cours"}
+	}
+	return HealthCheck{Name: "shutdown", Status: healthOK}
+}
+
+// writeHealthReport écrit un rapport, avec le code 503 s'il est en échec
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != healthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
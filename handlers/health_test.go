@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, dataFile string) *blockchain.Blockchain
+		status  int
+		failed  map[string]bool // Vérifications en échec
+	}{
+		{"nœud prêt", func(t *testing.T, dataFile string) *blockchain.Blockchain {
+			return blockchain.NewBlockchain(dataFile, 1)
+		}, http.StatusOK, nil},
+		{"fichier de la chaîne illisible", func(t *testing.T, dataFile string) *blockchain.Blockchain {
+			if err := os.WriteFile(dataFile, []byte("[{"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return blockchain.NewBlockchain(dataFile, 1)
+		}, http.StatusServiceUnavailable, map[string]bool{"chain": true}},
+		{"chaîne altérée", func(t *testing.T, dataFile string) *blockchain.Blockchain {
+			chain := blockchain.NewBlockchain(dataFile, 1)
+			chain.AddBlock("données d'origine", 1)
+			blocks, err := blockchain.ReadBlocksFile(dataFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks[1].Data = "données modifiées"
+			if err := blockchain.WriteBlocksFile(dataFile, blocks); err != nil {
+				t.Fatal(err)
+			}
+			return blockchain.NewBlockchain(dataFile, 1)
+		}, http.StatusServiceUnavailable, map[string]bool{"chain": true}},
+		{"arrêt en cours", func(t *testing.T, dataFile string) *blockchain.Blockchain {
+			chain := blockchain.NewBlockchain(dataFile, 1)
+			if err := chain.Drain(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return chain
+		}, http.StatusServiceUnavailable, map[string]bool{"shutdown": true}},
+		{"stockage inaccessible", func(t *testing.T, dataFile string) *blockchain.Blockchain {
+			settings.DataDir = filepath.Join(settings.DataDir, "absent")
+			return blockchain.NewBlockchain(dataFile, 1)
+		}, http.StatusServiceUnavailable, map[string]bool{"storage": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testSettings(t)
+			chain := tt.prepare(t, filepath.Join(cfg.DataDir, cfg.Files.Blockchain))
+
+			for _, method := range []string{http.MethodGet, http.MethodHead} {
+				w := httptest.NewRecorder()
+				ReadyzHandler(chain)(w, httptest.NewRequest(method, "/readyz", nil))
+				if w.Code != tt.status {
+					t.Errorf("%s : statut %d, attendu %d (%s)", method, w.Code, tt.status, w.Body)
+				}
+				if w.Header().Get("Cache-Control") != "no-store" {
+					t.Errorf("%s : Cache-Control %q", method, w.Header().Get("Cache-Control"))
+				}
+				if method == http.MethodHead {
+					continue
+				}
+
+				var report HealthReport
+				if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+					t.Fatalf("rapport illisible %s", w.Body)
+				}
+				if want := map[bool]string{true: healthOK, false: healthFail}[tt.status == http.StatusOK]; report.Status != want {
+					t.Errorf("état %q, attendu %q", report.Status, want)
+				}
+				if len(report.Checks) != 5 {
+					t.Errorf("%d vérifications, attendu 5", len(report.Checks))
+				}
+				for _, check := range report.Checks {
+					if failed := check.Status == healthFail; failed != tt.failed[check.Name] {
+						t.Errorf("vérification %s : %s (%s)", check.Name, check.Status, check.Detail)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHealthzDuringDrain(t *testing.T) {
+	cfg := testSettings(t)
+	chain := blockchain.NewBlockchain(filepath.Join(cfg.DataDir, cfg.Files.Blockchain), 1)
+	if err := chain.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// La sonde de vivacité reste à 200 pendant l'arrêt : seul /readyz retire le nœud du trafic
+	w := httptest.NewRecorder()
+	HealthzHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/healthz : statut %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	ReadyzHandler(chain)(w, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /readyz : statut %d", w.Code)
+	}
+}
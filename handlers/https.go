@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPSRedirectHandler redirige les requêtes en clair vers le serveur HTTPS écoutant sur httpsAddr,
+// en conservant l'hôte demandé, le chemin et les paramètres
+func HTTPSRedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		// 308 conserve la méthode et le corps des requêtes autres que GET
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
+
+// hstsMaxAge est la durée pendant laquelle le navigateur n'utilise plus que HTTPS pour l'hôte
+const hstsMaxAge = 365 * 24 * time.Hour
+
+// HSTSMiddleware ajoute l'en-tête Strict-Transport-Security aux réponses servies en HTTPS.
+// Derrière un proxy qui termine TLS, c'est au proxy de l'envoyer.
+func HSTSMiddleware(next http.Handler) http.Handler {
+	value := "max-age=" + strconv.Itoa(int(hstsMaxAge.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// startTestHTTPS démarre un serveur HTTPS avec un certificat autosigné généré pour le test
+// et retourne un client qui lui fait confiance
+func startTestHTTPS(t *testing.T, handler http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+	dir := t.TempDir()
+	cert, err := utils.LoadCertificate(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), true, []string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	t.Cleanup(client.CloseIdleConnections)
+	return server, client
+}
+
+func TestHSTSOnlyOverHTTPS(t *testing.T) {
+	handler := HSTSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	httpsServer, httpsClient := startTestHTTPS(t, handler)
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	tests := []struct {
+		name   string
+		client *http.Client
+		url    string
+		want   string
+	}{
+		{"HTTPS", httpsClient, httpsServer.URL, "max-age=31536000"},
+		{"HTTP", http.DefaultClient, httpServer.URL, ""},
+	}
+	for _, tt := range tests {
+		resp, err := tt.client.Get(tt.url + "/home")
+		if err != nil {
+			t.Fatalf("%s : %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if hsts := resp.Header.Get("Strict-Transport-Security"); hsts != tt.want {
+			t.Errorf("%s : Strict-Transport-Security %q, attendu %q", tt.name, hsts, tt.want)
+		}
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsAddr string
+		method    string
+		host      string
+		target    string
+		status    int
+		location  string
+	}{
+		{"GET", ":8443", http.MethodGet, "bkc.exemple.fr", "/messages?with=bob", http.StatusMovedPermanently, "https://bkc.exemple.fr:8443/messages?with=bob"},
+		{"HEAD", ":8443", http.MethodHead, "bkc.exemple.fr", "/", http.StatusMovedPermanently, "https://bkc.exemple.fr:8443/"},
+		{"POST conservé", ":8443", http.MethodPost, "bkc.exemple.fr", "/mine-block", http.StatusPermanentRedirect, "https://bkc.exemple.fr:8443/mine-block"},
+		{"port HTTP remplacé", ":8443", http.MethodGet, "bkc.exemple.fr:8080", "/stats", http.StatusMovedPermanently, "https://bkc.exemple.fr:8443/stats"},
+		{"port 443 omis", ":443", http.MethodGet, "bkc.exemple.fr:80", "/stats", http.StatusMovedPermanently, "https://bkc.exemple.fr/stats"},
+		{"adresse IPv6", "[::]:8443", http.MethodGet, "[::1]:8080", "/", http.StatusMovedPermanently, "https://[::1]:8443/"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		HTTPSRedirectHandler(tt.httpsAddr).ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s : %d %q, attendu %d %q", tt.name, w.Code, w.Header().Get("Location"), tt.status, tt.location)
+		}
+		if hsts := w.Header().Get("Strict-Transport-Security"); hsts != "" {
+			t.Errorf("%s : en-tête HSTS envoyé en HTTP", tt.name)
+		}
+	}
+}
+
+func TestHTTPSRedirectEndToEnd(t *testing.T) {
+	httpsServer, client := startTestHTTPS(t, HSTSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI())
+	})))
+	_, port, _ := net.SplitHostPort(httpsServer.Listener.Addr().String())
+	redirect := httptest.NewServer(HTTPSRedirectHandler(":" + port))
+	defer redirect.Close()
+
+	// Le client suit la redirection du port HTTP vers le serveur HTTPS, en conservant la méthode
+	_, httpPort, _ := net.SplitHostPort(redirect.Listener.Addr().String())
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, _ := http.NewRequest(method, "http://localhost:"+httpPort+"/blockchain?page=2", strings.NewReader("{}"))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s : %v", method, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.TLS == nil || resp.Request.URL.String() != "https://localhost:"+port+"/blockchain?page=2" {
+			t.Errorf("%s : URL finale %s", method, resp.Request.URL)
+		}
+		if want := method + " /blockchain?page=2"; string(body) != want {
+			t.Errorf("%s : réponse %q, attendu %q", method, body, want)
+		}
+		if resp.Header.Get("Strict-Transport-Security") == "" {
+			t.Errorf("%s : en-tête HSTS absent", method)
+		}
+	}
+}
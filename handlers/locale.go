@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"BkC/i18n"
+	"BkC/utils"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Choix de la langue par l'utilisateur
+const (
+	langQueryParam = "lang" // ?lang=en change la langue (et la mémorise dans le navigateur)
+	langCookieName = "lang" // Langue choisie, pour les visiteurs comme pour les comptes
+	langCookieAge  = 365 * 24 * time.Hour
+)
+
+// localeContextKey est la clé de contexte de la langue de la requête
+type localeContextKey struct{}
+
+// LocaleMiddleware détermine la langue de chaque requête : paramètre ?lang=, cookie lang,
+// préférence du compte connecté, en-tête Accept-Language puis langue par défaut.
+// Un choix explicite par ?lang= dans une requête GET est mémorisé dans le cookie ; la préférence
+// du compte ne change que par AccountLanguageHandler, une requête GET ne modifiant pas les comptes.
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := resolveLocale(w, r)
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language, Cookie")
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeContextKey{}, lang)))
+	})
+}
+
+// resolveLocale applique l'ordre de priorité décrit par LocaleMiddleware
+func resolveLocale(w http.ResponseWriter, r *http.Request) string {
+	if lang := r.URL.Query().Get(langQueryParam); i18n.IsSupported(lang) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			setLocaleCookie(w, r, lang)
+		}
+		return lang
+	}
+	if cookie, err := r.Cookie(langCookieName); err == nil && i18n.IsSupported(cookie.Value) {
+		return cookie.Value
+	}
+	if lang := sessionLanguage(r); lang != "" {
+		return lang
+	}
+	lang, _ := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	return lang
+}
+
+// setLocaleCookie mémorise la langue choisie dans le navigateur
+func setLocaleCookie(w http.ResponseWriter, r *http.Request, lang string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     langCookieName,
+		Value:    lang,
+		Path:     "/",
+		MaxAge:   int(langCookieAge / time.Second),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sessionLanguage retourne la langue préférée du compte connecté par cookie, sans compter
+// la requête comme une activité de l'utilisateur
+func sessionLanguage(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	session, _ := lookupDevice(cookie.Value)
+	if session == nil {
+		return ""
+	}
+	if user, exists := users[session.Username]; exists && i18n.IsSupported(user.Language) {
+		return user.Language
+	}
+	return ""
+}
+
+// AccountLanguageHandler enregistre la langue préférée du compte connecté, choisie depuis /account
+// (formulaire POST protégé par le jeton CSRF), et la mémorise aussi dans le navigateur
+func AccountLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := currentUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	lang := r.PostFormValue(langQueryParam)
+	if !i18n.IsSupported(lang) {
+		http.Error(w, tr(r, "error.unsupported_language"), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	changed := user.Language != lang
+	user.Language = lang
+	mu.Unlock()
+
+	if changed {
+		if err := SaveUsers(); err != nil {
+			slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+		}
+	}
+	setLocaleCookie(w, r, lang)
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// localeOf retourne la langue de la requête choisie par LocaleMiddleware
+func localeOf(r *http.Request) string {
+	if lang, ok := r.Context().Value(localeContextKey{}).(string); ok {
+		return lang
+	}
+	lang, _ := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	return lang
+}
+
+// tr traduit un message dans la langue de la requête
+func tr(r *http.Request, key string, args ...interface{}) string {
+	return i18n.T(localeOf(r), key, args...)
+}
+
+// translateErrors retourne une copie des erreurs de validation traduites dans la langue donnée
+// lorsque le catalogue contient la clé validation.<champ>.<code> ; les autres gardent leur message
+func translateErrors(lang string, errs []*utils.ValidationError) []*utils.ValidationError {
+	if errs == nil {
+		return nil
+	}
+	translated := make([]*utils.ValidationError, len(errs))
+	for i, e := range errs {
+		copied := *e
+		if key := "validation." + e.Field + "." + e.Code; i18n.Has(key) {
+			copied.Message = i18n.T(lang, key, e.Args...)
+		}
+		translated[i] = &copied
+	}
+	return translated
+}
+
+// localizeErrors traduit des erreurs de validation dans la langue de la requête
+func localizeErrors(r *http.Request, errs []*utils.ValidationError) []*utils.ValidationError {
+	return translateErrors(localeOf(r), errs)
+}
+
+// localizeError traduit une erreur de validation dans la langue de la requête
+func localizeError(r *http.Request, e *utils.ValidationError) *utils.ValidationError {
+	return localizeErrors(r, []*utils.ValidationError{e})[0]
+}
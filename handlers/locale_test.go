@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testSession ouvre une connexion pour username et retourne son cookie de session
+func testSession(t *testing.T, username string) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if _, _, err := startSession(w, httptest.NewRequest(http.MethodPost, "/login", nil), username); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(sessions, username)
+		mu.Unlock()
+	})
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			return cookie
+		}
+	}
+	t.Fatal("cookie de session absent")
+	return nil
+}
+
+// localeCookie retourne la valeur du cookie de langue posé par la réponse
+func localeCookie(w *httptest.ResponseRecorder) string {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == langCookieName {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func TestLocaleQueryParam(t *testing.T) {
+	testSettings(t)
+	alice := &utils.User{Username: "alice", Role: utils.RoleMember, Language: "fr"}
+	testUsers(t, alice)
+	session := testSession(t, "alice")
+
+	tests := []struct {
+		method string
+		target string
+		lang   string // Langue de la réponse
+		cookie string // Cookie de langue posé, "" si aucun
+	}{
+		{http.MethodGet, "/home?lang=en", "en", "en"},
+		{http.MethodHead, "/home?lang=en", "en", "en"},
+		{http.MethodPost, "/home?lang=en", "en", ""},
+		{http.MethodGet, "/home?lang=xx", "fr", ""},
+		{http.MethodGet, "/home", "fr", ""},
+	}
+	handler := LocaleMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		r.AddCookie(session)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if lang := w.Header().Get("Content-Language"); lang != tt.lang {
+			t.Errorf("%s %s : langue %q, attendu %q", tt.method, tt.target, lang, tt.lang)
+		}
+		if cookie := localeCookie(w); cookie != tt.cookie {
+			t.Errorf("%s %s : cookie %q, attendu %q", tt.method, tt.target, cookie, tt.cookie)
+		}
+	}
+	if alice.Language != "fr" {
+		t.Errorf("préférence du compte modifiée par ?lang= : %q", alice.Language)
+	}
+}
+
+func TestAccountLanguageHandler(t *testing.T) {
+	testSettings(t)
+	alice := &utils.User{Username: "alice", Role: utils.RoleMember, Language: "fr"}
+	testUsers(t, alice)
+	session := testSession(t, "alice")
+
+	tests := []struct {
+		name     string
+		method   string
+		lang     string
+		loggedIn bool
+		status   int
+		saved    string // Préférence du compte attendue ensuite
+	}{
+		{"méthode GET", http.MethodGet, "en", true, http.StatusMethodNotAllowed, "fr"},
+		{"non connecté", http.MethodPost, "en", false, http.StatusSeeOther, "fr"},
+		{"langue inconnue", http.MethodPost, "xx", true, http.StatusBadRequest, "fr"},
+		{"anglais", http.MethodPost, "en", true, http.StatusSeeOther, "en"},
+		{"retour au français", http.MethodPost, "fr", true, http.StatusSeeOther, "fr"},
+	}
+	for _, tt := range tests {
+		form := url.Values{langQueryParam: {tt.lang}}
+		r := httptest.NewRequest(tt.method, "/account/language", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tt.loggedIn {
+			r.AddCookie(session)
+		}
+		w := httptest.NewRecorder()
+		AccountLanguageHandler(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s : statut %d, attendu %d", tt.name, w.Code, tt.status)
+		}
+		if alice.Language != tt.saved {
+			t.Errorf("%s : préférence %q, attendu %q", tt.name, alice.Language, tt.saved)
+		}
+		if tt.loggedIn && tt.status == http.StatusSeeOther && localeCookie(w) != tt.lang {
+			t.Errorf("%s : cookie %q, attendu %q", tt.name, localeCookie(w), tt.lang)
+		}
+	}
+}
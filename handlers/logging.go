@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// requestIDHeader transporte l'identifiant de la requête, repris du proxy s'il est fourni
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limite les identifiants acceptés du client à des valeurs sans danger pour le journal
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestLogContextKey est la clé de contexte de l'entrée de journal de la requête
+type requestLogContextKey struct{}
+
+// requestLog est complété pendant le traitement de la requête et journalisé à la fin
+type requestLog struct {
+	id    string
+	mu    sync.Mutex
+	user  string
+	route string // Motif du ServeMux, noté par RouteMiddleware
+}
+
+// statusRecorder capture le code de statut et la taille de la réponse
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush transmet les données déjà écrites (réponses en flux)
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack permet la mise à niveau WebSocket à travers le middleware
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("la connexion ne peut pas être détournée")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap donne accès au ResponseWriter d'origine (http.ResponseController)
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// LoggingMiddleware attribue un identifiant à chaque requête (en-tête X-Request-ID) et journalise
+// sa méthode, son chemin, son statut, sa durée, la taille de la réponse et l'utilisateur authentifié.
+// La durée et le statut alimentent aussi les métriques HTTP.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		entry := &requestLog{id: id}
+		recorder := &statusRecorder{ResponseWriter: w}
+		routed := r.WithContext(context.WithValue(r.Context(), requestLogContextKey{}, entry))
+		next.ServeHTTP(recorder, routed)
+		duration := time.Since(start)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		entry.mu.Lock()
+		user, route := entry.user, entry.route
+		entry.mu.Unlock()
+		observeRequest(r.Method, route, status, duration)
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case r.URL.Path == "/healthz" || r.URL.Path == "/readyz":
+			level = slog.LevelDebug // Sondes appelées en boucle par le superviseur
+		}
+
+		slog.LogAttrs(r.Context(), level, "requête HTTP",
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+			slog.Int64("bytes", recorder.bytes),
+			slog.String("user", user),
+			slog.String("ip", utils.GetVisitorIP(r)),
+		)
+	})
+}
+
+// RouteMiddleware note dans l'entrée de journal le motif du ServeMux qui a servi la requête.
+// Il doit envelopper directement le ServeMux : celui-ci renseigne Pattern sur la requête reçue,
+// et les middlewares intermédiaires (langue, authentification) travaillent sur des copies.
+func RouteMiddleware(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if entry, ok := r.Context().Value(requestLogContextKey{}).(*requestLog); ok {
+				entry.mu.Lock()
+				entry.route = r.Pattern
+				entry.mu.Unlock()
+			}
+		}()
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// newRequestID génère un identifiant de requête aléatoire
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// setRequestUser note l'utilisateur authentifié pour le journal de la requête
+func setRequestUser(r *http.Request, username string) {
+	if entry, ok := r.Context().Value(requestLogContextKey{}).(*requestLog); ok {
+		entry.mu.Lock()
+		entry.user = username
+		entry.mu.Unlock()
+	}
+}
+
+// requestID retourne l'identifiant attribué à la requête par LoggingMiddleware
+func requestID(r *http.Request) string {
+	if entry, ok := r.Context().Value(requestLogContextKey{}).(*requestLog); ok {
+		return entry.id
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"BkC/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestRouteLabel vérifie que les métriques HTTP sont étiquetées avec le motif du ServeMux,
+// malgré les middlewares qui copient la requête entre LoggingMiddleware et le ServeMux
+func TestRouteLabel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /test-route/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	api := http.NewServeMux()
+	api.HandleFunc("GET /test-api/items", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/test-api/", api)
+	handler := LoggingMiddleware(LocaleMiddleware(CSRFMiddleware(RouteMiddleware(mux))))
+
+	tests := []struct {
+		path   string
+		sample string
+	}{
+		{"/test-route/42", `bkc_http_requests_total{route="/test-route/{id}",method="GET",code="418"}`},
+		{"/test-api/items", `bkc_http_requests_total{route="/test-api/items",method="GET",code="200"}`},
+	}
+	for _, tt := range tests {
+		before := sampleValue(tt.sample)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if after := sampleValue(tt.sample); after != before+1 {
+			t.Errorf("%s : échantillon %s à %v, attendu %v", tt.path, tt.sample, after, before+1)
+		}
+	}
+}
+
+// sampleValue renvoie la valeur d'un échantillon des métriques, 0 s'il est absent
+func sampleValue(sample string) float64 {
+	var out strings.Builder
+	metrics.Default.WriteTo(&out)
+	for _, line := range strings.Split(out.String(), "\n") {
+		if value, found := strings.CutPrefix(line, sample+" "); found {
+			v, _ := strconv.ParseFloat(value, 64)
+			return v
+		}
+	}
+	return 0
+}
@@ -2,9 +2,8 @@ package handlers
 
 import (
 	"BkC/blockchain"
+	"BkC/utils"
 	"encoding/json"
-	"fmt"
-	"html/template"
 	"net/http"
 	"time"
 )
@@ -41,6 +40,7 @@ type MessagePageData struct {
 	Conversations    []Conversation
 	BlockCount       int
 	LastHash         string
+	CSRFToken        string
 }
 
 // MessagesHandler gère l'affichage de la page des messages
@@ -64,6 +64,7 @@ func MessagesHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 			Username:         username,
 			CurrentRecipient: recipient,
 			BlockCount:       len(bc.Blocks),
+			CSRFToken:        CSRFToken(w, r),
 		}
 
 		// Récupérer le dernier hash
@@ -118,69 +119,80 @@ func MessagesHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 		}
 
 		// Afficher la page
-		tmpl, err := template.ParseFiles("templates/messages.html")
+		tmpl, err := loadTemplate(r, "messages.html")
 		if err != nil {
-			http.Error(w, "Erreur lors du chargement de la page de messages", http.StatusInternalServerError)
+			http.Error(w, tr(r, "error.page.messages"), http.StatusInternalServerError)
 			return
 		}
 		tmpl.Execute(w, pageData)
 	}
 }
 
-// APIMessagesHandler gère l'envoi de nouveaux messages via API
+// APIMessagesHandler liste (GET, paramètre facultatif with) et envoie (POST) des messages via API.
+// Les deux méthodes sont enregistrées séparément pour exiger la portée de lecture ou d'envoi.
 func APIMessagesHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Vérifier si l'utilisateur est connecté
 		username, ok := getLoggedInUser(r)
 		if !ok {
-			http.Error(w, "Utilisateur non connecté", http.StatusUnauthorized)
+			http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
 			return
 		}
 
-		if r.Method == "POST" {
-			// Décoder le corps de la requête
+		if r.Method == "GET" {
+			with := r.URL.Query().Get("with")
+			messages := make([]blockchain.Message, 0)
+			for _, message := range bc.GetUserMessages(username) {
+				if with == "" || message.Sender == with || message.Recipient == with {
+					messages = append(messages, message)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(messages)
+		} else if r.Method == "POST" {
+			// Décoder le corps de la requête (taille limitée)
+			r.Body = http.MaxBytesReader(w, r.Body, maxMessageBodyBytes)
 			var messageData MessageData
 			err := json.NewDecoder(r.Body).Decode(&messageData)
 			if err != nil {
-				http.Error(w, "Format de message invalide", http.StatusBadRequest)
+				writeValidationErrors(w, r, http.StatusBadRequest, &utils.ValidationError{
+					Field: "body", Code: "invalid_json", Message: "Format de message invalide",
+				})
 				return
 			}
 
-			// Valider les données
-			if messageData.Recipient == "" || messageData.Content == "" {
-				http.Error(w, "Destinataire ou contenu manquant", http.StatusBadRequest)
+			// Valider le destinataire et le contenu
+			recipient, errs := validateNewMessage(messageData.Recipient, messageData.Content)
+			if len(errs) > 0 {
+				writeValidationErrors(w, r, http.StatusUnprocessableEntity, errs...)
 				return
 			}
 
 			// Créer le message
-			message := blockchain.CreateMessage(username, messageData.Recipient, messageData.Content)
+			message := blockchain.CreateMessage(username, recipient, messageData.Content)
 
 			// Ajouter à la blockchain de manière asynchrone
-			bc.AddMessageBlockAsync(message, 4) // Difficulté 4 comme standard
+			bc.AddMessageBlockAsync(message, settings.Difficulty.Messages)
 
 			// Répondre avec succès
+			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
-			fmt.Fprintf(w, `{"status":"success","message":"Message envoyé"}`)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "success",
+				"message": tr(r, "success.message_sent"),
+			})
 		} else {
-			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
 		}
 	}
 }
 
 // getLoggedInUser vérifie si l'utilisateur est connecté et renvoie son nom d'utilisateur
 func getLoggedInUser(r *http.Request) (string, bool) {
-	cookie, err := r.Cookie("session")
-	if err != nil {
-		return "", false
-	}
-
-	mu.Lock()
-	session, exists := sessions[cookie.Value]
-	mu.Unlock()
-
-	if !exists || session == nil {
+	auth, ok := authenticateRequest(r)
+	if !ok {
 		return "", false
 	}
 
-	return session.Username, true
+	return auth.Username, true
 }
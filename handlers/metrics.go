@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"BkC/metrics"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Métriques du serveur HTTP, des connexions et du WebSocket, exposées par /metrics
+var (
+	httpRequests = metrics.NewCounterVec("bkc_http_requests_total",
+		"Requêtes HTTP traitées, par route, méthode et code de statut.", "route", "method", "code")
+	httpRequestDuration = metrics.NewHistogramVec("bkc_http_request_duration_seconds",
+		"Durée de traitement des requêtes HTTP, par route et méthode.", metrics.DefBuckets, "route", "method")
+	loginAttempts = metrics.NewCounterVec("bkc_logins_total",
+		"Tentatives de connexion, par résultat (success, failure, blocked, second_factor).", "result")
+)
+
+func init() {
+	metrics.NewGaugeFunc("bkc_websocket_clients", "Connexions WebSocket ouvertes.", func() float64 {
+		presence.mu.Lock()
+		defer presence.mu.Unlock()
+		return float64(len(presence.clients))
+	})
+	metrics.NewGaugeFunc("bkc_users_online", "Utilisateurs présents (en ligne, absents ou occupés).", func() float64 {
+		return float64(presence.OnlineCount())
+	})
+}
+
+// observeRequest enregistre la durée et le statut d'une requête. La route est le motif du
+// ServeMux qui l'a servie (noté par RouteMiddleware), pour borner le nombre de séries.
+func observeRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "other" // Requête rejetée avant le routage (CSRF, origine)
+	} else if _, path, found := strings.Cut(route, " "); found {
+		route = path // Motif de l'API v1 ("GET /api/v1/blocks"), la méthode étant déjà un label
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		method = "other"
+	}
+
+	httpRequests.Inc(route, method, strconv.Itoa(status))
+	httpRequestDuration.Observe(duration.Seconds(), route, method)
+}
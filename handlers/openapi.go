@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPIVersion est la version de l'API décrite (à incrémenter à chaque ajout compatible)
+const openAPIVersion = "1.0.0"
+
+// openAPIEnums liste les valeurs possibles des types énumérés
+var openAPIEnums = map[reflect.Type]func() []interface{}{
+	reflect.TypeOf(utils.TokenScope("")): func() []interface{} {
+		values := make([]interface{}, len(utils.AllScopes))
+		for i, scope := range utils.AllScopes {
+			values[i] = string(scope)
+		}
+		return values
+	},
+}
+
+// schemaBuilder génère les schémas JSON des types Go d'après leurs tags json.
+// Les structures nommées sont décrites une seule fois dans components/schemas.
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+// ref retourne le schéma d'un type, en référençant les structures nommées
+func (b *schemaBuilder) ref(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return map[string]interface{}{} // Valeur JSON quelconque
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if enum, ok := openAPIEnums[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": enum()}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.ref(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.ref(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		if _, done := b.components[t.Name()]; !done {
+			b.components[t.Name()] = nil // Réservé avant la description, pour les types récursifs
+			b.components[t.Name()] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+// object décrit les champs exportés d'une structure
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.ref(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// buildOpenAPI génère la description OpenAPI 3.1 des routes de l'API v1
+func buildOpenAPI(routes []apiRoute) map[string]interface{} {
+	schemas := &schemaBuilder{components: make(map[string]interface{})}
+	errorSchema := schemas.ref(reflect.TypeOf(APIErrorResponse{}))
+
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"operationId": route.ID,
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+		}
+
+		if len(route.Params) > 0 {
+			params := make([]interface{}, 0, len(route.Params))
+			for _, param := range route.Params {
+				params = append(params, map[string]interface{}{
+					"name":        param.Name,
+					"in":          param.In,
+					"required":    param.Required,
+					"description": param.Description,
+					"schema":      map[string]interface{}{"type": param.Type},
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.ref(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(route.Status)}
+		if route.Response != nil {
+			media := route.Media
+			if media == "" {
+				media = "application/json"
+			}
+			success["content"] = map[string]interface{}{
+				media: map[string]interface{}{"schema": schemas.ref(reflect.TypeOf(route.Response))},
+			}
+		}
+		responses := map[string]interface{}{strconv.Itoa(route.Status): success}
+
+		errorCodes := append([]int(nil), route.Errors...)
+		switch route.Access {
+		case accessToken:
+			scopes := []string{}
+			if route.Scope != "" {
+				scopes = append(scopes, string(route.Scope))
+			}
+			operation["security"] = []interface{}{
+				map[string]interface{}{"bearerAuth": scopes},
+				map[string]interface{}{"sessionCookie": []string{}},
+			}
+			errorCodes = append(errorCodes, http.StatusUnauthorized, http.StatusForbidden)
+		case accessSession:
+			operation["security"] = []interface{}{map[string]interface{}{"sessionCookie": []string{}}}
+			errorCodes = append(errorCodes, http.StatusUnauthorized, http.StatusForbidden)
+		default:
+			operation["security"] = []interface{}{}
+		}
+		for _, code := range errorCodes {
+			responses[strconv.Itoa(code)] = map[string]interface{}{
+				"description": http.StatusText(code),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": errorSchema},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		item, _ := paths[route.Path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	paths["/openapi.json"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"operationId": "getOpenAPI",
+			"summary":     "Cette description OpenAPI",
+			"tags":        []string{"meta"},
+			"security":    []interface{}{},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": http.StatusText(http.StatusOK),
+					"content":     map[string]interface{}{"application/json": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "API BkC",
+			"version":     openAPIVersion,
+			"description": "API JSON de BkC. Les erreurs ont toutes la forme {\"error\": {\"code\", \"message\", \"requestId\", \"details\"}}. Les requêtes modifiant l'état authentifiées par cookie doivent porter l'en-tête " + csrfHeaderName + ".",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": apiV1Prefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Jeton d'API personnel (" + apiTokenPrefix + "...), créé sur /tokens",
+				},
+				"sessionCookie": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": sessionCookieName,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// openAPISpec retourne la description servie par le routeur, décodée
+func openAPISpec(t *testing.T, router http.Handler) map[string]interface{} {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, apiV1Prefix+"/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("openapi.json : statut %d", w.Code)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	cfg := testSettings(t)
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	routes := apiV1Routes(chain)
+	spec := openAPISpec(t, APIv1Router(chain))
+	paths := spec["paths"].(map[string]interface{})
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	described := map[string]bool{"GET /openapi.json": true} // Opérations sans route de la table
+	operationIDs := map[string]string{}
+	placeholder := regexp.MustCompile(`\{([^}]+)\}`)
+	for _, route := range routes {
+		name := route.Method + " " + route.Path
+		if previous, ok := operationIDs[route.ID]; ok {
+			t.Errorf("%s : operationId %q déjà utilisé par %s", name, route.ID, previous)
+		}
+		operationIDs[route.ID] = name
+		described[name] = true
+
+		item, _ := paths[route.Path].(map[string]interface{})
+		operation, ok := item[strings.ToLower(route.Method)].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s : absente de la description", name)
+			continue
+		}
+		if operation["operationId"] != route.ID {
+			t.Errorf("%s : operationId %v, attendu %q", name, operation["operationId"], route.ID)
+		}
+
+		// Réponses : succès, erreurs déclarées et refus d'accès des routes protégées
+		responses := operation["responses"].(map[string]interface{})
+		codes := append([]int{route.Status}, route.Errors...)
+		if route.Access != accessPublic {
+			codes = append(codes, http.StatusUnauthorized, http.StatusForbidden)
+		}
+		for _, code := range codes {
+			if _, ok := responses[strconv.Itoa(code)]; !ok {
+				t.Errorf("%s : réponse %d non décrite", name, code)
+			}
+		}
+		if route.Response != nil && responses[strconv.Itoa(route.Status)].(map[string]interface{})["content"] == nil {
+			t.Errorf("%s : corps de la réponse non décrit", name)
+		}
+		if (route.Request != nil) != (operation["requestBody"] != nil) {
+			t.Errorf("%s : corps de la requête décrit %v, attendu %v", name, operation["requestBody"] != nil, route.Request != nil)
+		}
+
+		// Paramètres de chemin
+		var params []string
+		if list, ok := operation["parameters"].([]interface{}); ok {
+			for _, p := range list {
+				if param := p.(map[string]interface{}); param["in"] == "path" {
+					params = append(params, param["name"].(string))
+				}
+			}
+		}
+		var want []string
+		for _, match := range placeholder.FindAllStringSubmatch(route.Path, -1) {
+			want = append(want, match[1])
+		}
+		if !slices.Equal(params, want) {
+			t.Errorf("%s : paramètres de chemin %q, attendu %q", name, params, want)
+		}
+
+		// Portée demandée aux jetons d'API
+		security, _ := json.Marshal(operation["security"])
+		if route.Scope != "" && route.Access == accessToken && !strings.Contains(string(security), `"bearerAuth":["`+string(route.Scope)+`"]`) {
+			t.Errorf("%s : sécurité %s sans la portée %s", name, security, route.Scope)
+		}
+		if route.Access == accessPublic && string(security) != "[]" {
+			t.Errorf("%s : route publique avec la sécurité %s", name, security)
+		}
+	}
+
+	// Aucune opération décrite sans route
+	for path, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			if name := strings.ToUpper(method) + " " + path; !described[name] {
+				t.Errorf("%s : décrite sans route", name)
+			}
+		}
+	}
+
+	// Tous les schémas référencés existent
+	data, _ := json.Marshal(spec)
+	for _, match := range regexp.MustCompile(`"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(data), -1) {
+		if schemas[match[1]] == nil {
+			t.Errorf("schéma %s référencé mais non décrit", match[1])
+		}
+	}
+}
+
+func TestAPIv1CatchAll(t *testing.T) {
+	cfg := testSettings(t)
+	router := APIv1Router(blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		allow  string // En-tête Allow attendu, "" pour un 404
+	}{
+		{http.MethodGet, "/inconnue", http.StatusNotFound, ""},
+		{http.MethodGet, "/blocks/1/suite", http.StatusNotFound, ""},
+		{http.MethodPost, "/", http.StatusNotFound, ""},
+		{http.MethodDelete, "/blocks", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodPut, "/session", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodPost, "/presence", http.StatusMethodNotAllowed, "GET, HEAD, PUT"},
+		{http.MethodGet, "/rpc", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodGet, "/tokens/t1", http.StatusMethodNotAllowed, "DELETE"},
+		{http.MethodPatch, "/webhooks/h1/ping", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodPost, "/openapi.json", http.StatusMethodNotAllowed, "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		name := tt.method + " " + tt.path
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, apiV1Prefix+tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s : statut %d, attendu %d", name, w.Code, tt.status)
+			continue
+		}
+		if allow := w.Header().Get("Allow"); allow != tt.allow {
+			t.Errorf("%s : Allow %q, attendu %q", name, allow, tt.allow)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("%s : Content-Type %q", name, contentType)
+		}
+
+		var envelope APIErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			t.Errorf("%s : enveloppe illisible %s", name, w.Body)
+			continue
+		}
+		code := errNotFound
+		if tt.status == http.StatusMethodNotAllowed {
+			code = errMethodNotAllowed
+		}
+		if envelope.Error.Code != code || envelope.Error.Message == "" {
+			t.Errorf("%s : erreur %+v, attendu le code %s", name, envelope.Error, code)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"BkC/i18n"
+	"BkC/utils"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// passwordResetTTL est la durée de validité d'un lien de réinitialisation
+const passwordResetTTL = 30 * time.Minute
+
+var (
+	notifier   utils.Notifier = utils.LogNotifier{} // Canal d'envoi des liens de réinitialisation
+	notifierMu sync.RWMutex                         // Protection du notifier
+
+	// 5 demandes de réinitialisation par IP et par heure
+	resetLimiter = utils.NewRateLimiter(5, time.Hour)
+)
+
+// passwordReset représente une demande de réinitialisation en attente
+type passwordReset struct {
+	Username  string
+	ExpiresAt time.Time
+}
+
+// passwordResets stocke les demandes, indexées par l'empreinte du jeton (protégé par mu)
+var passwordResets = make(map[string]*passwordReset)
+
+// SetNotifier configure le canal d'envoi des notifications (SMTP, journal...)
+func SetNotifier(n utils.Notifier) {
+	notifierMu.Lock()
+	notifier = n
+	notifierMu.Unlock()
+}
+
+// currentNotifier retourne le canal d'envoi configuré
+func currentNotifier() utils.Notifier {
+	notifierMu.RLock()
+	defer notifierMu.RUnlock()
+	return notifier
+}
+
+// publicBaseURL retourne l'URL publique du site pour construire les liens envoyés.
+// La première origine configurée est préférée à l'en-tête Host, qui peut être falsifié.
+func publicBaseURL(r *http.Request) string {
+	allowedOriginsMu.RLock()
+	defer allowedOriginsMu.RUnlock()
+	if len(allowedOrigins) > 0 {
+		return allowedOrigins[0]
+	}
+	scheme := "http"
+	if isSecureRequest(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// lookupPasswordReset retourne la demande associée à un jeton s'il est encore valide.
+// L'appelant doit détenir mu.
+func lookupPasswordReset(token string) (string, *passwordReset) {
+	if token == "" {
+		return "", nil
+	}
+	key := utils.HashToken(token)
+	reset, exists := passwordResets[key]
+	if !exists {
+		return "", nil
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		delete(passwordResets, key)
+		return "", nil
+	}
+	return key, reset
+}
+
+// ForgotPasswordHandler affiche le formulaire de demande de réinitialisation
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := loadTemplate(r, "forgot_password.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.reset"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, map[string]string{
+		"Error":     r.URL.Query().Get("error"),
+		"Message":   r.URL.Query().Get("message"),
+		"Retry":     r.URL.Query().Get("retry"),
+		"CSRFToken": CSRFToken(w, r),
+	})
+}
+
+// ForgotPasswordSubmitHandler émet un jeton de réinitialisation et l'envoie au titulaire du compte.
+// La réponse est identique que le compte existe ou non, pour ne pas révéler les noms d'utilisateur.
+func ForgotPasswordSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+		return
+	}
+
+	identifier := strings.TrimSpace(r.FormValue("username"))
+	clientIP := utils.GetVisitorIP(r)
+
+	if allowed, wait := resetLimiter.Allow(clientIP); !allowed {
+		slog.Warn("Demandes de réinitialisation bloquées", "ip", clientIP, "retry_in", wait.Round(time.Second))
+		http.Redirect(w, r, fmt.Sprintf("/forgot-password?error=locked&retry=%d", retrySeconds(wait)), http.StatusSeeOther)
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		http.Error(w, tr(r, "error.token_creation"), http.StatusInternalServerError)
+		return
+	}
+
+	// Retrouver le compte par nom d'utilisateur ou par adresse e-mail
+	mu.Lock()
+	var user *utils.User
+	for _, u := range users {
+		if u.Username == identifier || (u.Email != "" && strings.EqualFold(u.Email, identifier)) {
+			user = u
+			break
+		}
+	}
+	var username, email string
+	lang := localeOf(r)
+	if user != nil && !user.Disabled {
+		username, email = user.Username, user.Email
+		if i18n.IsSupported(user.Language) {
+			lang = user.Language // Le courriel est rédigé dans la langue choisie par le titulaire du compte
+		}
+
+		// Une nouvelle demande invalide les précédentes
+		for key, reset := range passwordResets {
+			if reset.Username == username || time.Now().After(reset.ExpiresAt) {
+				delete(passwordResets, key)
+			}
+		}
+		passwordResets[utils.HashToken(token)] = &passwordReset{
+			Username:  username,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		}
+	}
+	mu.Unlock()
+
+	if username != "" {
+		link := publicBaseURL(r) + "/reset-password?token=" + token
+		body := i18n.T(lang, "email.reset.body", username, clientIP, int(passwordResetTTL/time.Minute), link)
+		subject := i18n.T(lang, "email.reset.subject")
+
+		// Envoi en arrière-plan : le temps de réponse ne doit pas révéler l'existence du compte
+		n := currentNotifier()
+		go func() {
+			if err := n.Notify(email, subject, body); err != nil {
+				slog.Error("Échec de l'envoi du lien de réinitialisation", "user", username, "error", err)
+			}
+		}()
+		slog.Info("Réinitialisation du mot de passe demandée", "user", username, "ip", clientIP)
+	}
+
+	http.Redirect(w, r, "/forgot-password?message=sent", http.StatusSeeOther)
+}
+
+// ResetPasswordHandler affiche le formulaire de choix du nouveau mot de passe
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	mu.Lock()
+	_, reset := lookupPasswordReset(token)
+	mu.Unlock()
+
+	data := map[string]string{
+		"Token":     token,
+		"Error":     r.URL.Query().Get("error"),
+		"CSRFToken": CSRFToken(w, r),
+	}
+	if reset == nil {
+		data["Error"] = "invalid"
+	}
+
+	tmpl, err := loadTemplate(r, "reset_password.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.reset"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, data)
+}
+
+// ResetPasswordSubmitHandler consomme le jeton, change le mot de passe, ferme toutes les connexions
+// et révoque les jetons d'API du compte
+func ResetPasswordSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+	confirmPassword := r.FormValue("confirm_password")
+
+	if password == "" || password != confirmPassword {
+		http.Redirect(w, r, "/reset-password?error=password_mismatch&token="+token, http.StatusSeeOther)
+		return
+	}
+
+	mu.Lock()
+	key, reset := lookupPasswordReset(token)
+	if reset == nil {
+		mu.Unlock()
+		http.Redirect(w, r, "/reset-password?error=invalid", http.StatusSeeOther)
+		return
+	}
+	// Jeton à usage unique
+	delete(passwordResets, key)
+
+	user, exists := users[reset.Username]
+	if !exists || user.Disabled {
+		mu.Unlock()
+		http.Redirect(w, r, "/reset-password?error=invalid", http.StatusSeeOther)
+		return
+	}
+	user.PasswordHash = utils.HashPassword(password)
+	revokeAllDevices(user.Username)
+	// Un jeton créé par un intrus lui garderait l'accès au compte récupéré
+	revokeAPITokens(user.Username)
+	mu.Unlock()
+
+	// Le titulaire a prouvé l'accès à sa boîte : lever la temporisation du compte
+	loginAccountLimiter.Reset(accountKey(reset.Username))
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+	if err := SaveSessions(); err != nil {
+		slog.Error("Sauvegarde des sessions impossible", "error", err)
+	}
+	if err := SaveAPITokens(); err != nil {
+		slog.Error("Sauvegarde des jetons d'API impossible", "error", err)
+	}
+
+	slog.Info("Mot de passe réinitialisé", "user", reset.Username, "ip", utils.GetVisitorIP(r))
+	http.Redirect(w, r, "/login?message=password_reset", http.StatusSeeOther)
+}
+
+// ChangeEmailHandler enregistre l'adresse e-mail de l'utilisateur connecté après confirmation
+// du mot de passe (et du second facteur s'il est activé) : l'adresse reçoit les liens de
+// réinitialisation, une session volée ne doit pas suffire à la détourner
+func ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email {
+			http.Error(w, tr(r, "error.invalid_email"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	clientIP := utils.GetVisitorIP(r)
+	if allowed, wait := checkLoginAllowed(user.Username, clientIP); !allowed {
+		http.Error(w, tr(r, "error.too_many_attempts", retrySeconds(wait)), http.StatusTooManyRequests)
+		return
+	}
+
+	mu.Lock()
+	valid := user.CheckPassword(req.Password) &&
+		(!user.TOTPEnabled || user.VerifySecondFactor(req.Code, time.Now()))
+	if !valid {
+		mu.Unlock()
+		recordLoginFailure(user.Username, clientIP)
+		http.Error(w, tr(r, "error.wrong_password_or_code"), http.StatusForbidden)
+		return
+	}
+	for _, u := range users {
+		if email != "" && u != user && strings.EqualFold(u.Email, email) {
+			mu.Unlock()
+			http.Error(w, tr(r, "error.email_taken"), http.StatusConflict)
+			return
+		}
+	}
+	user.Email = email
+	mu.Unlock()
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+	slog.Info("Adresse e-mail changée", "user", user.Username, "ip", clientIP)
+	writeAdminSuccess(w, tr(r, "success.email_saved"))
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Paramètres du suivi de présence
+const (
+	presenceTimeout       = 2 * time.Minute  // Sans WebSocket ouvert, un utilisateur inactif depuis ce délai est hors ligne
+	presenceAwayAfter     = 5 * time.Minute  // Inactivité au-delà de laquelle un utilisateur connecté passe absent
+	presenceSweepInterval = 30 * time.Second // Fréquence de recalcul des statuts
+	presenceQueueSize     = 256              // Événements en attente de diffusion
+)
+
+// PresenceEvent décrit le statut d'un utilisateur dans l'API et les messages WebSocket
+type PresenceEvent struct {
+	Username string    `json:"username"`
+	Status   string    `json:"status"` // "offline", "online", "away" ou "busy"
+	Manual   bool      `json:"manual"` // Statut choisi par l'utilisateur plutôt que déduit de son activité
+	Since    time.Time `json:"since"`
+}
+
+// presenceState est l'état de présence d'un utilisateur
+type presenceState struct {
+	connections  int              // Connexions WebSocket ouvertes depuis une session navigateur
+	lastActivity time.Time        // Dernière requête ou interaction connue
+	manual       utils.UserStatus // StatusAway ou StatusBusy si choisi, StatusOnline pour le mode automatique
+	status       utils.UserStatus // Dernier statut publié
+	since        time.Time        // Date du dernier changement de statut
+}
+
+// presenceTracker déduit le statut des utilisateurs de leurs connexions WebSocket et de leur activité.
+// Il dispose de son propre verrou : mu peut être détenu en l'appelant, mais jamais l'inverse.
+type presenceTracker struct {
+	mu      sync.Mutex
+	states  map[string]*presenceState
+	clients map[*WebSocketClient]bool
+	events  chan PresenceEvent
+	now     func() time.Time // Horloge, remplacée dans les tests
+	start   sync.Once
+}
+
+// presence est le suivi de présence global
+var presence = newPresenceTracker(time.Now)
+
+// newPresenceTracker crée un suivi de présence vide qui lit l'heure avec now
+func newPresenceTracker(now func() time.Time) *presenceTracker {
+	return &presenceTracker{
+		states:  make(map[string]*presenceState),
+		clients: make(map[*WebSocketClient]bool),
+		events:  make(chan PresenceEvent, presenceQueueSize),
+		now:     now,
+	}
+}
+
+// StartPresence démarre la diffusion des changements de statut et le passage automatique en absence
+func StartPresence() {
+	presence.start.Do(func() {
+		go presence.dispatch()
+		go presence.sweep()
+	})
+}
+
+// compute déduit le statut d'un utilisateur. L'appelant doit détenir p.mu.
+func (st *presenceState) compute(now time.Time) utils.UserStatus {
+	idle := now.Sub(st.lastActivity)
+	if st.connections == 0 && idle >= presenceTimeout {
+		return utils.StatusOffline
+	}
+	if st.manual == utils.StatusAway || st.manual == utils.StatusBusy {
+		return st.manual
+	}
+	if idle >= presenceAwayAfter {
+		return utils.StatusAway
+	}
+	return utils.StatusOnline
+}
+
+// event construit l'événement correspondant à l'état. L'appelant doit détenir p.mu.
+func (st *presenceState) event(username string) PresenceEvent {
+	return PresenceEvent{
+		Username: username,
+		Status:   st.status.String(),
+		Manual:   st.manual != utils.StatusOnline && st.status == st.manual,
+		Since:    st.since,
+	}
+}
+
+// update applique une modification à l'état d'un utilisateur et publie le nouveau statut s'il a changé
+func (p *presenceTracker) update(username string, change func(st *presenceState)) {
+	if username == "" {
+		return
+	}
+
+	p.mu.Lock()
+	st, exists := p.states[username]
+	if !exists {
+		st = &presenceState{manual: utils.StatusOnline, status: utils.StatusOffline}
+		p.states[username] = st
+	}
+	change(st)
+	event, changed := p.refresh(username, st, p.now())
+	p.mu.Unlock()
+
+	if changed {
+		p.publish(event)
+	}
+}
+
+// refresh recalcule le statut et indique s'il a changé. L'appelant doit détenir p.mu.
+func (p *presenceTracker) refresh(username string, st *presenceState, now time.Time) (PresenceEvent, bool) {
+	status := st.compute(now)
+	changed := status != st.status
+	if changed {
+		st.status = status
+		st.since = now
+	}
+	// Un utilisateur hors ligne sans statut choisi n'a plus besoin d'être suivi
+	if status == utils.StatusOffline && st.manual == utils.StatusOnline {
+		delete(p.states, username)
+	}
+	if !changed {
+		return PresenceEvent{}, false
+	}
+	return st.event(username), true
+}
+
+// publish met un événement en file de diffusion sans jamais bloquer l'appelant
+func (p *presenceTracker) publish(event PresenceEvent) {
+	select {
+	case p.events <- event:
+	default:
+		slog.Warn("File de présence pleine, changement de statut ignoré", "user", event.Username)
+	}
+}
+
+// Touch enregistre une activité de l'utilisateur
+func (p *presenceTracker) Touch(username string) {
+	now := p.now()
+	p.update(username, func(st *presenceState) {
+		st.lastActivity = now
+	})
+}
+
+// SetManual fixe le statut choisi par l'utilisateur (StatusOnline rétablit le mode automatique)
+func (p *presenceTracker) SetManual(username string, status utils.UserStatus) {
+	now := p.now()
+	p.update(username, func(st *presenceState) {
+		st.manual = status
+		st.lastActivity = now
+	})
+}
+
+// Forget marque l'utilisateur hors ligne et ferme ses connexions WebSocket de session,
+// après une déconnexion ou la révocation de toutes ses connexions
+func (p *presenceTracker) Forget(username string) {
+	p.mu.Lock()
+	for client := range p.clients {
+		if client.username == username && client.auth.Token == nil {
+			client.conn.Close()
+		}
+	}
+	p.mu.Unlock()
+
+	p.update(username, func(st *presenceState) {
+		st.lastActivity = time.Time{}
+		st.manual = utils.StatusOnline
+	})
+}
+
+// Connect enregistre un client WebSocket pour la diffusion des statuts.
+// Seules les connexions ouvertes depuis une session navigateur rendent l'utilisateur présent.
+func (p *presenceTracker) Connect(client *WebSocketClient) {
+	p.mu.Lock()
+	p.clients[client] = true
+	p.mu.Unlock()
+
+	if client.auth.Token == nil {
+		now := p.now()
+		p.update(client.username, func(st *presenceState) {
+			st.connections++
+			st.lastActivity = now
+		})
+	}
+}
+
+// Disconnect retire un client WebSocket
+func (p *presenceTracker) Disconnect(client *WebSocketClient) {
+	p.mu.Lock()
+	_, registered := p.clients[client]
+	delete(p.clients, client)
+	p.mu.Unlock()
+
+	if registered && client.auth.Token == nil {
+		p.update(client.username, func(st *presenceState) {
+			if st.connections > 0 {
+				st.connections--
+			}
+		})
+	}
+}
+
+// Status retourne le statut courant d'un utilisateur
+func (p *presenceTracker) Status(username string) PresenceEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st, exists := p.states[username]
+	if !exists {
+		return PresenceEvent{Username: username, Status: utils.StatusOffline.String()}
+	}
+	return st.event(username)
+}
+
+// OnlineUsers retourne les utilisateurs présents (en ligne, absents ou occupés), triés par nom
+func (p *presenceTracker) OnlineUsers() []string {
+	p.mu.Lock()
+	names := make([]string, 0, len(p.states))
+	for username, st := range p.states {
+		if st.status != utils.StatusOffline {
+			names = append(names, username)
+		}
+	}
+	p.mu.Unlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// OnlineCount retourne le nombre d'utilisateurs présents
+func (p *presenceTracker) OnlineCount() int {
+	return len(p.OnlineUsers())
+}
+
+// sweep recalcule périodiquement les statuts pour appliquer l'absence automatique et l'expiration
+func (p *presenceTracker) sweep() {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.expire()
+	}
+}
+
+// expire recalcule le statut de chaque utilisateur suivi et publie ceux qui ont changé
+func (p *presenceTracker) expire() {
+	now := p.now()
+	var events []PresenceEvent
+	p.mu.Lock()
+	for username, st := range p.states {
+		if event, changed := p.refresh(username, st, now); changed {
+			events = append(events, event)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, event := range events {
+		p.publish(event)
+	}
+}
+
+// dispatch reporte les changements de statut dans les sessions et les envoie
+// à l'utilisateur concerné ainsi qu'à ses contacts connectés
+func (p *presenceTracker) dispatch() {
+	for event := range p.events {
+		status, _ := utils.ParseUserStatus(event.Status)
+
+		mu.Lock()
+		if session, exists := sessions[event.Username]; exists && session != nil {
+			session.Status = status
+		}
+		mu.Unlock()
+
+		recipients := contactsOf(event.Username)
+		recipients[event.Username] = true
+
+		message := ServerMessage{
+			Type:    "presence",
+			Data:    event,
+			Time:    time.Now(),
+			Success: true,
+		}
+
+		p.mu.Lock()
+		for client := range p.clients {
+			if recipients[client.username] {
+				client.trySend(message)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// contactsOf retourne les utilisateurs ayant échangé au moins un message avec username
+func contactsOf(username string) map[string]bool {
+	contacts := make(map[string]bool)
+	if bc == nil {
+		return contacts
+	}
+	for _, message := range bc.GetUserMessages(username) {
+		if message.Sender != username {
+			contacts[message.Sender] = true
+		}
+		if message.Recipient != username {
+			contacts[message.Recipient] = true
+		}
+	}
+	return contacts
+}
+
+// contactsPresence retourne le statut de chaque contact de l'utilisateur, trié par nom
+func contactsPresence(username string) []PresenceEvent {
+	contacts := contactsOf(username)
+	views := make([]PresenceEvent, 0, len(contacts))
+	for contact := range contacts {
+		views = append(views, presence.Status(contact))
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Username < views[j].Username
+	})
+	return views
+}
+
+// parseManualStatus convertit le statut demandé par un client ; seuls online, away et busy sont acceptés
+func parseManualStatus(name string) (utils.UserStatus, bool) {
+	status, err := utils.ParseUserStatus(name)
+	if err != nil || status == utils.StatusOffline {
+		return utils.StatusOffline, false
+	}
+	return status, true
+}
+
+// PresenceHandler retourne (GET) le statut de l'utilisateur et de ses contacts,
+// ou fixe (POST {"status": "online"|"away"|"busy"}) le statut choisi
+func PresenceHandler(w http.ResponseWriter, r *http.Request) {
+	username, ok := getLoggedInUser(r)
+	if !ok {
+		http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+			return
+		}
+		status, valid := parseManualStatus(req.Status)
+		if !valid {
+			http.Error(w, tr(r, "validation.status.invalid"), http.StatusBadRequest)
+			return
+		}
+		presence.SetManual(username, status)
+	default:
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presenceView(username))
+}
+
+// PresenceView est l'état de présence d'un utilisateur et de ses contacts
+type PresenceView struct {
+	Me       PresenceEvent   `json:"me"`
+	Contacts []PresenceEvent `json:"contacts"`
+}
+
+// presenceView retourne la présence de l'utilisateur et de ses contacts
+func presenceView(username string) PresenceView {
+	return PresenceView{Me: presence.Status(username), Contacts: contactsPresence(username)}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock est une horloge avancée à la main par le test
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// testPresence crée un suivi de présence sur une horloge factice, sans diffusion
+func testPresence(t *testing.T) (*presenceTracker, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	return newPresenceTracker(clock.Now), clock
+}
+
+// testPresenceClient crée un client WebSocket sans connexion qui reçoit les messages dans send
+func testPresenceClient(username string, token *utils.APIToken) *WebSocketClient {
+	return &WebSocketClient{
+		username: username,
+		auth:     &requestAuth{Username: username, Token: token},
+		send:     make(chan ServerMessage, 8),
+	}
+}
+
+// nextPresenceEvent retourne l'événement en file ou échoue si aucun n'a été publié
+func nextPresenceEvent(t *testing.T, p *presenceTracker) PresenceEvent {
+	t.Helper()
+	select {
+	case event := <-p.events:
+		return event
+	default:
+		t.Fatal("aucun changement de statut publié")
+		return PresenceEvent{}
+	}
+}
+
+// noPresenceEvent échoue si un changement de statut a été publié
+func noPresenceEvent(t *testing.T, p *presenceTracker) {
+	t.Helper()
+	select {
+	case event := <-p.events:
+		t.Fatalf("changement de statut inattendu %+v", event)
+	default:
+	}
+}
+
+func TestPresenceTimeout(t *testing.T) {
+	type step struct {
+		action func(p *presenceTracker, client *WebSocketClient)
+		after  time.Duration // Temps écoulé avant le recalcul
+		want   string        // Statut publié, vide si aucun changement
+	}
+	touch := func(p *presenceTracker, client *WebSocketClient) { p.Touch("alice") }
+	connect := func(p *presenceTracker, client *WebSocketClient) { p.Connect(client) }
+	disconnect := func(p *presenceTracker, client *WebSocketClient) { p.Disconnect(client) }
+	busy := func(p *presenceTracker, client *WebSocketClient) { p.SetManual("alice", utils.StatusBusy) }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"activité sans WebSocket", []step{
+			{touch, 0, "online"},
+			{nil, presenceTimeout - time.Second, ""},
+			{nil, time.Second, "offline"},
+		}},
+		{"activité prolongée par une requête", []step{
+			{touch, 0, "online"},
+			{touch, presenceTimeout - time.Second, ""},
+			{nil, presenceTimeout - time.Second, ""},
+			{nil, time.Second, "offline"},
+		}},
+		{"WebSocket ouvert : absent, jamais hors ligne", []step{
+			{connect, 0, "online"},
+			{nil, presenceAwayAfter, "away"},
+			{nil, time.Hour, ""},
+		}},
+		{"WebSocket fermé", []step{
+			{connect, 0, "online"},
+			{disconnect, time.Minute, ""},
+			{nil, presenceTimeout - time.Minute, "offline"},
+		}},
+		{"statut occupé sans WebSocket", []step{
+			{busy, 0, "busy"},
+			{nil, presenceTimeout, "offline"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock := testPresence(t)
+			client := testPresenceClient("alice", nil)
+
+			for i, s := range tt.steps {
+				clock.Advance(s.after)
+				if s.action != nil {
+					s.action(p, client)
+				} else {
+					p.expire()
+				}
+				if s.want == "" {
+					noPresenceEvent(t, p)
+					continue
+				}
+				event := nextPresenceEvent(t, p)
+				if event.Username != "alice" || event.Status != s.want || !event.Since.Equal(clock.Now()) {
+					t.Errorf("étape %d : %+v, attendu %s à %s", i, event, s.want, clock.Now())
+				}
+				if got := p.Status("alice").Status; got != s.want {
+					t.Errorf("étape %d : Status %s, attendu %s", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPresenceOfflineForgetsState(t *testing.T) {
+	p, clock := testPresence(t)
+	p.Touch("alice")
+	p.SetManual("bob", utils.StatusAway)
+	clock.Advance(presenceTimeout)
+	p.expire()
+
+	// Sans statut choisi, l'utilisateur hors ligne n'est plus suivi ; un statut choisi est conservé
+	p.mu.Lock()
+	_, alice := p.states["alice"]
+	_, bob := p.states["bob"]
+	p.mu.Unlock()
+	if alice || !bob {
+		t.Errorf("états suivis : alice %v, bob %v", alice, bob)
+	}
+	if users := p.OnlineUsers(); len(users) != 0 {
+		t.Errorf("utilisateurs présents %v", users)
+	}
+}
+
+func TestPresenceOfflineBroadcast(t *testing.T) {
+	cfg := testSettings(t)
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	chain.AddMessageBlock(blockchain.CreateMessage("alice", "bob", "bonjour"), 1)
+	chain.AddMessageBlock(blockchain.CreateMessage("carol", "dave", "salut"), 1)
+	previous := bc
+	bc = chain
+	t.Cleanup(func() { bc = previous })
+
+	p, clock := testPresence(t)
+	go p.dispatch()
+	t.Cleanup(func() { close(p.events) })
+
+	// Le client d'alice utilise un jeton d'API : il reçoit les statuts sans la rendre présente
+	token := &utils.APIToken{}
+	clients := map[string]*WebSocketClient{
+		"alice": testPresenceClient("alice", token),
+		"bob":   testPresenceClient("bob", token),
+		"carol": testPresenceClient("carol", token),
+	}
+	for _, client := range clients {
+		p.Connect(client)
+	}
+
+	p.Touch("alice")
+	clock.Advance(presenceTimeout)
+	p.expire()
+
+	for _, want := range []string{"online", "offline"} {
+		for _, name := range []string{"alice", "bob"} {
+			select {
+			case message := <-clients[name].send:
+				event, ok := message.Data.(PresenceEvent)
+				if message.Type != "presence" || !ok || event.Username != "alice" || event.Status != want {
+					t.Errorf("%s a reçu %+v, attendu alice %s", name, message, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s n'a pas reçu le statut %s d'alice", name, want)
+			}
+		}
+	}
+
+	// carol n'a jamais échangé avec alice : aucun statut ne lui est envoyé
+	select {
+	case message := <-clients["carol"].send:
+		t.Errorf("carol a reçu %+v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/i18n"
+	"BkC/metrics"
+	"BkC/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Interface JSON-RPC 2.0 (POST /api/v1/rpc et WebSocket /ws)
+const (
+	rpcVersion      = "2.0"
+	maxRPCBatch     = 50        // Requêtes au plus dans un lot
+	maxRPCBodyBytes = 256 << 10 // Taille maximale d'une requête HTTP (lot compris)
+)
+
+// Codes d'erreur JSON-RPC : codes standard, puis erreurs du serveur (-32000 à -32099)
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcForbidden      = -32003 // Rôle ou portée du jeton insuffisants
+	rpcNotFound       = -32004 // Bloc introuvable
+	rpcServerBusy     = -32005 // Trop d'appels en cours sur la connexion WebSocket
+)
+
+var rpcCalls = metrics.NewCounterVec("bkc_rpc_calls_total",
+	"Appels JSON-RPC, par méthode (unknown : méthode inconnue, invalid : requête invalide) et résultat (success, error).",
+	"method", "result")
+
+// RPCRequest est une requête JSON-RPC 2.0 ; sans id, c'est une notification, sans réponse
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"` // Toujours "2.0"
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"` // Objet (paramètres nommés) ou tableau (positionnels)
+	ID      json.RawMessage `json:"id,omitempty"`     // Chaîne, nombre ou null
+}
+
+// RPCResponse est la réponse à une requête JSON-RPC : result ou error
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"` // null si la requête était illisible
+}
+
+// RPCError décrit l'échec d'une requête JSON-RPC
+type RPCError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *RPCErrorData `json:"data,omitempty"`
+}
+
+// RPCErrorData précise une erreur JSON-RPC avec le code d'erreur de l'API REST
+type RPCErrorData struct {
+	Code    string                   `json:"code"`
+	Details []*utils.ValidationError `json:"details,omitempty"` // Erreurs par paramètre
+}
+
+// rpcContext est le contexte d'un appel : chaîne, authentification et langue des messages
+type rpcContext struct {
+	bc   *blockchain.Blockchain
+	auth *requestAuth
+	lang string
+}
+
+// rpcMethod est une méthode JSON-RPC, protégée comme une route de l'API
+type rpcMethod struct {
+	Role   utils.Role
+	Scope  utils.TokenScope
+	Params []string // Noms des paramètres, dans l'ordre des paramètres positionnels
+	Call   func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError)
+}
+
+// rpcMethods sont les méthodes exposées ; les paramètres reçus sont toujours un objet
+var rpcMethods = map[string]rpcMethod{
+	"chain_getHeight": {
+		Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			_, total := c.bc.GetBlocks(0, 0)
+			return total, nil
+		},
+	},
+	"chain_getBlockByIndex": {
+		Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain, Params: []string{"index"},
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			var p struct {
+				Index *int `json:"index"`
+			}
+			if err := c.decode(params, &p); err != nil {
+				return nil, err
+			}
+			if p.Index == nil {
+				return nil, c.invalidParams(&utils.ValidationError{Field: "index", Code: "required", Message: "L'index du bloc est obligatoire"})
+			}
+			block, ok := c.bc.GetBlock(*p.Index)
+			if !ok {
+				return nil, c.error(rpcNotFound, errNotFound, i18n.T(c.lang, "error.block_not_found", *p.Index))
+			}
+			return block, nil
+		},
+	},
+	"chain_getBlockByHash": {
+		Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain, Params: []string{"hash"},
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			var p struct {
+				Hash string `json:"hash"`
+			}
+			if err := c.decode(params, &p); err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(p.Hash) == "" {
+				return nil, c.invalidParams(&utils.ValidationError{Field: "hash", Code: "required", Message: "Le hash du bloc est obligatoire"})
+			}
+			block, ok := c.bc.GetBlockByHash(strings.TrimSpace(p.Hash))
+			if !ok {
+				return nil, c.error(rpcNotFound, errNotFound, i18n.T(c.lang, "error.block_hash_not_found", p.Hash))
+			}
+			return block, nil
+		},
+	},
+	"msg_send": {
+		Role: utils.RoleMember, Scope: utils.ScopeSendMessages, Params: []string{"recipient", "content"},
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			var p MessageData
+			if err := c.decode(params, &p); err != nil {
+				return nil, err
+			}
+			recipient, errs := validateNewMessage(p.Recipient, p.Content)
+			if len(errs) > 0 {
+				return nil, c.invalidParams(errs...)
+			}
+			// Comme POST /api/v1/messages : le message est ajouté à la chaîne une fois le bloc miné
+			message := blockchain.CreateMessage(c.auth.Username, recipient, p.Content)
+			c.bc.AddMessageBlockAsync(message, settings.Difficulty.Messages)
+			return message, nil
+		},
+	},
+	"msg_list": {
+		Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain, Params: []string{"with"},
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			var p struct {
+				With string `json:"with"`
+			}
+			if err := c.decode(params, &p); err != nil {
+				return nil, err
+			}
+			messages := make([]blockchain.Message, 0)
+			for _, message := range c.bc.GetUserMessages(c.auth.Username) {
+				if p.With == "" || message.Sender == p.With || message.Recipient == p.With {
+					messages = append(messages, message)
+				}
+			}
+			return messages, nil
+		},
+	},
+	"mining_submit": {
+		Role: utils.RoleMember, Scope: utils.ScopeMine, Params: []string{"data"},
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			var p MineRequest
+			if err := c.decode(params, &p); err != nil {
+				return nil, err
+			}
+			// La réponse attend la fin du minage
+			return MineBlock(c.bc, c.auth.Username, p.Data), nil
+		},
+	},
+	"node_stats": {
+		Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			return CollectStats(c.bc), nil
+		},
+	},
+}
+
+// error construit une erreur JSON-RPC
+func (c *rpcContext) error(code int, apiCode, message string, details ...*utils.ValidationError) *RPCError {
+	return &RPCError{Code: code, Message: message, Data: &RPCErrorData{Code: apiCode, Details: translateErrors(c.lang, details)}}
+}
+
+// invalidParams signale des paramètres refusés
+func (c *rpcContext) invalidParams(details ...*utils.ValidationError) *RPCError {
+	return c.error(rpcInvalidParams, errValidation, i18n.T(c.lang, "rpc.invalid_params"), details...)
+}
+
+// decode lit les paramètres d'une méthode (un objet, voir normalizeParams)
+func (c *rpcContext) decode(params json.RawMessage, v interface{}) *RPCError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return c.invalidParams(&utils.ValidationError{Field: "params", Code: "invalid", Message: "Paramètres invalides"})
+	}
+	return nil
+}
+
+// normalizeParams convertit les paramètres positionnels en objet, d'après les noms déclarés par
+// la méthode ; des paramètres absents donnent un objet vide
+func (c *rpcContext) normalizeParams(method rpcMethod, params json.RawMessage) (json.RawMessage, *RPCError) {
+	params = bytes.TrimSpace(params)
+	switch {
+	case len(params) == 0 || bytes.Equal(params, []byte("null")):
+		return json.RawMessage("{}"), nil
+	case params[0] == '{':
+		return params, nil
+	case params[0] == '[':
+		var positional []json.RawMessage
+		if err := json.Unmarshal(params, &positional); err != nil {
+			return nil, c.error(rpcInvalidRequest, errBadRequest, i18n.T(c.lang, "rpc.invalid_request"))
+		}
+		if len(positional) > len(method.Params) {
+			return nil, c.invalidParams(&utils.ValidationError{
+				Field: "params", Code: "too_many", Args: []interface{}{len(method.Params)},
+				Message: fmt.Sprintf("Trop de paramètres (%d au maximum)", len(method.Params)),
+			})
+		}
+		named := make(map[string]json.RawMessage, len(positional))
+		for i, value := range positional {
+			named[method.Params[i]] = value
+		}
+		object, _ := json.Marshal(named)
+		return object, nil
+	}
+	// La spécification n'autorise qu'un objet ou un tableau
+	return nil, c.error(rpcInvalidRequest, errBadRequest, i18n.T(c.lang, "rpc.invalid_request"))
+}
+
+// validRPCID indique si un identifiant de requête est une chaîne, un nombre ou null
+func validRPCID(id json.RawMessage) bool {
+	var value interface{}
+	if err := json.Unmarshal(id, &value); err != nil {
+		return false
+	}
+	switch value.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+// call exécute une requête et retourne sa réponse, ou nil pour une notification
+func (c *rpcContext) call(raw json.RawMessage) *RPCResponse {
+	var req RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != rpcVersion || req.Method == "" || (req.ID != nil && !validRPCID(req.ID)) {
+		// Requête invalide : l'identifiant n'est repris que s'il est lisible
+		var id json.RawMessage
+		if req.ID != nil && validRPCID(req.ID) {
+			id = req.ID
+		}
+		rpcCalls.Inc("invalid", "error")
+		return &RPCResponse{JSONRPC: rpcVersion, Error: c.error(rpcInvalidRequest, errBadRequest, i18n.T(c.lang, "rpc.invalid_request")), ID: id}
+	}
+
+	result, rerr := c.invoke(req)
+	if req.ID == nil {
+		return nil
+	}
+	if rerr != nil {
+		return &RPCResponse{JSONRPC: rpcVersion, Error: rerr, ID: req.ID}
+	}
+	return &RPCResponse{JSONRPC: rpcVersion, Result: result, ID: req.ID}
+}
+
+// invoke vérifie les droits de l'appelant puis appelle la méthode
+func (c *rpcContext) invoke(req RPCRequest) (result interface{}, rerr *RPCError) {
+	method, exists := rpcMethods[req.Method]
+	label := req.Method
+	if !exists {
+		label = "unknown"
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.Error("Appel JSON-RPC interrompu", "method", req.Method, "user", c.auth.Username, "panic", recovered)
+			result, rerr = nil, c.error(rpcInternalError, errInternal, i18n.T(c.lang, "rpc.internal_error"))
+		}
+		if rerr != nil {
+			rpcCalls.Inc(label, "error")
+		} else {
+			rpcCalls.Inc(label, "success")
+		}
+	}()
+
+	if !exists {
+		return nil, c.error(rpcMethodNotFound, errNotFound, i18n.T(c.lang, "rpc.method_not_found", req.Method))
+	}
+	if !c.auth.Allows(method.Scope) {
+		slog.Warn("Portée du jeton insuffisante", "token", c.auth.Token.ID, "scope", method.Scope, "method", req.Method)
+		return nil, c.error(rpcForbidden, errForbidden, i18n.T(c.lang, "error.insufficient_scope"))
+	}
+	if !hasRole(c.auth.Username, method.Role) {
+		slog.Warn("Accès refusé (rôle insuffisant)", "method", req.Method, "user", c.auth.Username)
+		return nil, c.error(rpcForbidden, errForbidden, i18n.T(c.lang, "error.forbidden"))
+	}
+
+	params, rerr := c.normalizeParams(method, req.Params)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return method.Call(c, params)
+}
+
+// serve traite une requête ou un lot de requêtes JSON-RPC et retourne la réponse encodée,
+// ou nil si elle ne contenait que des notifications
+func (c *rpcContext) serve(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	var response interface{}
+
+	switch {
+	case !json.Valid(body):
+		response = &RPCResponse{JSONRPC: rpcVersion, Error: c.error(rpcParseError, errInvalidJSON, i18n.T(c.lang, "rpc.parse_error"))}
+
+	case body[0] == '[':
+		var batch []json.RawMessage
+		json.Unmarshal(body, &batch)
+		if len(batch) == 0 {
+			response = &RPCResponse{JSONRPC: rpcVersion, Error: c.error(rpcInvalidRequest, errBadRequest, i18n.T(c.lang, "rpc.invalid_request"))}
+			break
+		}
+		if len(batch) > maxRPCBatch {
+			response = &RPCResponse{JSONRPC: rpcVersion, Error: c.error(rpcInvalidRequest, errBadRequest, i18n.T(c.lang, "rpc.batch_too_large", maxRPCBatch))}
+			break
+		}
+		responses := make([]*RPCResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := c.call(raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		response = responses
+
+	default:
+		resp := c.call(body)
+		if resp == nil {
+			return nil
+		}
+		response = resp
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		slog.Error("Encodage de la réponse JSON-RPC impossible", "error", err)
+		data, _ = json.Marshal(&RPCResponse{JSONRPC: rpcVersion, Error: c.error(rpcInternalError, errInternal, i18n.T(c.lang, "rpc.internal_error"))})
+	}
+	return data
+}
+
+// refuse répond à une requête ou à chaque requête d'un lot par la même erreur, sans appeler les
+// méthodes ; retourne nil si elle ne contenait que des notifications
+func (c *rpcContext) refuse(body []byte, rerr *RPCError) []byte {
+	body = bytes.TrimSpace(body)
+	batch := len(body) > 0 && body[0] == '['
+	calls := []json.RawMessage{body}
+	if batch {
+		calls = nil
+		json.Unmarshal(body, &calls)
+	}
+
+	responses := make([]*RPCResponse, 0, len(calls))
+	for _, raw := range calls {
+		var req RPCRequest
+		if err := json.Unmarshal(raw, &req); err == nil && req.ID == nil {
+			continue // Notification
+		}
+		var id json.RawMessage
+		if req.ID != nil && validRPCID(req.ID) {
+			id = req.ID
+		}
+		responses = append(responses, &RPCResponse{JSONRPC: rpcVersion, Error: rerr, ID: id})
+	}
+
+	var data []byte
+	switch {
+	case len(calls) == 0:
+		data, _ = json.Marshal(&RPCResponse{JSONRPC: rpcVersion, Error: rerr})
+	case len(responses) == 0:
+		return nil
+	case batch:
+		data, _ = json.Marshal(responses)
+	default:
+		data, _ = json.Marshal(responses[0])
+	}
+	return data
+}
+
+// isRPCMessage indique si un message WebSocket est une requête JSON-RPC (ou un lot) plutôt
+// qu'un ClientMessage
+func isRPCMessage(message []byte) bool {
+	message = bytes.TrimSpace(message)
+	if len(message) > 0 && message[0] == '[' {
+		return true
+	}
+	var probe struct {
+		JSONRPC *string `json:"jsonrpc"`
+	}
+	return json.Unmarshal(message, &probe) == nil && probe.JSONRPC != nil
+}
+
+// apiRPC traite les requêtes JSON-RPC envoyées en HTTP. Les erreurs sont retournées dans la
+// réponse JSON-RPC (statut 200) ; un lot de notifications reçoit 204.
+func apiRPC(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth, _ := r.Context().Value(authContextKey{}).(*requestAuth)
+		c := &rpcContext{bc: bc, auth: auth, lang: localeOf(r)}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if !errors.As(err, &tooLarge) {
+				return
+			}
+			detail := &utils.ValidationError{
+				Field: "body", Code: "too_large", Args: []interface{}{tooLarge.Limit},
+				Message: fmt.Sprintf("Corps de la requête trop volumineux (%d octets au maximum)", tooLarge.Limit),
+			}
+			writeAPIJSON(w, http.StatusOK, &RPCResponse{JSONRPC: rpcVersion, Error: c.error(rpcParseError, errInvalidJSON, localizeError(r, detail).Message, detail)})
+			return
+		}
+
+		response := c.serve(body)
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testUsers remplace les comptes le temps d'un test
+func testUsers(t *testing.T, accounts ...*utils.User) {
+	t.Helper()
+	mu.Lock()
+	previous := users
+	users = make(map[string]*utils.User, len(accounts))
+	for _, user := range accounts {
+		users[user.Username] = user
+	}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		users = previous
+		mu.Unlock()
+	})
+}
+
+// rpcReply est une réponse JSON-RPC décodée pour les tests
+type rpcReply struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	ID     json.RawMessage `json:"id"`
+}
+
+func TestRPCServe(t *testing.T) {
+	cfg := testSettings(t)
+	testUsers(t,
+		&utils.User{Username: "alice", Role: utils.RoleMember},
+		&utils.User{Username: "bob", Role: utils.RoleReadOnly},
+		&utils.User{Username: "eve", Role: utils.RoleMember, Disabled: true},
+	)
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	genesis, _ := chain.GetBlock(0)
+	readOnlyToken := &utils.APIToken{ID: "tok", Owner: "alice", Scopes: []utils.TokenScope{utils.ScopeReadChain}}
+
+	tests := []struct {
+		name   string
+		user   string
+		token  *utils.APIToken
+		body   string
+		code   int    // Code d'erreur attendu, 0 pour un succès
+		result string // Extrait attendu du résultat
+		id     string
+	}{
+		{"hauteur", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getHeight","id":1}`, 0, "1", "1"},
+		{"bloc par index nommé", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getBlockByIndex","params":{"index":0},"id":"a"}`, 0, genesis.Hash, `"a"`},
+		{"bloc par index positionnel", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getBlockByIndex","params":[0],"id":2}`, 0, genesis.Hash, "2"},
+		{"bloc par hash", "bob", nil, fmt.Sprintf(`{"jsonrpc":"2.0","method":"chain_getBlockByHash","params":[%q],"id":3}`, genesis.Hash), 0, genesis.Hash, "3"},
+		{"bloc introuvable", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getBlockByIndex","params":[99],"id":4}`, rpcNotFound, "", "4"},
+		{"paramètre manquant", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getBlockByIndex","id":5}`, rpcInvalidParams, "", "5"},
+		{"trop de paramètres", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getBlockByIndex","params":[0,1],"id":6}`, rpcInvalidParams, "", "6"},
+		{"paramètres de mauvais type", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getBlockByIndex","params":{"index":"zéro"},"id":7}`, rpcInvalidParams, "", "7"},
+		{"paramètres ni objet ni tableau", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getHeight","params":"x","id":8}`, rpcInvalidRequest, "", "8"},
+		{"méthode inconnue", "bob", nil, `{"jsonrpc":"2.0","method":"chain_drop","id":9}`, rpcMethodNotFound, "", "9"},
+		{"mauvaise version", "bob", nil, `{"jsonrpc":"1.0","method":"chain_getHeight","id":10}`, rpcInvalidRequest, "", "10"},
+		{"identifiant invalide", "bob", nil, `{"jsonrpc":"2.0","method":"chain_getHeight","id":{"a":1}}`, rpcInvalidRequest, "", "null"},
+		{"JSON illisible", "bob", nil, `{"jsonrpc":`, rpcParseError, "", "null"},
+		{"lot vide", "bob", nil, `[]`, rpcInvalidRequest, "", "null"},
+		{"rôle insuffisant", "bob", nil, `{"jsonrpc":"2.0","method":"msg_send","params":["alice","bonjour"],"id":11}`, rpcForbidden, "", "11"},
+		{"compte désactivé", "eve", nil, `{"jsonrpc":"2.0","method":"chain_getHeight","id":12}`, rpcForbidden, "", "12"},
+		{"portée du jeton insuffisante", "alice", readOnlyToken, `{"jsonrpc":"2.0","method":"msg_send","params":["bob","bonjour"],"id":13}`, rpcForbidden, "", "13"},
+		{"portée du jeton suffisante", "alice", readOnlyToken, `{"jsonrpc":"2.0","method":"chain_getHeight","id":14}`, 0, "1", "14"},
+		{"message invalide", "alice", nil, `{"jsonrpc":"2.0","method":"msg_send","params":{"recipient":"inconnu","content":""},"id":15}`, rpcInvalidParams, "unknown_recipient", "15"},
+	}
+	for _, tt := range tests {
+		c := &rpcContext{bc: chain, auth: &requestAuth{Username: tt.user, Token: tt.token}, lang: "fr"}
+		var reply rpcReply
+		if err := json.Unmarshal(c.serve([]byte(tt.body)), &reply); err != nil {
+			t.Errorf("%s : réponse illisible : %v", tt.name, err)
+			continue
+		}
+		if string(reply.ID) != tt.id {
+			t.Errorf("%s : id %s, attendu %s", tt.name, reply.ID, tt.id)
+		}
+		switch {
+		case tt.code == 0 && reply.Error != nil:
+			t.Errorf("%s : erreur %+v", tt.name, reply.Error)
+		case tt.code == 0 && !strings.Contains(string(reply.Result), tt.result):
+			t.Errorf("%s : résultat %s, attendu %q", tt.name, reply.Result, tt.result)
+		case tt.code != 0 && (reply.Error == nil || reply.Error.Code != tt.code):
+			t.Errorf("%s : erreur %+v, code attendu %d", tt.name, reply.Error, tt.code)
+		case tt.code != 0 && tt.result != "":
+			details, _ := json.Marshal(reply.Error.Data)
+			if !strings.Contains(string(details), tt.result) {
+				t.Errorf("%s : détails %s, attendu %q", tt.name, details, tt.result)
+			}
+		}
+	}
+}
+
+func TestRPCBatchAndNotifications(t *testing.T) {
+	cfg := testSettings(t)
+	testUsers(t, &utils.User{Username: "bob", Role: utils.RoleReadOnly})
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	c := &rpcContext{bc: chain, auth: &requestAuth{Username: "bob"}, lang: "fr"}
+
+	// Une notification ne reçoit pas de réponse, même seule dans un lot
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"chain_getHeight"}`,
+		`[{"jsonrpc":"2.0","method":"chain_getHeight"},{"jsonrpc":"2.0","method":"inconnue"}]`,
+	} {
+		if response := c.serve([]byte(body)); response != nil {
+			t.Errorf("notification %s : réponse %s", body, response)
+		}
+	}
+
+	// Les réponses d'un lot reprennent les identifiants, les notifications sont omises
+	var replies []rpcReply
+	body := `[{"jsonrpc":"2.0","method":"chain_getHeight","id":1},{"jsonrpc":"2.0","method":"chain_getHeight"},{"jsonrpc":"2.0","method":"inconnue","id":"x"},42]`
+	if err := json.Unmarshal(c.serve([]byte(body)), &replies); err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 3 || string(replies[0].ID) != "1" || replies[0].Error != nil ||
+		string(replies[1].ID) != `"x"` || replies[1].Error.Code != rpcMethodNotFound ||
+		replies[2].Error == nil || replies[2].Error.Code != rpcInvalidRequest {
+		t.Errorf("lot : %+v", replies)
+	}
+
+	// Lot trop grand : une seule erreur
+	calls := make([]string, maxRPCBatch+1)
+	for i := range calls {
+		calls[i] = fmt.Sprintf(`{"jsonrpc":"2.0","method":"chain_getHeight","id":%d}`, i)
+	}
+	var reply rpcReply
+	if err := json.Unmarshal(c.serve([]byte("["+strings.Join(calls, ",")+"]")), &reply); err != nil || reply.Error == nil || reply.Error.Code != rpcInvalidRequest {
+		t.Errorf("lot trop grand : %+v, %v", reply, err)
+	}
+}
+
+func TestAPIRPC(t *testing.T) {
+	cfg := testSettings(t)
+	testUsers(t, &utils.User{Username: "bob", Role: utils.RoleReadOnly})
+	handler := apiRPC(blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   int
+	}{
+		{"appel", `{"jsonrpc":"2.0","method":"chain_getHeight","id":1}`, http.StatusOK, 0},
+		{"erreur dans la réponse", `{"jsonrpc":"2.0","method":"inconnue","id":1}`, http.StatusOK, rpcMethodNotFound},
+		{"notification", `{"jsonrpc":"2.0","method":"chain_getHeight"}`, http.StatusNoContent, 0},
+		{"corps trop volumineux", `"` + strings.Repeat("a", maxRPCBodyBytes) + `"`, http.StatusOK, rpcParseError},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/rpc", strings.NewReader(tt.body))
+		r = r.WithContext(context.WithValue(r.Context(), authContextKey{}, &requestAuth{Username: "bob"}))
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s : statut %d, attendu %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusNoContent {
+			continue
+		}
+		var reply rpcReply
+		if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+			t.Errorf("%s : réponse illisible : %v", tt.name, err)
+			continue
+		}
+		code := 0
+		if reply.Error != nil {
+			code = reply.Error.Code
+		}
+		if code != tt.code {
+			t.Errorf("%s : code %d, attendu %d", tt.name, code, tt.code)
+		}
+	}
+}
+
+func TestIsRPCMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		rpc     bool
+	}{
+		{`{"jsonrpc":"2.0","method":"chain_getHeight","id":1}`, true},
+		{`  [{"jsonrpc":"2.0"}]`, true},
+		{`[]`, true},
+		{`{"jsonrpc":null}`, false},
+		{`{"type":"subscribe","channel":"blocks"}`, false},
+		{`pas du JSON`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := isRPCMessage([]byte(tt.message)); got != tt.rpc {
+			t.Errorf("isRPCMessage(%q) = %v, attendu %v", tt.message, got, tt.rpc)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeFrameTimeout borne l'envoi de la trame de fermeture à chaque client WebSocket
+const closeFrameTimeout = time.Second
+
+// CloseWebSockets ferme toutes les connexions WebSocket avec une trame de fermeture « going away ».
+// Les connexions WebSocket échappent à http.Server.Shutdown : elles doivent être fermées ici.
+func CloseWebSockets() {
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "Arrêt du serveur")
+
+	presence.mu.Lock()
+	clients := make([]*WebSocketClient, 0, len(presence.clients))
+	for client := range presence.clients {
+		clients = append(clients, client)
+	}
+	presence.mu.Unlock()
+
+	for _, client := range clients {
+		// WriteControl peut être appelé en même temps que les écritures de writePump
+		client.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeFrameTimeout))
+		client.conn.Close()
+	}
+}
+
+// SaveState sauvegarde les sessions et les jetons d'API (dont la date de dernière utilisation
+// n'est conservée qu'en mémoire) ; appelé à l'arrêt du serveur
+func SaveState() error {
+	var errs []error
+	if err := SaveSessions(); err != nil {
+		errs = append(errs, fmt.Errorf("sessions: %v", err))
+	}
+	if err := SaveAPITokens(); err != nil {
+		errs = append(errs, fmt.Errorf("jetons d'API: %v", err))
+	}
+	return errors.Join(errs...)
+}
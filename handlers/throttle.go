@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Limiteurs protégeant la connexion et l'inscription contre la force brute
+var (
+	// Par compte : 3 essais libres, puis 1s, 2s, 4s... (max 5 min), verrouillage 15 min après 10 échecs
+	loginAccountLimiter = utils.NewAttemptLimiter(3, time.Second, 5*time.Minute, 10, 15*time.Minute, time.Hour)
+	// Par IP : plus tolérant car une IP peut être partagée par plusieurs utilisateurs
+	loginIPLimiter = utils.NewAttemptLimiter(10, time.Second, 5*time.Minute, 30, 15*time.Minute, time.Hour)
+	// Inscriptions : 3 comptes par IP et par heure
+	signupLimiter = utils.NewRateLimiter(3, time.Hour)
+)
+
+// LockoutView décrit un blocage dans les réponses de l'API d'administration
+type LockoutView struct {
+	Kind string `json:"kind"` // "account", "ip" ou "signup"
+	utils.Lockout
+}
+
+// accountKey normalise le nom d'utilisateur utilisé comme clé de limitation
+func accountKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// checkLoginAllowed vérifie les limiteurs de compte et d'IP avant une tentative de connexion
+func checkLoginAllowed(username, clientIP string) (bool, time.Duration) {
+	accountOK, accountWait := loginAccountLimiter.Allow(accountKey(username))
+	ipOK, ipWait := loginIPLimiter.Allow(clientIP)
+	if accountOK && ipOK {
+		return true, 0
+	}
+	if accountWait > ipWait {
+		return false, accountWait
+	}
+	return false, ipWait
+}
+
+// recordLoginFailure enregistre un échec de connexion pour le compte et l'IP
+func recordLoginFailure(username, clientIP string) {
+	loginAccountLimiter.Fail(accountKey(username))
+	loginIPLimiter.Fail(clientIP)
+}
+
+// recordLoginSuccess réinitialise le compteur du compte après une connexion réussie.
+// Le compteur de l'IP est conservé pour ne pas permettre de le remettre à zéro
+// en se connectant régulièrement à un compte contrôlé.
+func recordLoginSuccess(username string) {
+	loginAccountLimiter.Reset(accountKey(username))
+}
+
+// retrySeconds arrondit un délai d'attente à la seconde supérieure
+func retrySeconds(wait time.Duration) int {
+	return int((wait + time.Second - 1) / time.Second)
+}
+
+// currentLockouts rassemble tous les blocages actifs
+func currentLockouts() []LockoutView {
+	views := make([]LockoutView, 0)
+	for _, lockout := range loginAccountLimiter.Lockouts() {
+		views = append(views, LockoutView{Kind: "account", Lockout: lockout})
+	}
+	for _, lockout := range loginIPLimiter.Lockouts() {
+		views = append(views, LockoutView{Kind: "ip", Lockout: lockout})
+	}
+	for _, lockout := range signupLimiter.Lockouts() {
+		views = append(views, LockoutView{Kind: "signup", Lockout: lockout})
+	}
+	return views
+}
+
+// AdminLockoutsHandler liste les comptes et IP actuellement bloqués
+func AdminLockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentLockouts())
+}
+
+// AdminClearLockoutHandler lève le blocage d'un compte, d'une IP ou des inscriptions d'une IP
+func AdminClearLockoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Kind string `json:"kind"`
+		Key  string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key == "" {
+		http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+		return
+	}
+
+	switch req.Kind {
+	case "account":
+		loginAccountLimiter.Reset(req.Key)
+	case "ip":
+		loginIPLimiter.Reset(req.Key)
+	case "signup":
+		signupLimiter.Reset(req.Key)
+	default:
+		http.Error(w, tr(r, "error.unknown_lockout_type"), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminSuccess(w, tr(r, "success.lockout_cleared"))
+}
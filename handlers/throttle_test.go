@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminClearLockout(t *testing.T) {
+	tests := []struct {
+		kind    string
+		key     string
+		block   func(key string)
+		blocked func(key string) bool
+	}{
+		{"account", "mallory",
+			func(key string) {
+				for i := 0; i < 10; i++ {
+					loginAccountLimiter.Fail(key)
+				}
+			},
+			func(key string) bool { ok, _ := loginAccountLimiter.Allow(key); return !ok }},
+		{"ip", "198.51.100.7",
+			func(key string) {
+				for i := 0; i < 30; i++ {
+					loginIPLimiter.Fail(key)
+				}
+			},
+			func(key string) bool { ok, _ := loginIPLimiter.Allow(key); return !ok }},
+		{"signup", "198.51.100.8",
+			func(key string) {
+				for i := 0; i < 3; i++ {
+					signupLimiter.Allow(key)
+				}
+			},
+			func(key string) bool {
+				// Allow enregistre une inscription si elle est autorisée : la clé est oubliée ensuite
+				ok, _ := signupLimiter.Allow(key)
+				signupLimiter.Reset(key)
+				return !ok
+			}},
+	}
+	for _, tt := range tests {
+		tt.block(tt.key)
+		body := `{"kind":"` + tt.kind + `","key":"` + tt.key + `"}`
+		w := httptest.NewRecorder()
+		AdminClearLockoutHandler(w, httptest.NewRequest(http.MethodPost, "/admin/lockouts/clear", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s : statut %d", tt.kind, w.Code)
+		}
+		if tt.blocked(tt.key) {
+			t.Errorf("%s : %s toujours bloqué", tt.kind, tt.key)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	AdminClearLockoutHandler(w, httptest.NewRequest(http.MethodPost, "/admin/lockouts/clear", strings.NewReader(`{"kind":"autre","key":"x"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("type inconnu : statut %d", w.Code)
+	}
+}
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// mfaCookieName porte le jeton de connexion en attente du second facteur
+	mfaCookieName = "mfa_pending"
+	// mfaPendingTTL est le délai laissé pour saisir le second facteur
+	mfaPendingTTL = 5 * time.Minute
+	// mfaMaxAttempts est le nombre de codes erronés tolérés avant de redemander le mot de passe
+	mfaMaxAttempts = 5
+	// totpIssuer est le nom affiché dans les applications d'authentification
+	totpIssuer = "CryptoChain Go"
+)
+
+// pendingLogin représente une connexion dont le mot de passe a été vérifié
+// et qui attend le second facteur
+type pendingLogin struct {
+	Username  string
+	ExpiresAt time.Time
+	Attempts  int
+}
+
+// pendingLogins stocke les connexions en attente, indexées par l'empreinte du jeton (protégé par mu)
+var pendingLogins = make(map[string]*pendingLogin)
+
+// beginSecondFactor enregistre une connexion en attente et pose le cookie correspondant
+func beginSecondFactor(w http.ResponseWriter, r *http.Request, username string) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	mu.Lock()
+	// Nettoyer les connexions en attente expirées
+	for key, pending := range pendingLogins {
+		if now.After(pending.ExpiresAt) {
+			delete(pendingLogins, key)
+		}
+	}
+	pendingLogins[utils.HashToken(token)] = &pendingLogin{
+		Username:  username,
+		ExpiresAt: now.Add(mfaPendingTTL),
+	}
+	mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     mfaCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(mfaPendingTTL / time.Second),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// pendingFromRequest retourne la clé et la connexion en attente associées à la requête.
+// L'appelant doit détenir mu.
+func pendingFromRequest(r *http.Request) (string, *pendingLogin) {
+	cookie, err := r.Cookie(mfaCookieName)
+	if err != nil || cookie.Value == "" {
+		return "", nil
+	}
+
+	key := utils.HashToken(cookie.Value)
+	pending, exists := pendingLogins[key]
+	if !exists {
+		return "", nil
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		delete(pendingLogins, key)
+		return "", nil
+	}
+	return key, pending
+}
+
+// clearPendingCookie supprime le cookie de connexion en attente
+func clearPendingCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   mfaCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+}
+
+// TwoFactorLoginHandler affiche la page de saisie du second facteur
+func TwoFactorLoginHandler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	_, pending := pendingFromRequest(r)
+	mu.Unlock()
+
+	if pending == nil {
+		http.Redirect(w, r, "/login?error=expired", http.StatusSeeOther)
+		return
+	}
+
+	tmpl, err := loadTemplate(r, "login_2fa.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.login"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, map[string]string{
+		"Error":     r.URL.Query().Get("error"),
+		"Retry":     r.URL.Query().Get("retry"),
+		"CSRFToken": CSRFToken(w, r),
+	})
+}
+
+// TwoFactorLoginSubmitHandler vérifie le code TOTP (ou un code de récupération) et ouvre la session
+func TwoFactorLoginSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/login-2fa", http.StatusSeeOther)
+		return
+	}
+
+	code := r.FormValue("code")
+	clientIP := utils.GetVisitorIP(r)
+
+	mu.Lock()
+	key, pending := pendingFromRequest(r)
+	if pending == nil {
+		mu.Unlock()
+		clearPendingCookie(w)
+		http.Redirect(w, r, "/login?error=expired", http.StatusSeeOther)
+		return
+	}
+	username := pending.Username
+	mu.Unlock()
+
+	// Les codes erronés sont limités comme les mots de passe
+	if allowed, wait := checkLoginAllowed(username, clientIP); !allowed {
+		loginAttempts.Inc("blocked")
+		slog.Warn("Second facteur bloqué", "user", username, "ip", clientIP, "retry_in", wait.Round(time.Second))
+		http.Redirect(w, r, fmt.Sprintf("/login-2fa?error=locked&retry=%d", retrySeconds(wait)), http.StatusSeeOther)
+		return
+	}
+
+	mu.Lock()
+	user, exists := users[username]
+	valid := exists && !user.Disabled && user.VerifySecondFactor(code, time.Now())
+	remainingCodes := 0
+	if exists {
+		remainingCodes = len(user.RecoveryCodes)
+	}
+	if valid {
+		delete(pendingLogins, key)
+	} else {
+		pending.Attempts++
+		if pending.Attempts >= mfaMaxAttempts {
+			delete(pendingLogins, key)
+		}
+	}
+	attempts := pending.Attempts
+	mu.Unlock()
+
+	if !valid {
+		recordLoginFailure(username, clientIP)
+		loginAttempts.Inc("failure")
+		slog.Warn("Code de second facteur invalide", "user", username, "ip", clientIP)
+		if attempts >= mfaMaxAttempts {
+			clearPendingCookie(w)
+			http.Redirect(w, r, "/login?error=expired", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/login-2fa?error=1", http.StatusSeeOther)
+		return
+	}
+
+	clearPendingCookie(w)
+	slog.Info("Second facteur validé", "user", username, "recovery_codes_left", remainingCodes)
+	completeLogin(w, r, username, clientIP)
+}
+
+// TwoFactorPageHandler affiche la page de gestion de la double authentification
+func TwoFactorPageHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	csrfToken := CSRFToken(w, r)
+
+	mu.Lock()
+	data := map[string]interface{}{
+		"Username":      user.Username,
+		"Enabled":       user.TOTPEnabled,
+		"RecoveryCodes": len(user.RecoveryCodes),
+		"CSRFToken":     csrfToken,
+	}
+	mu.Unlock()
+
+	tmpl, err := loadTemplate(r, "twofactor.html")
+	if err != nil {
+		http.Error(w, tr(r, "error.page.twofactor"), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, data)
+}
+
+// twoFactorRequest est le corps JSON des actions de double authentification
+type twoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+// decodeTwoFactorRequest vérifie la méthode, la session et décode le corps de la requête
+func decodeTwoFactorRequest(w http.ResponseWriter, r *http.Request) (*utils.User, twoFactorRequest, bool) {
+	var req twoFactorRequest
+	if r.Method != "POST" {
+		http.Error(w, tr(r, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return nil, req, false
+	}
+
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, tr(r, "error.not_logged_in"), http.StatusUnauthorized)
+		return nil, req, false
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, tr(r, "error.invalid_json"), http.StatusBadRequest)
+			return nil, req, false
+		}
+	}
+	return user, req, true
+}
+
+// checkCodeAllowed applique aux codes saisis depuis une session les limites des connexions : sans
+// elles, une session détournée permettrait d'essayer tous les codes. Retourne l'IP du client, ou
+// false après avoir répondu 429.
+func checkCodeAllowed(w http.ResponseWriter, r *http.Request, username string) (string, bool) {
+	clientIP := utils.GetVisitorIP(r)
+	if allowed, wait := checkLoginAllowed(username, clientIP); !allowed {
+		slog.Warn("Code de second facteur bloqué", "user", username, "ip", clientIP, "path", r.URL.Path, "retry_in", wait.Round(time.Second))
+		http.Error(w, tr(r, "error.too_many_attempts", retrySeconds(wait)), http.StatusTooManyRequests)
+		return clientIP, false
+	}
+	return clientIP, true
+}
+
+// TwoFactorSetupHandler génère un nouveau secret TOTP en attente de confirmation
+func TwoFactorSetupHandler(w http.ResponseWriter, r *http.Request) {
+	user, _, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		http.Error(w, tr(r, "error.secret_generation"), http.StatusInternalServerError)
+		return
+	}
+
+	mu.Lock()
+	if user.TOTPEnabled {
+		mu.Unlock()
+		http.Error(w, tr(r, "error.twofactor_already_enabled"), http.StatusConflict)
+		return
+	}
+	user.TOTPPending = secret
+	mu.Unlock()
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret": secret,
+		"uri":    utils.TOTPProvisioningURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// TwoFactorConfirmHandler active la double authentification après vérification d'un premier code
+func TwoFactorConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	clientIP, allowed := checkCodeAllowed(w, r, user.Username)
+	if !allowed {
+		return
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		http.Error(w, tr(r, "error.recovery_codes"), http.StatusInternalServerError)
+		return
+	}
+
+	mu.Lock()
+	if user.TOTPPending == "" {
+		mu.Unlock()
+		http.Error(w, tr(r, "error.no_pending_activation"), http.StatusBadRequest)
+		return
+	}
+	step, valid := utils.ValidateTOTP(user.TOTPPending, req.Code, time.Now(), 0)
+	if valid {
+		user.TOTPEnabled = true
+		user.TOTPSecret = user.TOTPPending
+		user.TOTPPending = ""
+		user.TOTPLastStep = step
+		user.RecoveryCodes = hashes
+	}
+	mu.Unlock()
+
+	if !valid {
+		recordLoginFailure(user.Username, clientIP)
+		http.Error(w, tr(r, "error.invalid_code"), http.StatusBadRequest)
+		return
+	}
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+	slog.Info("Double authentification activée", "user", user.Username)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "success",
+		"message":       tr(r, "success.twofactor_enabled"),
+		"recoveryCodes": codes,
+	})
+}
+
+// TwoFactorDisableHandler désactive la double authentification sur présentation d'un code valide
+func TwoFactorDisableHandler(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	clientIP, allowed := checkCodeAllowed(w, r, user.Username)
+	if !allowed {
+		return
+	}
+
+	mu.Lock()
+	valid := user.VerifySecondFactor(req.Code, time.Now())
+	if valid {
+		user.ResetTwoFactor()
+	}
+	mu.Unlock()
+
+	if !valid {
+		recordLoginFailure(user.Username, clientIP)
+		slog.Warn("Code de second facteur invalide", "user", user.Username, "ip", clientIP, "path", r.URL.Path)
+		http.Error(w, tr(r, "error.invalid_code"), http.StatusBadRequest)
+		return
+	}
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+	slog.Info("Double authentification désactivée", "user", user.Username)
+
+	writeAdminSuccess(w, tr(r, "success.twofactor_disabled"))
+}
+
+// TwoFactorRecoveryCodesHandler remplace les codes de récupération sur présentation d'un code valide
+func TwoFactorRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	clientIP, allowed := checkCodeAllowed(w, r, user.Username)
+	if !allowed {
+		return
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		http.Error(w, tr(r, "error.recovery_codes"), http.StatusInternalServerError)
+		return
+	}
+
+	mu.Lock()
+	valid := user.VerifySecondFactor(req.Code, time.Now())
+	if valid {
+		user.RecoveryCodes = hashes
+	}
+	mu.Unlock()
+
+	if !valid {
+		recordLoginFailure(user.Username, clientIP)
+		slog.Warn("Code de second facteur invalide", "user", user.Username, "ip", clientIP, "path", r.URL.Path)
+		http.Error(w, tr(r, "error.invalid_code"), http.StatusBadRequest)
+		return
+	}
+
+	if err := SaveUsers(); err != nil {
+		slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "success",
+		"message":       tr(r, "success.recovery_codes"),
+		"recoveryCodes": codes,
+	})
+}
+
+// AdminResetTwoFactorHandler désactive la double authentification d'un compte (perte du téléphone...)
+func AdminResetTwoFactorHandler(bc *blockchain.Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin, req, ok := decodeAdminRequest(w, r)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		if user, exists := users[req.Username]; exists {
+			user.ResetTwoFactor()
+		}
+		mu.Unlock()
+
+		if err := SaveUsers(); err != nil {
+			slog.Error("Sauvegarde des utilisateurs impossible", "error", err)
+		}
+
+		recordAudit(bc, admin, "reset_2fa", req.Username, "")
+		writeAdminSuccess(w, tr(r, "success.twofactor_reset"))
+	}
+}
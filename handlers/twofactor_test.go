@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTwoFactorCodesThrottled(t *testing.T) {
+	testSettings(t)
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		ip      string
+	}{
+		{"désactivation", TwoFactorDisableHandler, "198.51.100.21"},
+		{"codes de récupération", TwoFactorRecoveryCodesHandler, "198.51.100.22"},
+	}
+	for _, tt := range tests {
+		_, hashes, _ := utils.GenerateRecoveryCodes()
+		user := &utils.User{Username: "alice", Role: utils.RoleMember, TOTPEnabled: true, TOTPSecret: secret, RecoveryCodes: hashes}
+		testUsers(t, user)
+		session := testSession(t, "alice")
+		resetLimiters := func() {
+			loginAccountLimiter.Reset(accountKey("alice"))
+			loginIPLimiter.Reset(tt.ip)
+		}
+		resetLimiters()
+		t.Cleanup(resetLimiters)
+
+		call := func(code string) int {
+			r := httptest.NewRequest(http.MethodPost, "/api/2fa", strings.NewReader(`{"code":"`+code+`"}`))
+			r.RemoteAddr = tt.ip + ":4000"
+			r.AddCookie(session)
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			return w.Code
+		}
+
+		// Trois essais libres, comme pour les connexions ; le quatrième échec temporise le compte
+		// et l'appel suivant est refusé, même avec un code valide
+		for i := 0; i < 4; i++ {
+			if status := call("invalide"); status != http.StatusBadRequest {
+				t.Fatalf("%s : essai %d, statut %d", tt.name, i+1, status)
+			}
+		}
+		code, _ := utils.TOTPCode(secret, time.Now())
+		if status := call(code); status != http.StatusTooManyRequests {
+			t.Errorf("%s : statut %d après 4 codes erronés, attendu 429", tt.name, status)
+		}
+		if !user.TOTPEnabled || len(user.RecoveryCodes) != len(hashes) || user.RecoveryCodes[0] != hashes[0] {
+			t.Errorf("%s : double authentification modifiée malgré le blocage", tt.name)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUserNotFound est retournée par les opérations sur un compte inexistant
+var ErrUserNotFound = errors.New("utilisateur introuvable")
+
+// Gestion des comptes hors requête HTTP (ligne de commande). Chaque opération est enregistrée
+// dans la piste d'audit au nom de actor, comme les actions de la page d'administration.
+
+// ListUsers retourne les comptes enregistrés, triés par nom
+func ListUsers() []AdminUserView {
+	mu.Lock()
+	views := make([]AdminUserView, 0, len(users))
+	for username, user := range users {
+		view := AdminUserView{
+			Username:  username,
+			Role:      user.Role,
+			Disabled:  user.Disabled,
+			CreatedAt: user.CreatedAt,
+			LastLogin: user.LastLogin,
+			TwoFactor: user.TOTPEnabled,
+		}
+		if session, exists := sessions[username]; exists && session != nil {
+			view.Devices = len(session.Devices)
+		}
+		view.Status = presence.Status(username).Status
+		view.Online = view.Status != utils.StatusOffline.String()
+		views = append(views, view)
+	}
+	mu.Unlock()
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Username < views[j].Username
+	})
+	return views
+}
+
+// UserExists indique si un compte porte exactement ce nom
+func UserExists(username string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, exists := users[username]
+	return exists
+}
+
+// CreateUser crée un compte avec le rôle indiqué, selon les mêmes règles que l'inscription
+func CreateUser(actor, username, password string, role utils.Role) error {
+	if err := utils.ValidateUsername(username); err != nil {
+		return err
+	}
+	if password == "" {
+		return &utils.ValidationError{Field: "password", Code: "required", Message: "Le mot de passe est obligatoire"}
+	}
+
+	mu.Lock()
+	if usernameTaken(username) {
+		mu.Unlock()
+		return &utils.ValidationError{Field: "username", Code: "taken", Message: "Ce nom d'utilisateur est déjà utilisé"}
+	}
+	users[username] = &utils.User{
+		Username:     username,
+		PasswordHash: utils.HashPassword(password),
+		CreatedAt:    time.Now(),
+		Role:         role,
+	}
+	mu.Unlock()
+
+	if err := SaveUsers(); err != nil {
+		return err
+	}
+
+	recordAudit(bc, actor, "create", username, string(role))
+	return nil
+}
+
+// SetUserDisabled active ou désactive un compte ; un compte désactivé perd ses connexions
+func SetUserDisabled(actor, username string, disabled bool) error {
+	mu.Lock()
+	user, exists := users[username]
+	if !exists {
+		mu.Unlock()
+		return ErrUserNotFound
+	}
+	user.Disabled = disabled
+	if disabled {
+		revokeAllDevices(username)
+	}
+	mu.Unlock()
+
+	if err := SaveUsers(); err != nil {
+		return err
+	}
+	if err := SaveSessions(); err != nil {
+		return err
+	}
+
+	action := "enable"
+	if disabled {
+		action = "disable"
+	}
+	recordAudit(bc, actor, action, username, "")
+	return nil
+}
+
+// ResetUserPassword remplace le mot de passe d'un compte, révoque ses connexions et ses jetons
+// d'API et lève le blocage éventuel des tentatives de connexion
+func ResetUserPassword(actor, username, password string) error {
+	if strings.TrimSpace(password) == "" {
+		return &utils.ValidationError{Field: "password", Code: "required", Message: "Le mot de passe est obligatoire"}
+	}
+
+	mu.Lock()
+	user, exists := users[username]
+	if !exists {
+		mu.Unlock()
+		return ErrUserNotFound
+	}
+	user.PasswordHash = utils.HashPassword(password)
+	revokeAllDevices(username)
+	revokeAPITokens(username)
+	mu.Unlock()
+
+	loginAccountLimiter.Reset(accountKey(username))
+
+	if err := SaveUsers(); err != nil {
+		return err
+	}
+	if err := SaveSessions(); err != nil {
+		return err
+	}
+	if err := SaveAPITokens(); err != nil {
+		return err
+	}
+
+	recordAudit(bc, actor, "password_reset", username, "")
+	return nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"BkC/utils"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// maxMessageBodyBytes limite la taille du corps des requêtes d'envoi de message
+const maxMessageBodyBytes = 16 << 10
+
+// ValidationResponse est la réponse JSON renvoyée lorsque des données sont refusées
+type ValidationResponse struct {
+	Status string                   `json:"status"`
+	Errors []*utils.ValidationError `json:"errors"`
+}
+
+// writeValidationErrors répond avec la liste des erreurs de validation
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, status int, errs ...*utils.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ValidationResponse{Status: "error", Errors: localizeErrors(r, errs)})
+}
+
+// findUserFold recherche un compte sans tenir compte de la casse.
+// L'appelant doit détenir mu.
+func findUserFold(username string) (*utils.User, bool) {
+	if user, exists := users[username]; exists {
+		return user, true
+	}
+	for name, user := range users {
+		if strings.EqualFold(name, username) {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+// usernameTaken indique si un nom est déjà utilisé ou retiré, sans tenir compte de la casse.
+// L'appelant doit détenir mu.
+func usernameTaken(username string) bool {
+	if _, exists := findUserFold(username); exists {
+		return true
+	}
+	for name := range retiredUsers {
+		if strings.EqualFold(name, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNewMessage vérifie le destinataire et le contenu d'un message.
+// Elle retourne le nom exact du destinataire enregistré.
+func validateNewMessage(recipient, content string) (string, []*utils.ValidationError) {
+	var errs []*utils.ValidationError
+
+	recipient = strings.TrimSpace(recipient)
+	if recipient == "" {
+		errs = append(errs, &utils.ValidationError{Field: "recipient", Code: "required", Message: "Le destinataire est obligatoire"})
+	} else {
+		mu.Lock()
+		user, exists := findUserFold(recipient)
+		if exists {
+			recipient = user.Username
+		}
+		mu.Unlock()
+
+		if !exists {
+			errs = append(errs, &utils.ValidationError{Field: "recipient", Code: "unknown_recipient", Message: "Le destinataire n'existe pas"})
+		}
+	}
+
+	if err := utils.ValidateMessageContent(content); err != nil {
+		errs = append(errs, err)
+	}
+
+	return recipient, errs
+}
@@ -0,0 +1,681 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/metrics"
+	"BkC/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Événements transmis aux webhooks
+const (
+	WebhookBlockNew   = "block.new"   // Nouveau bloc ajouté à la chaîne
+	WebhookMessageNew = "message.new" // Message reçu par le propriétaire (tous les messages pour un webhook global)
+	WebhookChainReorg = "chain.reorg" // Chaîne remplacée depuis le dernier bloc annoncé
+	WebhookPing       = "ping"        // Test envoyé à la demande (POST /api/v1/webhooks/{id}/ping)
+)
+
+// webhookEvents liste les événements auxquels un webhook peut s'abonner
+var webhookEvents = []string{WebhookBlockNew, WebhookMessageNew, WebhookChainReorg}
+
+// En-têtes des requêtes envoyées aux webhooks
+const (
+	WebhookEventHeader     = "X-BkC-Event"
+	WebhookDeliveryHeader  = "X-BkC-Delivery"
+	WebhookSignatureHeader = "X-BkC-Signature" // "t=<horodatage unix>,v1=<HMAC-SHA256 hexadécimal>"
+	webhookUserAgent       = "BkC-Webhook/1.0"
+)
+
+// webhookRetryBase est le délai avant la deuxième tentative, doublé ensuite (variable pour les tests)
+var webhookRetryBase = 2 * time.Second
+
+// Livraison des webhooks
+const (
+	webhookRetryMax    = 10 * time.Minute // Délai maximal entre deux tentatives
+	webhookConcurrency = 8                // Livraisons simultanées (goroutines de livraison) pour l'ensemble des webhooks
+	webhookLogSize     = 50               // Livraisons récentes conservées par webhook
+	maxDeadLetters     = 1000             // Livraisons en échec conservées (les plus anciennes sont oubliées)
+	maxWebhooksPerUser = 10
+	webhookCatchUpMax  = 100 // Blocs rattrapés au démarrage au plus
+)
+
+// États d'une livraison
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed" // Tentatives épuisées : livraison placée en lettre morte
+)
+
+var errPrivateAddress = errors.New("adresse locale ou privée non autorisée")
+
+// Métriques des webhooks, exposées par /metrics
+var webhookDeliveries = metrics.NewCounterVec("bkc_webhook_deliveries_total",
+	"Tentatives de livraison des webhooks, par résultat (delivered, retry, failed).", "result")
+
+// Webhook est une URL notifiée des événements de la chaîne. Le secret signe les requêtes ;
+// il n'est communiqué qu'à la création.
+type Webhook struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Global    bool      `json:"global"` // Webhook d'administration : reçoit les messages de tous les utilisateurs
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// subscribed indique si le webhook est abonné à l'événement
+func (h *Webhook) subscribed(event string) bool {
+	return slices.Contains(h.Events, event)
+}
+
+// WebhookDelivery est l'envoi d'un événement à un webhook. Toutes les tentatives portent le même
+// identifiant (en-tête X-BkC-Delivery), qui permet au destinataire d'ignorer les doublons.
+type WebhookDelivery struct {
+	ID          string          `json:"id"`
+	WebhookID   string          `json:"webhookId"`
+	Event       string          `json:"event"`
+	State       string          `json:"state"` // pending, delivered ou failed
+	Attempts    int             `json:"attempts"`
+	StatusCode  int             `json:"statusCode,omitempty"` // Dernier code HTTP reçu
+	Error       string          `json:"error,omitempty"`      // Cause du dernier échec
+	CreatedAt   time.Time       `json:"createdAt"`
+	LastAttempt *time.Time      `json:"lastAttempt,omitempty"`
+	Payload     json.RawMessage `json:"payload"` // Corps envoyé
+}
+
+// WebhookPayload est le corps JSON des requêtes envoyées aux webhooks
+type WebhookPayload struct {
+	ID        string      `json:"id"` // Identifiant de la livraison
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"` // Bloc, message, ChainReorg ou identifiant du webhook (ping)
+}
+
+// ChainHead identifie le dernier bloc de la chaîne
+type ChainHead struct {
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// ChainReorg décrit un remplacement de la chaîne (bkc import -force) constaté au démarrage :
+// le dernier bloc annoncé n'en fait plus partie. Les destinataires doivent relire la chaîne.
+type ChainReorg struct {
+	Previous ChainHead `json:"previous"` // Dernier bloc annoncé avant le remplacement
+	Current  ChainHead `json:"current"`  // Dernier bloc de la nouvelle chaîne
+}
+
+// webhookState est le contenu du fichier des webhooks
+type webhookState struct {
+	Webhooks    []*Webhook         `json:"webhooks"`
+	DeadLetters []*WebhookDelivery `json:"deadLetters"`
+	Pending     []*WebhookDelivery `json:"pending,omitempty"` // Livraisons non terminées, reprises au démarrage
+	Head        *ChainHead         `json:"head,omitempty"`    // Dernier bloc annoncé
+}
+
+// webhookDispatcher reçoit les blocs de la chaîne et les livre aux webhooks abonnés
+type webhookDispatcher struct {
+	mu          sync.Mutex
+	hooks       map[string]*Webhook
+	deadLetters []*WebhookDelivery
+	logs        map[string][]WebhookDelivery // Livraisons récentes par webhook, de la plus ancienne à la plus récente
+	pending     map[string]*WebhookDelivery  // Livraisons en cours ou en attente d'une nouvelle tentative
+	queue       []*WebhookDelivery           // Livraisons prêtes pour leur prochaine tentative
+	head        *ChainHead
+
+	bc       *blockchain.Blockchain
+	updates  chan blockchain.BlockUpdate
+	ctx      context.Context // nil tant que StartWebhooks n'a pas été appelé
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	wake     chan struct{} // Signale aux goroutines de livraison que la file n'est plus vide
+	client   *http.Client  // Toutes destinations
+	external *http.Client  // Adresses publiques uniquement
+}
+
+var webhooks = &webhookDispatcher{
+	hooks:   make(map[string]*Webhook),
+	logs:    make(map[string][]WebhookDelivery),
+	pending: make(map[string]*WebhookDelivery),
+}
+
+// SaveWebhooks sauvegarde les webhooks, les livraisons en échec ou en cours et le dernier bloc annoncé
+func SaveWebhooks() error {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+	return webhooks.saveLocked()
+}
+
+// saveLocked écrit le fichier des webhooks. L'appelant doit détenir webhooks.mu.
+func (d *webhookDispatcher) saveLocked() error {
+	state := webhookState{
+		Webhooks:    make([]*Webhook, 0, len(d.hooks)),
+		DeadLetters: append([]*WebhookDelivery{}, d.deadLetters...),
+		Head:        d.head,
+	}
+	for _, hook := range d.hooks {
+		state.Webhooks = append(state.Webhooks, hook)
+	}
+	slices.SortFunc(state.Webhooks, func(a, b *Webhook) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for _, delivery := range d.pending {
+		state.Pending = append(state.Pending, delivery)
+	}
+	slices.SortFunc(state.Pending, func(a, b *WebhookDelivery) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation des webhooks: %v", err)
+	}
+	return ioutil.WriteFile(settings.DataPath(settings.Files.Webhooks), data, 0600) // Contient les secrets de signature
+}
+
+// LoadWebhooks charge les webhooks depuis un fichier
+func LoadWebhooks() error {
+	if _, err := os.Stat(settings.DataPath(settings.Files.Webhooks)); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(settings.DataPath(settings.Files.Webhooks))
+	if err != nil {
+		return fmt.Errorf("erreur lors de la lecture du fichier des webhooks: %v", err)
+	}
+
+	var state webhookState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("erreur lors de la désérialisation des webhooks: %v", err)
+	}
+
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+	for _, hook := range state.Webhooks {
+		webhooks.hooks[hook.ID] = hook
+	}
+	webhooks.deadLetters = state.DeadLetters
+	for _, delivery := range state.Pending {
+		webhooks.pending[delivery.ID] = delivery
+	}
+	webhooks.head = state.Head
+	return nil
+}
+
+// StartWebhooks abonne les webhooks aux nouveaux blocs. Les livraisons interrompues par l'arrêt
+// précédent sont reprises, puis les blocs ajoutés depuis le dernier bloc annoncé (serveur arrêté,
+// commandes bkc) sont rattrapés ; si ce bloc n'appartient plus à la chaîne, un événement
+// chain.reorg est émis à la place.
+func StartWebhooks(bc *blockchain.Blockchain) {
+	d := webhooks
+	timeout := time.Duration(settings.Webhooks.TimeoutSeconds) * time.Second
+	admins := activeAdmins() // Avant d.mu : mu est toujours pris avant webhooks.mu
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.bc = bc
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.queue = nil
+	d.wake = make(chan struct{}, 1)
+	d.client = newWebhookClient(timeout, nil)
+	d.external = newWebhookClient(timeout, publicAddressOnly)
+	for i := 0; i < webhookConcurrency; i++ {
+		d.wg.Add(1)
+		go d.work(d.ctx)
+	}
+
+	// Livraisons chargées par LoadWebhooks, avec leur identifiant et leurs tentatives
+	if len(d.pending) > 0 {
+		resumed := make([]*WebhookDelivery, 0, len(d.pending))
+		for _, delivery := range d.pending {
+			resumed = append(resumed, delivery)
+		}
+		for _, delivery := range resumed {
+			d.scheduleLocked(delivery)
+		}
+		slog.Info("Livraisons de webhooks reprises", "count", len(resumed))
+	}
+
+	// Abonnement avant la lecture de la chaîne : un bloc ajouté entre-temps n'est pas perdu
+	d.updates = bc.Subscribe()
+
+	_, total := bc.GetBlocks(0, 0)
+	last, _ := bc.GetBlock(total - 1)
+	current := &ChainHead{Index: last.Index, Hash: last.Hash}
+
+	switch previous := d.head; {
+	case previous == nil:
+		// Premier démarrage : seuls les blocs à venir sont annoncés
+
+	case previous.Index >= total || !sameBlock(bc, previous):
+		slog.Warn("Chaîne remplacée depuis le dernier bloc annoncé", "previous", previous.Index, "current", current.Index)
+		reorg := ChainReorg{Previous: *previous, Current: *current}
+		for _, hook := range d.hooks {
+			if hook.subscribed(WebhookChainReorg) {
+				d.enqueueLocked(hook, WebhookChainReorg, reorg)
+			}
+		}
+
+	case previous.Index < current.Index:
+		first := previous.Index + 1
+		if missed := current.Index - previous.Index; missed > webhookCatchUpMax {
+			slog.Warn("Blocs trop anciens non annoncés aux webhooks", "count", missed-webhookCatchUpMax)
+			first = current.Index - webhookCatchUpMax + 1
+		}
+		blocks, _ := bc.GetBlocks(first, current.Index-first+1)
+		for _, block := range blocks {
+			d.announceLocked(block, admins)
+		}
+		slog.Info("Blocs rattrapés pour les webhooks", "count", len(blocks))
+	}
+	d.head = current
+
+	d.wg.Add(1)
+	go d.listen()
+}
+
+// StopWebhooks arrête les livraisons et sauvegarde l'état. Celles qui n'ont pas abouti restent
+// en attente dans le fichier des webhooks et sont reprises par StartWebhooks au redémarrage.
+func StopWebhooks(ctx context.Context) error {
+	d := webhooks
+	d.mu.Lock()
+	if d.cancel == nil {
+		d.mu.Unlock()
+		return nil
+	}
+	d.bc.Unsubscribe(d.updates)
+	d.cancel()
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("Livraisons de webhooks non terminées", "error", ctx.Err())
+	}
+
+	return SaveWebhooks()
+}
+
+// sameBlock indique si le bloc head fait partie de la chaîne
+func sameBlock(bc *blockchain.Blockchain, head *ChainHead) bool {
+	block, ok := bc.GetBlock(head.Index)
+	return ok && block.Hash == head.Hash
+}
+
+// listen annonce les nouveaux blocs jusqu'au désabonnement
+func (d *webhookDispatcher) listen() {
+	defer d.wg.Done()
+	for update := range d.updates {
+		if update.Type != "new" {
+			continue
+		}
+		admins := activeAdmins()
+		d.mu.Lock()
+		if update.Block.Index > d.head.Index { // Sinon déjà annoncé par le rattrapage
+			d.announceLocked(update.Block, admins)
+			d.head = &ChainHead{Index: update.Block.Index, Hash: update.Block.Hash}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// announceLocked crée les livraisons d'un nouveau bloc. Un webhook global ne reçoit les messages
+// des autres utilisateurs que si son propriétaire fait partie des administrateurs actifs admins.
+// L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) announceLocked(block *blockchain.Block, admins map[string]bool) {
+	message, isMessage := blockMessage(block)
+	for _, hook := range d.hooks {
+		if hook.subscribed(WebhookBlockNew) {
+			d.enqueueLocked(hook, WebhookBlockNew, block)
+		}
+		if isMessage && hook.subscribed(WebhookMessageNew) &&
+			(hook.Owner == message.Recipient || (hook.Global && admins[hook.Owner])) {
+			d.enqueueLocked(hook, WebhookMessageNew, message)
+		}
+	}
+}
+
+// activeAdmins retourne les administrateurs ni supprimés ni désactivés
+func activeAdmins() map[string]bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	admins := make(map[string]bool)
+	for username, user := range users {
+		if !user.Disabled && user.Role.Includes(utils.RoleAdmin) {
+			admins[username] = true
+		}
+	}
+	return admins
+}
+
+// requiresAdmin indique si la livraison n'est due qu'au statut global du webhook : un message
+// adressé à un autre utilisateur que le propriétaire
+func requiresAdmin(hook *Webhook, delivery *WebhookDelivery) bool {
+	if !hook.Global || delivery.Event != WebhookMessageNew {
+		return false
+	}
+	var payload struct {
+		Data blockchain.Message `json:"data"`
+	}
+	return json.Unmarshal(delivery.Payload, &payload) != nil || payload.Data.Recipient != hook.Owner
+}
+
+// enqueueLocked crée une livraison et lance sa première tentative. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) enqueueLocked(hook *Webhook, event string, data interface{}) *WebhookDelivery {
+	id, err := generateToken()
+	if err != nil {
+		slog.Error("Création de la livraison impossible", "webhook", hook.ID, "error", err)
+		return nil
+	}
+	delivery := &WebhookDelivery{
+		ID:        id[:16],
+		WebhookID: hook.ID,
+		Event:     event,
+		State:     deliveryPending,
+		CreatedAt: time.Now(),
+	}
+	delivery.Payload, err = json.Marshal(WebhookPayload{
+		ID:        delivery.ID,
+		Event:     event,
+		CreatedAt: delivery.CreatedAt,
+		Data:      data,
+	})
+	if err != nil {
+		slog.Error("Sérialisation de l'événement impossible", "webhook", hook.ID, "event", event, "error", err)
+		return nil
+	}
+
+	d.scheduleLocked(delivery)
+	return delivery
+}
+
+// scheduleLocked met une livraison en file pour sa première tentative. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) scheduleLocked(delivery *WebhookDelivery) {
+	d.pending[delivery.ID] = delivery
+	d.recordLocked(delivery)
+	d.queueLocked(delivery)
+}
+
+// queueLocked ajoute une livraison à la file des goroutines de livraison. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) queueLocked(delivery *WebhookDelivery) {
+	d.queue = append(d.queue, delivery)
+	d.signal()
+}
+
+// signal réveille une goroutine de livraison en attente
+func (d *webhookDispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default: // Signal déjà en attente
+	}
+}
+
+// work est l'une des webhookConcurrency goroutines de livraison : elle effectue les tentatives
+// de la file jusqu'à l'arrêt
+func (d *webhookDispatcher) work(ctx context.Context) {
+	defer d.wg.Done()
+	for ctx.Err() == nil {
+		d.mu.Lock()
+		var delivery *WebhookDelivery
+		if len(d.queue) > 0 {
+			delivery, d.queue = d.queue[0], d.queue[1:]
+			if len(d.queue) > 0 {
+				d.signal() // Une autre goroutine prend la suite
+			}
+		}
+		d.mu.Unlock()
+
+		if delivery == nil {
+			select {
+			case <-d.wake:
+			case <-ctx.Done():
+			}
+			continue
+		}
+		d.attempt(ctx, delivery)
+	}
+}
+
+// retryDelay retourne le délai avant la tentative suivant la n-ième : webhookRetryBase, doublé
+// après chaque échec, au plus webhookRetryMax
+func retryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// attempt effectue une tentative de livraison. En cas d'échec, la livraison est remise en file
+// après retryDelay, ou placée en lettre morte une fois les tentatives épuisées.
+func (d *webhookDispatcher) attempt(ctx context.Context, delivery *WebhookDelivery) {
+	d.mu.Lock()
+	hook, exists := d.hooks[delivery.WebhookID]
+	if !exists { // Webhook supprimé entre-temps
+		delete(d.pending, delivery.ID)
+		d.mu.Unlock()
+		return
+	}
+	hook = &Webhook{ID: hook.ID, Owner: hook.Owner, Global: hook.Global, URL: hook.URL, Secret: hook.Secret}
+	d.mu.Unlock()
+
+	// Le propriétaire d'un webhook global a pu être rétrogradé, désactivé ou supprimé depuis
+	if requiresAdmin(hook, delivery) && !hasRole(hook.Owner, utils.RoleAdmin) {
+		d.mu.Lock()
+		d.forgetLocked(delivery)
+		d.mu.Unlock()
+		slog.Warn("Livraison abandonnée : le propriétaire du webhook global n'est plus administrateur", "webhook", hook.ID, "delivery", delivery.ID, "owner", hook.Owner)
+		return
+	}
+
+	status, err := d.send(hook, delivery)
+	if ctx.Err() != nil {
+		return // Reste en attente : sauvegardée par StopWebhooks
+	}
+
+	d.mu.Lock()
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttempt = &now
+	delivery.StatusCode = status
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	switch {
+	case err == nil:
+		delivery.State = deliveryDelivered
+		delete(d.pending, delivery.ID)
+		d.recordLocked(delivery)
+		d.mu.Unlock()
+		webhookDeliveries.Inc("delivered")
+		slog.Info("Webhook livré", "webhook", hook.ID, "delivery", delivery.ID, "event", delivery.Event, "status", status, "attempts", delivery.Attempts)
+		return
+
+	case delivery.Attempts >= settings.Webhooks.MaxAttempts:
+		d.failLocked(delivery)
+		if err := d.saveLocked(); err != nil {
+			slog.Error("Sauvegarde des webhooks impossible", "error", err)
+		}
+		d.mu.Unlock()
+		webhookDeliveries.Inc("failed")
+		slog.Error("Livraison du webhook abandonnée", "webhook", hook.ID, "delivery", delivery.ID, "event", delivery.Event, "attempts", delivery.Attempts, "error", err)
+		return
+	}
+	d.recordLocked(delivery)
+	d.mu.Unlock()
+
+	delay := retryDelay(delivery.Attempts)
+	webhookDeliveries.Inc("retry")
+	slog.Warn("Échec de livraison du webhook", "webhook", hook.ID, "delivery", delivery.ID, "attempt", delivery.Attempts, "retry_in", delay, "error", err)
+
+	// Après l'arrêt, la livraison reste en attente et sera reprise par StartWebhooks
+	time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if ctx.Err() == nil && d.pending[delivery.ID] == delivery {
+			d.queueLocked(delivery)
+		}
+	})
+}
+
+// forgetLocked retire une livraison qui ne doit plus être envoyée, y compris du journal du
+// webhook. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) forgetLocked(delivery *WebhookDelivery) {
+	delete(d.pending, delivery.ID)
+	d.logs[delivery.WebhookID] = slices.DeleteFunc(d.logs[delivery.WebhookID], func(logged WebhookDelivery) bool {
+		return logged.ID == delivery.ID
+	})
+}
+
+// failLocked place une livraison en lettre morte. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) failLocked(delivery *WebhookDelivery) {
+	delivery.State = deliveryFailed
+	delete(d.pending, delivery.ID)
+	d.recordLocked(delivery)
+
+	d.deadLetters = append(d.deadLetters, delivery)
+	if excess := len(d.deadLetters) - maxDeadLetters; excess > 0 {
+		d.deadLetters = slices.Delete(d.deadLetters, 0, excess)
+	}
+}
+
+// recordLocked met à jour le journal des livraisons du webhook. L'appelant doit détenir d.mu.
+func (d *webhookDispatcher) recordLocked(delivery *WebhookDelivery) {
+	log := d.logs[delivery.WebhookID]
+	for i := range log {
+		if log[i].ID == delivery.ID {
+			log[i] = *delivery
+			return
+		}
+	}
+	log = append(log, *delivery)
+	if len(log) > webhookLogSize {
+		log = slices.Delete(log, 0, len(log)-webhookLogSize)
+	}
+	d.logs[delivery.WebhookID] = log
+}
+
+// send effectue une tentative et retourne le code HTTP reçu. Seules les réponses 2xx sont
+// des succès ; les redirections ne sont pas suivies.
+func (d *webhookDispatcher) send(hook *Webhook, delivery *WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(hook.Secret, time.Now(), delivery.Payload))
+
+	client := d.external
+	if settings.Webhooks.AllowPrivate || hasRole(hook.Owner, utils.RoleAdmin) {
+		client = d.client
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("réponse HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook calcule l'en-tête X-BkC-Signature : HMAC-SHA256, avec le secret du webhook, de
+// l'horodatage unix suivi d'un point et du corps. L'horodatage permet au destinataire de
+// rejeter les requêtes rejouées.
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookClient crée le client HTTP des livraisons. Sans proxy, pour que control s'applique
+// à l'adresse réellement contactée.
+func newWebhookClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddressOnly refuse la connexion aux adresses locales ou privées, après la résolution du
+// nom : un utilisateur ne peut pas viser les services internes du serveur
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// isPublicIP indique si une adresse est routable sur Internet
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// removeUserWebhooks supprime les webhooks d'un compte supprimé
+func removeUserWebhooks(username string) {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+
+	removed := false
+	for id, hook := range webhooks.hooks {
+		if hook.Owner == username {
+			delete(webhooks.hooks, id)
+			delete(webhooks.logs, id)
+			removed = true
+		}
+	}
+	if removed {
+		webhooks.deadLetters = slices.DeleteFunc(webhooks.deadLetters, func(delivery *WebhookDelivery) bool {
+			_, exists := webhooks.hooks[delivery.WebhookID]
+			return !exists
+		})
+		if err := webhooks.saveLocked(); err != nil {
+			slog.Error("Sauvegarde des webhooks impossible", "error", err)
+		}
+	}
+}
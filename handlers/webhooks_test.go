@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/config"
+	"BkC/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSettings installe une configuration par défaut dont les données sont dans un répertoire temporaire
+func testSettings(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	settings = cfg
+	return cfg
+}
+
+// startTestWebhooks démarre un répartiteur de webhooks neuf sur une chaîne vide
+func startTestWebhooks(t *testing.T, maxAttempts int, allowPrivate bool) *blockchain.Blockchain {
+	t.Helper()
+	cfg := testSettings(t)
+	cfg.Webhooks.MaxAttempts = maxAttempts
+	cfg.Webhooks.AllowPrivate = allowPrivate
+	cfg.Webhooks.TimeoutSeconds = 5
+
+	base := webhookRetryBase
+	webhookRetryBase = 10 * time.Millisecond
+	webhooks = &webhookDispatcher{
+		hooks:   make(map[string]*Webhook),
+		logs:    make(map[string][]WebhookDelivery),
+		pending: make(map[string]*WebhookDelivery),
+	}
+
+	bc := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+	StartWebhooks(bc)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		StopWebhooks(ctx)
+		webhookRetryBase = base
+	})
+	return bc
+}
+
+// addTestWebhook enregistre un webhook vers url
+func addTestWebhook(url string) *Webhook {
+	hook := &Webhook{
+		ID:        "hook-test",
+		Owner:     "alice",
+		URL:       url,
+		Events:    []string{WebhookBlockNew},
+		Secret:    "whsec_test",
+		CreatedAt: time.Now(),
+	}
+	webhooks.mu.Lock()
+	webhooks.hooks[hook.ID] = hook
+	webhooks.mu.Unlock()
+	return hook
+}
+
+// waitDelivery attend que la livraison atteigne l'état voulu
+func waitDelivery(t *testing.T, hookID, deliveryID, state string) WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, delivery := range recentDeliveries(hookID) {
+			if delivery.ID == deliveryID && delivery.State == state {
+				return delivery
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("livraison %s : état %s non atteint (%+v)", deliveryID, state, recentDeliveries(hookID))
+	return WebhookDelivery{}
+}
+
+// receiver est un destinataire de webhooks qui répond avec les codes donnés, puis 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	startTestWebhooks(t, 3, true)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	hook := addTestWebhook(server.URL)
+
+	delivery := pingWebhook(hook)
+	waitDelivery(t, hook.ID, delivery.ID, deliveryDelivered)
+
+	rc.mu.Lock()
+	req, body := rc.requests[0], rc.bodies[0]
+	rc.mu.Unlock()
+
+	if got := req.Header.Get(WebhookEventHeader); got != WebhookPing {
+		t.Errorf("%s = %q", WebhookEventHeader, got)
+	}
+	if got := req.Header.Get(WebhookDeliveryHeader); got != delivery.ID {
+		t.Errorf("%s = %q, attendu %q", WebhookDeliveryHeader, got, delivery.ID)
+	}
+
+	// Recalcul indépendant : HMAC-SHA256(secret, "<t>." + corps)
+	var timestamp, signature string
+	for _, part := range strings.Split(req.Header.Get(WebhookSignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature %q, attendu %q", signature, want)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	tests := []struct {
+		secret, body string
+	}{
+		{"whsec_a", `{"id":"1"}`},
+		{"whsec_b", `{"id":"1"}`},
+		{"whsec_a", ``},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		header := SignWebhook(tt.secret, at, []byte(tt.body))
+		if !strings.HasPrefix(header, "t=1700000000,v1=") || len(header) != len("t=1700000000,v1=")+64 {
+			t.Errorf("SignWebhook(%q, %q) = %q", tt.secret, tt.body, header)
+		}
+		if seen[header] {
+			t.Errorf("signature identique pour des entrées différentes : %q", header)
+		}
+		seen[header] = true
+	}
+}
+
+func TestWebhookRetryThenDeadLetterAndRedeliver(t *testing.T) {
+	startTestWebhooks(t, 3, true)
+	rc := &receiver{statuses: []int{500, 503}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	hook := addTestWebhook(server.URL)
+
+	// Deux échecs puis un succès à la troisième tentative
+	delivery := pingWebhook(hook)
+	delivered := waitDelivery(t, hook.ID, delivery.ID, deliveryDelivered)
+	if delivered.Attempts != 3 || delivered.StatusCode != http.StatusOK {
+		t.Errorf("livraison après reprise : %d tentatives, statut %d", delivered.Attempts, delivered.StatusCode)
+	}
+
+	// Tentatives épuisées : lettre morte
+	rc.mu.Lock()
+	rc.statuses = []int{500, 500, 500}
+	rc.mu.Unlock()
+	before := rc.count()
+	failed := pingWebhook(hook)
+	dead := waitDelivery(t, hook.ID, failed.ID, deliveryFailed)
+	if dead.Attempts != 3 || dead.StatusCode != http.StatusInternalServerError {
+		t.Errorf("lettre morte : %d tentatives, statut %d", dead.Attempts, dead.StatusCode)
+	}
+	if sent := rc.count() - before; sent != 3 {
+		t.Errorf("%d requêtes envoyées, attendu 3", sent)
+	}
+	letters := deadLetters(hook.ID)
+	if len(letters) != 1 || letters[0].ID != failed.ID {
+		t.Fatalf("lettres mortes : %+v", letters)
+	}
+
+	// Relance : même identifiant, même corps
+	retried := redeliver(hook.ID)
+	if len(retried) != 1 || retried[0].ID != failed.ID || retried[0].State != deliveryPending {
+		t.Fatalf("relance : %+v", retried)
+	}
+	waitDelivery(t, hook.ID, failed.ID, deliveryDelivered)
+	if letters := deadLetters(hook.ID); len(letters) != 0 {
+		t.Errorf("lettres mortes après relance : %+v", letters)
+	}
+
+	rc.mu.Lock()
+	last := rc.requests[len(rc.requests)-1]
+	first, final := rc.bodies[before], rc.bodies[len(rc.bodies)-1]
+	rc.mu.Unlock()
+	if last.Header.Get(WebhookDeliveryHeader) != failed.ID || string(first) != string(final) {
+		t.Errorf("la relance doit reprendre l'identifiant et le corps d'origine")
+	}
+}
+
+func TestWebhookRetryBackOff(t *testing.T) {
+	startTestWebhooks(t, 3, true)
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	hook := addTestWebhook(server.URL)
+
+	delivery := pingWebhook(hook)
+	waitDelivery(t, hook.ID, delivery.ID, deliveryFailed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 3 {
+		t.Fatalf("%d tentatives, attendu 3", len(times))
+	}
+	// Délais de webhookRetryBase puis du double
+	if gap := times[1].Sub(times[0]); gap < webhookRetryBase {
+		t.Errorf("premier délai %v, attendu au moins %v", gap, webhookRetryBase)
+	}
+	if gap := times[2].Sub(times[1]); gap < 2*webhookRetryBase {
+		t.Errorf("second délai %v, attendu au moins %v", gap, 2*webhookRetryBase)
+	}
+}
+
+func TestWebhookResumedAfterRestart(t *testing.T) {
+	startTestWebhooks(t, 3, true)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	hook := addTestWebhook(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	StopWebhooks(ctx)
+
+	// Livraison restée en attente à l'arrêt, rechargée depuis le fichier des webhooks
+	pending := &WebhookDelivery{ID: "interrompue", WebhookID: hook.ID, Event: WebhookPing, State: deliveryPending,
+		CreatedAt: time.Now(), Payload: []byte(`{"id":"interrompue"}`)}
+	webhooks.mu.Lock()
+	webhooks.pending[pending.ID] = pending
+	if err := webhooks.saveLocked(); err != nil {
+		t.Fatal(err)
+	}
+	webhooks.pending = make(map[string]*WebhookDelivery)
+	webhooks.hooks = make(map[string]*Webhook)
+	webhooks.mu.Unlock()
+
+	if err := LoadWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+	StartWebhooks(webhooks.bc)
+
+	waitDelivery(t, hook.ID, pending.ID, deliveryDelivered)
+}
+
+func TestPublicAddressOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"127.0.0.1:80", false},
+		{"[::1]:443", false},
+		{"10.1.2.3:443", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.10:8080", false},
+		{"169.254.169.254:80", false}, // Métadonnées des hébergeurs
+		{"0.0.0.0:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"224.0.0.1:80", false},
+		{"example.org:80", false}, // Nom non résolu
+		{"93.184.216.34:443", true},
+		{"[2606:4700::1111]:443", true},
+	}
+	for _, tt := range tests {
+		err := publicAddressOnly("tcp", tt.address, nil)
+		if (err == nil) != tt.allowed {
+			t.Errorf("publicAddressOnly(%q) = %v, autorisée attendu %v", tt.address, err, tt.allowed)
+		}
+	}
+}
+
+func TestWebhookPrivateAddressRefused(t *testing.T) {
+	startTestWebhooks(t, 1, false)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	hook := addTestWebhook(server.URL) // 127.0.0.1, propriétaire non administrateur
+
+	delivery := pingWebhook(hook)
+	failed := waitDelivery(t, hook.ID, delivery.ID, deliveryFailed)
+	if !strings.Contains(failed.Error, errPrivateAddress.Error()) {
+		t.Errorf("erreur %q, attendu %q", failed.Error, errPrivateAddress)
+	}
+	if rc.count() != 0 {
+		t.Errorf("%d requêtes reçues par une adresse privée", rc.count())
+	}
+}
+
+// waitNoPending attend que toutes les livraisons soient terminées ou abandonnées
+func waitNoPending(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		webhooks.mu.Lock()
+		pending := len(webhooks.pending)
+		webhooks.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("livraisons toujours en attente")
+}
+
+func TestGlobalWebhookRequiresActiveAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		owner     *utils.User
+		recipient string
+		announced bool // Propriétaire encore administrateur lors de l'annonce du bloc
+		delivered bool
+	}{
+		{"administrateur", &utils.User{Username: "root", Role: utils.RoleAdmin}, "bob", false, true},
+		{"rétrogradé", &utils.User{Username: "root", Role: utils.RoleMember}, "bob", false, false},
+		{"désactivé", &utils.User{Username: "root", Role: utils.RoleAdmin, Disabled: true}, "bob", false, false},
+		{"rétrogradé après l'annonce", &utils.User{Username: "root", Role: utils.RoleMember}, "bob", true, false},
+		{"message adressé au propriétaire", &utils.User{Username: "root", Role: utils.RoleMember}, "root", false, true},
+	}
+	for _, tt := range tests {
+		startTestWebhooks(t, 1, true)
+		testUsers(t, tt.owner, &utils.User{Username: "bob", Role: utils.RoleMember})
+		rc := &receiver{}
+		server := httptest.NewServer(rc)
+		hook := addTestWebhook(server.URL)
+		hook.Owner, hook.Global, hook.Events = "root", true, []string{WebhookMessageNew}
+
+		message := blockchain.Message{ID: "m1", Sender: "alice", Recipient: tt.recipient, Content: "secret"}
+		data, _ := json.Marshal(message)
+		block := &blockchain.Block{Index: 1, Data: string(data)}
+
+		admins := activeAdmins()
+		if tt.announced {
+			admins = map[string]bool{"root": true}
+		}
+		webhooks.mu.Lock()
+		webhooks.announceLocked(block, admins)
+		webhooks.mu.Unlock()
+		waitNoPending(t)
+
+		if got := rc.count() == 1; got != tt.delivered {
+			t.Errorf("%s : %d livraisons reçues", tt.name, rc.count())
+		}
+		if !tt.delivered && len(recentDeliveries(hook.ID)) != 0 {
+			t.Errorf("%s : livraison conservée dans le journal : %+v", tt.name, recentDeliveries(hook.ID))
+		}
+		server.Close()
+	}
+}
+
+func TestWebhookDeliveriesUseFixedWorkers(t *testing.T) {
+	startTestWebhooks(t, 1, true)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+	hook := addTestWebhook(server.URL)
+
+	before := runtime.NumGoroutine()
+	const deliveries = 10 * webhookConcurrency
+	for i := 0; i < deliveries; i++ {
+		pingWebhook(hook)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		busy := inFlight
+		mu.Unlock()
+		if busy == webhookConcurrency || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Une goroutine par livraison en attente en créerait au moins autant que de livraisons
+	if extra := runtime.NumGoroutine() - before; extra >= deliveries {
+		t.Errorf("%d goroutines de plus pour %d livraisons", extra, deliveries)
+	}
+	close(release)
+	waitNoPending(t)
+	if maxInFlight != webhookConcurrency {
+		t.Errorf("%d requêtes simultanées, attendu %d", maxInFlight, webhookConcurrency)
+	}
+}
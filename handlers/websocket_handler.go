@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"BkC/blockchain"
+	"BkC/i18n"
+	"BkC/utils"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -14,11 +16,16 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Autoriser toutes les origines pour le dev
-	},
+	CheckOrigin:     checkWebSocketOrigin, // Refuser les origines non configurées
 }
 
+// maxWebSocketRPCCalls borne les appels JSON-RPC simultanés d'une connexion WebSocket
+const maxWebSocketRPCCalls = 4
+
+// rpcReplyTimeout borne l'attente d'une place pour une réponse JSON-RPC : au-delà, le client ne
+// lit plus ses réponses et la connexion est fermée
+const rpcReplyTimeout = 10 * time.Second
+
 // ClientMessage représente un message du client au serveur
 type ClientMessage struct {
 	Type string `json:"type"`
@@ -38,8 +45,13 @@ type WebSocketClient struct {
 	conn          *websocket.Conn
 	bc            *blockchain.Blockchain
 	username      string
+	auth          *requestAuth // Session ou jeton d'API utilisé pour la connexion
+	lang          string       // Langue des messages envoyés au client
 	updates       chan blockchain.BlockUpdate
 	send          chan ServerMessage
+	rpcReplies    chan []byte   // Réponses JSON-RPC, envoyées telles quelles
+	rpcSlots      chan struct{} // Appels JSON-RPC en cours ; au-delà de la limite, les appels sont refusés
+	done          chan struct{} // Fermé à la fin de la lecture, quand la connexion est fermée
 	lastMessageID string
 	mutex         sync.Mutex
 }
@@ -47,53 +59,69 @@ type WebSocketClient struct {
 // WebSocketHandler gère les connexions WebSocket
 func WebSocketHandler(bc *blockchain.Blockchain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Vérifier si l'utilisateur est connecté
-		username, ok := getLoggedInUser(r)
+		// Vérifier si l'utilisateur est connecté (session ou jeton d'API)
+		auth, ok := authenticateRequest(r)
 		if !ok {
-			http.Error(w, "Non autorisé", http.StatusUnauthorized)
+			http.Error(w, tr(r, "error.unauthorized"), http.StatusUnauthorized)
 			return
 		}
 
 		// Mettre à niveau la connexion HTTP vers WebSocket
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Printf("Erreur lors de la mise à niveau de la connexion: %v", err)
+			slog.Warn("Mise à niveau WebSocket impossible", "error", err)
 			return
 		}
 
 		// Créer un client WebSocket
 		client := &WebSocketClient{
-			conn:     conn,
-			bc:       bc,
-			username: username,
-			updates:  bc.Subscribe(), // S'abonner aux mises à jour de la blockchain
-			send:     make(chan ServerMessage, 256),
+			conn:       conn,
+			bc:         bc,
+			username:   auth.Username,
+			auth:       auth,
+			lang:       localeOf(r),
+			updates:    bc.Subscribe(), // S'abonner aux mises à jour de la blockchain
+			send:       make(chan ServerMessage, 256),
+			rpcReplies: make(chan []byte, 16),
+			rpcSlots:   make(chan struct{}, maxWebSocketRPCCalls),
+			done:       make(chan struct{}),
 		}
 
-		// Démarrer les goroutines pour gérer la lecture et l'écriture
-		go client.readPump()
-		go client.writePump()
-		go client.listenForUpdates()
-
-		// Envoyer un message initial
+		// Envoyer un message initial, puis le statut de l'utilisateur et de ses contacts
 		client.send <- ServerMessage{
 			Type:    "connected",
-			Data:    "Connecté au système de blockchain en temps réel",
+			Data:    i18n.T(client.lang, "ws.connected"),
 			Time:    time.Now(),
 			Success: true,
 		}
+		client.send <- ServerMessage{
+			Type:    "presence_snapshot",
+			Data:    append([]PresenceEvent{presence.Status(client.username)}, contactsPresence(client.username)...),
+			Time:    time.Now(),
+			Success: true,
+		}
+
+		// Annoncer la présence de l'utilisateur à ses contacts
+		presence.Connect(client)
+
+		// Démarrer les goroutines pour gérer la lecture et l'écriture
+		go client.readPump()
+		go client.writePump()
+		go client.listenForUpdates()
 	}
 }
 
 // readPump lit les messages WebSocket du client
 func (c *WebSocketClient) readPump() {
 	defer func() {
+		presence.Disconnect(c)
 		c.bc.Unsubscribe(c.updates)
 		c.conn.Close()
+		close(c.done)
 	}()
 
 	// Configurer les paramètres de lecture
-	c.conn.SetReadLimit(512) // Limite à 512 octets
+	c.conn.SetReadLimit(maxMessageBodyBytes) // Assez pour un message de taille maximale encodé deux fois en JSON
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -104,34 +132,95 @@ func (c *WebSocketClient) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("Erreur de lecture WebSocket: %v", err)
+				slog.Warn("Erreur de lecture WebSocket", "user", c.username, "error", err)
 			}
 			break
 		}
 
+		// Requête JSON-RPC : traitée à part, une méthode comme mining_submit pouvant être longue.
+		// Sans place libre, elle est refusée aussitôt pour ne pas suspendre la lecture (et les pongs).
+		if isRPCMessage(message) {
+			select {
+			case c.rpcSlots <- struct{}{}:
+				go c.serveRPC(message)
+			default:
+				rpc := &rpcContext{bc: c.bc, auth: c.auth, lang: c.lang}
+				busy := rpc.error(rpcServerBusy, errTooManyRequests, i18n.T(c.lang, "rpc.server_busy", maxWebSocketRPCCalls))
+				if refusal := rpc.refuse(message, busy); refusal != nil {
+					c.replyRPC(refusal, 0)
+				}
+			}
+			continue
+		}
+
 		// Décoder le message
 		var clientMsg ClientMessage
 		if err := json.Unmarshal(message, &clientMsg); err != nil {
-			log.Printf("Erreur de décodage JSON: %v", err)
+			slog.Debug("Message WebSocket illisible", "user", c.username, "error", err)
 			continue
 		}
 
+		// Toute action du client compte comme une activité (sauf pour les jetons d'API)
+		if c.auth.Token == nil {
+			presence.Touch(c.username)
+		}
+
 		// Traiter le message en fonction de son type
 		switch clientMsg.Type {
+		case "activity":
+			// Signal d'activité envoyé par la page (clavier, souris), déjà pris en compte ci-dessus
+
+		case "set_presence":
+			// Choisir son statut : "online" (automatique), "away" ou "busy"
+			status, valid := parseManualStatus(clientMsg.Data)
+			if !valid {
+				c.trySend(ServerMessage{
+					Type:    "error",
+					Data:    i18n.T(c.lang, "validation.status.invalid"),
+					Time:    time.Now(),
+					Success: false,
+				})
+				continue
+			}
+			presence.SetManual(c.username, status)
+
 		case "send_message":
+			// Les comptes en lecture seule et les jetons sans la portée d'envoi ne peuvent pas envoyer de messages
+			if !hasRole(c.username, utils.RoleMember) || !c.auth.Allows(utils.ScopeSendMessages) {
+				c.send <- ServerMessage{
+					Type:    "error",
+					Data:    i18n.T(c.lang, "ws.send_forbidden"),
+					Time:    time.Now(),
+					Success: false,
+				}
+				continue
+			}
+
 			// Traiter l'envoi d'un message
 			var messageData struct {
 				Recipient string `json:"recipient"`
 				Content   string `json:"content"`
 			}
 			if err := json.Unmarshal([]byte(clientMsg.Data), &messageData); err != nil {
-				log.Printf("Erreur lors du décodage des données du message: %v", err)
+				slog.Debug("Données de message illisibles", "user", c.username, "error", err)
+				continue
+			}
+
+			// Valider le destinataire et le contenu
+			recipient, errs := validateNewMessage(messageData.Recipient, messageData.Content)
+			if len(errs) > 0 {
+				c.send <- ServerMessage{
+					Type:    "validation_error",
+					Data:    translateErrors(c.lang, errs),
+					Time:    time.Now(),
+					Success: false,
+				}
 				continue
 			}
 
 			// Créer et ajouter le message à la blockchain de manière asynchrone
-			msg := blockchain.CreateMessage(c.username, messageData.Recipient, messageData.Content)
-			c.bc.AddMessageBlockAsync(msg, 4)
+			msg := blockchain.CreateMessage(c.username, recipient, messageData.Content)
+			c.bc.AddMessageBlockAsync(msg, settings.Difficulty.Messages)
 
 			// Sauvegarder l'ID du dernier message envoyé pour le tracking
 			c.mutex.Lock()
@@ -149,6 +238,51 @@ func (c *WebSocketClient) readPump() {
 	}
 }
 
+// serveRPC traite une requête JSON-RPC reçue par le WebSocket, avec les droits de la connexion
+func (c *WebSocketClient) serveRPC(message []byte) {
+	defer func() { <-c.rpcSlots }()
+
+	rpc := &rpcContext{bc: c.bc, auth: c.auth, lang: c.lang}
+	reply := rpc.serve(message)
+	if reply != nil {
+		c.replyRPC(reply, rpcReplyTimeout)
+	}
+}
+
+// replyRPC met une réponse JSON-RPC en file d'envoi, en attendant au plus wait qu'une place se
+// libère ; si le client ne lit plus ses réponses, la connexion est fermée plutôt que la réponse perdue
+func (c *WebSocketClient) replyRPC(reply []byte, wait time.Duration) {
+	select {
+	case c.rpcReplies <- reply:
+		return
+	case <-c.done:
+		return // Connexion déjà fermée
+	default:
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case c.rpcReplies <- reply:
+			return
+		case <-c.done:
+			return
+		case <-timer.C:
+		}
+	}
+	slog.Warn("Réponses JSON-RPC non lues, connexion WebSocket fermée", "user", c.username)
+	c.conn.Close()
+}
+
+// trySend envoie un message sans bloquer ; il est abandonné si le client ne suit pas
+func (c *WebSocketClient) trySend(message ServerMessage) {
+	select {
+	case c.send <- message:
+	default:
+	}
+}
+
 // writePump envoie des messages WebSocket au client
 func (c *WebSocketClient) writePump() {
 	ticker := time.NewTicker(30 * time.Second) // Ping toutes les 30 secondes
@@ -170,7 +304,7 @@ func (c *WebSocketClient) writePump() {
 			// Encoder et envoyer le message
 			data, err := json.Marshal(message)
 			if err != nil {
-				log.Printf("Erreur d'encodage JSON: %v", err)
+				slog.Error("Encodage JSON impossible", "error", err)
 				return
 			}
 
@@ -183,6 +317,11 @@ func (c *WebSocketClient) writePump() {
 			if err := w.Close(); err != nil {
 				return
 			}
+		case reply := <-c.rpcReplies:
+			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.conn.WriteMessage(websocket.TextMessage, reply); err != nil {
+				return
+			}
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
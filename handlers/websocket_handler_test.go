@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"BkC/blockchain"
+	"BkC/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTestWebSocket ouvre une connexion WebSocket authentifiée au nom de username
+func dialTestWebSocket(t *testing.T, bc *blockchain.Blockchain, username string) *websocket.Conn {
+	t.Helper()
+	handler := WebSocketHandler(bc)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), authContextKey{}, &requestAuth{Username: username})
+		handler(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readRPCReplies lit les messages de la connexion jusqu'à obtenir n réponses JSON-RPC,
+// celles d'un lot comptant chacune
+func readRPCReplies(t *testing.T, conn *websocket.Conn, n int) []rpcReply {
+	t.Helper()
+	var replies []rpcReply
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(replies) < n {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("%d réponses reçues sur %d : %v", len(replies), n, err)
+		}
+		if !strings.Contains(string(data), `"jsonrpc"`) {
+			continue // Message de l'application (connexion, présence)
+		}
+		batch := []rpcReply{{}}
+		if data[0] == '[' {
+			err = json.Unmarshal(data, &batch)
+		} else {
+			err = json.Unmarshal(data, &batch[0])
+		}
+		if err != nil {
+			t.Fatalf("réponse illisible %s : %v", data, err)
+		}
+		replies = append(replies, batch...)
+	}
+	return replies
+}
+
+func TestWebSocketRPCOverLimitRefused(t *testing.T) {
+	cfg := testSettings(t)
+	testUsers(t, &utils.User{Username: "alice", Role: utils.RoleMember})
+	chain := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), 1)
+
+	// Méthode qui reste en cours jusqu'à la fin du test
+	release := make(chan struct{})
+	started := make(chan struct{}, maxWebSocketRPCCalls)
+	rpcMethods["test_block"] = rpcMethod{
+		Role: utils.RoleReadOnly, Scope: utils.ScopeReadChain,
+		Call: func(c *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+			started <- struct{}{}
+			<-release
+			return "ok", nil
+		},
+	}
+	t.Cleanup(func() { delete(rpcMethods, "test_block") })
+
+	conn := dialTestWebSocket(t, chain, "alice")
+	for i := 0; i < maxWebSocketRPCCalls; i++ {
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"test_block","id":%d}`, i)))
+		<-started
+	}
+
+	tests := []struct {
+		name string
+		body string
+		ids  []string // Identifiants des refus attendus, dans l'ordre
+	}{
+		{"appel", `{"jsonrpc":"2.0","method":"chain_getHeight","id":"extra"}`, []string{`"extra"`}},
+		{"lot", `[{"jsonrpc":"2.0","method":"chain_getHeight","id":7},{"jsonrpc":"2.0","method":"chain_getHeight"},{"jsonrpc":"2.0","method":"chain_getHeight","id":8}]`, []string{"7", "8"}},
+		{"lot illisible", `[{"jsonrpc":"2.0","method":`, []string{"null"}},
+	}
+	for _, tt := range tests {
+		conn.WriteMessage(websocket.TextMessage, []byte(tt.body))
+		for i, reply := range readRPCReplies(t, conn, len(tt.ids)) {
+			if reply.Error == nil || reply.Error.Code != rpcServerBusy || string(reply.ID) != tt.ids[i] {
+				t.Errorf("%s : réponse %d %+v, attendu le refus %s", tt.name, i, reply, tt.ids[i])
+			}
+		}
+	}
+
+	// Les appels en cours aboutissent une fois libérés, et la lecture n'a pas été suspendue
+	close(release)
+	for _, reply := range readRPCReplies(t, conn, maxWebSocketRPCCalls) {
+		if reply.Error != nil || string(reply.Result) != `"ok"` {
+			t.Errorf("appel %s : %+v", reply.ID, reply)
+		}
+	}
+}
+
+func TestWebSocketUnreadRPCRepliesCloseConnection(t *testing.T) {
+	client := &WebSocketClient{rpcReplies: make(chan []byte, 1), done: make(chan struct{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		client.conn = conn
+		client.replyRPC([]byte("première"), 0)
+		client.replyRPC([]byte("seconde"), 10*time.Millisecond) // File pleine : la connexion est fermée
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var netErr net.Error
+	if _, _, err := conn.ReadMessage(); err == nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+		t.Errorf("connexion non fermée : %v", err)
+	}
+	if len(client.rpcReplies) != 1 {
+		t.Errorf("%d réponses en file, attendu 1", len(client.rpcReplies))
+	}
+}
@@ -0,0 +1,125 @@
+// Package i18n fournit les catalogues de messages de l'interface et de l'API, ainsi que
+// le choix de la langue d'après l'en-tête Accept-Language.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default est la langue utilisée lorsqu'aucune langue prise en charge n'est demandée
+const Default = "fr"
+
+// Supported liste les langues disponibles, dans l'ordre de préférence par défaut
+var Supported = []string{"fr", "en"}
+
+//go:embed locales/*.json
+var catalogueFiles embed.FS
+
+// catalogues contient les messages par langue puis par clé
+var catalogues = loadCatalogues()
+
+// loadCatalogues lit les catalogues intégrés et signale les clés absentes d'une langue
+func loadCatalogues() map[string]map[string]string {
+	loaded := make(map[string]map[string]string, len(Supported))
+	for _, lang := range Supported {
+		data, err := catalogueFiles.ReadFile(path.Join("locales", lang+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("catalogue %s absent: %v", lang, err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("catalogue %s invalide: %v", lang, err))
+		}
+		loaded[lang] = messages
+	}
+	return loaded
+}
+
+// Check retourne les clés présentes dans le catalogue par défaut mais absentes d'une autre langue
+// (et inversement), par langue
+func Check() map[string][]string {
+	missing := make(map[string][]string)
+	for _, lang := range Supported {
+		for key := range catalogues[Default] {
+			if _, ok := catalogues[lang][key]; !ok {
+				missing[lang] = append(missing[lang], key)
+			}
+		}
+		for key := range catalogues[lang] {
+			if _, ok := catalogues[Default][key]; !ok {
+				missing[Default] = append(missing[Default], key)
+			}
+		}
+	}
+	for lang := range missing {
+		sort.Strings(missing[lang])
+	}
+	return missing
+}
+
+// IsSupported indique si la langue est disponible
+func IsSupported(lang string) bool {
+	_, ok := catalogues[lang]
+	return ok
+}
+
+// T retourne le message key dans la langue demandée, formaté avec args (verbes de fmt).
+// Un message absent est cherché dans la langue par défaut, puis la clé elle-même est renvoyée.
+func T(lang, key string, args ...interface{}) string {
+	message, ok := catalogues[lang][key]
+	if !ok {
+		if message, ok = catalogues[Default][key]; !ok {
+			slog.Warn("Message non traduit", "lang", lang, "key", key)
+			message = key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Has indique si une clé existe dans le catalogue par défaut
+func Has(key string) bool {
+	_, ok := catalogues[Default][key]
+	return ok
+}
+
+// Negotiate choisit la langue prise en charge préférée d'après un en-tête Accept-Language
+// ("en-GB,en;q=0.9,fr;q=0.8"). Les variantes régionales correspondent à leur langue de base.
+func Negotiate(acceptLanguage string) (string, bool) {
+	type choice struct {
+		lang    string
+		quality float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			value, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = value
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if quality > 0 && IsSupported(base) {
+			choices = append(choices, choice{base, quality})
+		}
+	}
+	if len(choices) == 0 {
+		return Default, false
+	}
+	sort.SliceStable(choices, func(i, j int) bool {
+		return choices[i].quality > choices[j].quality
+	})
+	return choices[0].lang, true
+}
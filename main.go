@@ -2,17 +2,30 @@ package main
 
 import (
 	"BkC/blockchain"
+	"BkC/config"
 	"BkC/handlers"
+	"BkC/i18n"
+	"BkC/metrics"
 	"BkC/utils"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout borne la durée de l'arrêt propre (requêtes en cours et minages en attente)
+const shutdownTimeout = 20 * time.Second
+
 // openBrowser ouvre le navigateur par défaut avec l'URL spécifiée.
 func openBrowser(url string) {
 	var cmd string
@@ -27,7 +40,7 @@ func openBrowser(url string) {
 	case "linux":
 		cmd = "xdg-open"
 	default:
-		log.Println("⚠️ Système non supporté pour l'ouverture automatique du navigateur")
+		slog.Warn("Système non supporté pour l'ouverture automatique du navigateur", "os", runtime.GOOS)
 		return
 	}
 
@@ -35,52 +48,166 @@ func openBrowser(url string) {
 	exec.Command(cmd, args...).Start()
 }
 
+// fatal journalise une erreur puis arrête le programme
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	utils.CloseLog()
+	os.Exit(1)
+}
+
 func main() {
-	var err error
-	utils.LogFile, err = os.OpenFile("server.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	os.Exit(run(os.Args[1:]))
+}
+
+// serve démarre le serveur web jusqu'à la réception de SIGINT ou SIGTERM
+func serve(args []string) int {
+	// Configuration : valeurs par défaut, bkc.json, variables BKC_* puis options de la ligne de commande
+	cfg, _, err := config.LoadCommand("bkc serve", args, nil)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		log.Fatalf("❌ Configuration invalide :\n%v", err)
+	}
+
+	// Un seul processus à la fois sur le répertoire de données (serveur ou commande qui le modifie)
+	release, err := lockDataDir(cfg)
 	if err != nil {
-		log.Fatalf("Erreur lors de l'ouverture du fichier log : %v", err)
+		log.Fatalf("❌ %v", err)
+	}
+	defer release()
+
+	// Journal structuré : sortie d'erreur et fichier JSON archivé au-delà de sa taille maximale
+	err = utils.SetupLogging(utils.LogOptions{
+		Level:      cfg.Log.SlogLevel(),
+		JSON:       cfg.Log.Format == "json",
+		File:       cfg.DataPath(cfg.Files.Log),
+		MaxSize:    int64(cfg.Log.MaxSizeMB) << 20,
+		MaxBackups: cfg.Log.MaxBackups,
+	})
+	if err != nil {
+		log.Fatalf("❌ Ouverture du fichier journal impossible : %v", err)
 	}
-	defer utils.LogFile.Close()
 
 	// Initialisation de la blockchain.
-	bc := blockchain.NewBlockchain()
+	bc := blockchain.NewBlockchain(cfg.DataPath(cfg.Files.Blockchain), cfg.Difficulty.Genesis)
 
 	// Initialiser la référence globale
-	handlers.InitGlobalBC(bc)
+	handlers.InitGlobalBC(bc, cfg)
 
-	// Route par défaut : affiche la page d'accueil (acceuil.html)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		tmpl, err := template.ParseFiles("templates/acceuil.html")
+	// Suivi de présence (statuts en ligne / absent / occupé diffusés par WebSocket)
+	handlers.StartPresence()
+
+	// Webhooks : livraison des nouveaux blocs, des messages et des remplacements de la chaîne
+	handlers.StartWebhooks(bc)
+
+	// Origines autorisées pour les requêtes cross-origin et WebSocket
+	handlers.SetAllowedOrigins(cfg.Origins())
+
+	// Proxies de confiance et en-tête de transfert qu'ils écrivent (seul en-tête cru)
+	if err := utils.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Configuration des proxies invalide", err)
+	}
+	if err := utils.SetForwardedHeader(cfg.ProxyHeader); err != nil {
+		fatal("Configuration des proxies invalide", err)
+	}
+
+	// Géolocalisation locale : bases MaxMind DB (.mmdb) ou CSV de plages CIDR
+	// (sans base, les pays restent inconnus)
+	if err := handlers.LoadGeoIP(cfg.GeoIPDatabases); err != nil {
+		fatal("Configuration de la géolocalisation invalide", err)
+	}
+
+	// SIGHUP recharge les bases de géolocalisation après leur mise à jour
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := handlers.ReloadGeoIP(); err != nil {
+				slog.Error("Rechargement de la géolocalisation impossible", "error", err)
+			}
+		}
+	}()
+
+	// Envoi des liens de réinitialisation : SMTP si configuré, sinon dans le journal du serveur
+	if cfg.SMTP.Addr != "" {
+		notifier, err := utils.NewSMTPNotifier(cfg.SMTP.Addr, cfg.SMTP.From, cfg.SMTP.Username, cfg.SMTP.Password)
 		if err != nil {
-			http.Error(w, "Erreur lors du chargement de la page d'accueil", http.StatusInternalServerError)
-			return
+			fatal("Configuration SMTP invalide", err)
 		}
-		tmpl.Execute(w, nil)
-	})
+		handlers.SetNotifier(notifier)
+	}
+
+	// Modèles et fichiers statiques intégrés au binaire (relus depuis le disque en mode développement)
+	if err := handlers.InitAssets(templatesFS(cfg), staticFS(cfg), cfg.Dev); err != nil {
+		fatal("Chargement des modèles impossible", err)
+	}
+
+	// Catalogues de messages : une clé absente d'une langue est affichée dans la langue par défaut
+	for lang, keys := range i18n.Check() {
+		slog.Warn("Messages non traduits", "lang", lang, "keys", keys)
+	}
+
+	// Route par défaut : affiche la page d'accueil (acceuil.html)
+	http.HandleFunc("/", handlers.WelcomeHandler)
 
 	// Routes pour l'authentification
 	http.HandleFunc("/login", handlers.LoginHandler)
 	http.HandleFunc("/login-submit", handlers.LoginSubmitHandler)
+	http.HandleFunc("/login-2fa", handlers.TwoFactorLoginHandler)
+	http.HandleFunc("/login-2fa-submit", handlers.TwoFactorLoginSubmitHandler)
 	http.HandleFunc("/signin", handlers.SigninHandler)
 	http.HandleFunc("/signin-submit", handlers.SigninSubmitHandler)
 	http.HandleFunc("/logout", handlers.LogoutHandler)
 
+	// Réinitialisation du mot de passe
+	http.HandleFunc("/forgot-password", handlers.ForgotPasswordHandler)
+	http.HandleFunc("/forgot-password-submit", handlers.ForgotPasswordSubmitHandler)
+	http.HandleFunc("/reset-password", handlers.ResetPasswordHandler)
+	http.HandleFunc("/reset-password-submit", handlers.ResetPasswordSubmitHandler)
+
 	// Route d'accueil après connexion
 	http.HandleFunc("/home", handlers.HomeHandler)
 
+	// API JSON versionnée (description OpenAPI sur /api/v1/openapi.json)
+	http.Handle("/api/v1/", handlers.APIv1Router(bc))
+
 	// Route pour la messagerie
 	http.HandleFunc("/messages", handlers.MessagesHandler(bc))
-	http.HandleFunc("/api/messages", handlers.APIMessagesHandler(bc))
+	http.HandleFunc("GET /api/messages", handlers.RequireAccess(utils.RoleReadOnly, utils.ScopeReadChain, handlers.APIMessagesHandler(bc)))
+	http.HandleFunc("POST /api/messages", handlers.RequireAccess(utils.RoleMember, utils.ScopeSendMessages, handlers.APIMessagesHandler(bc)))
 
 	// Route pour le minage de blocs
-	http.HandleFunc("/mine-block", handlers.MineBlockHandler(bc))
+	http.HandleFunc("/mine-block", handlers.RequireAccess(utils.RoleMember, utils.ScopeMine, handlers.MineBlockHandler(bc)))
 
 	// Route WebSocket pour les mises à jour en temps réel
-	http.HandleFunc("/ws", handlers.WebSocketHandler(bc))
+	http.HandleFunc("/ws", handlers.RequireAccess(utils.RoleReadOnly, utils.ScopeReadChain, handlers.WebSocketHandler(bc)))
+
+	// Présence des utilisateurs et de leurs contacts
+	http.HandleFunc("/api/presence", handlers.RequireAccess(utils.RoleReadOnly, utils.ScopeReadChain, handlers.PresenceHandler))
+
+	// Jetons d'API personnels (accès programmatique via Authorization: Bearer)
+	http.HandleFunc("/tokens", handlers.TokensPageHandler)
+	http.HandleFunc("/api/tokens", handlers.APITokensHandler)
+
+	// Gestion du compte (mot de passe, connexions, suppression)
+	http.HandleFunc("/account", handlers.AccountPageHandler)
+	http.HandleFunc("/account/language", handlers.AccountLanguageHandler)
+	http.HandleFunc("/api/account/password", handlers.ChangePasswordHandler)
+	http.HandleFunc("/api/account/email", handlers.ChangeEmailHandler)
+	http.HandleFunc("/api/account/sessions", handlers.AccountSessionsHandler)
+	http.HandleFunc("/api/account/delete", handlers.DeleteAccountHandler(bc))
+
+	// Double authentification (TOTP)
+	http.HandleFunc("/2fa", handlers.TwoFactorPageHandler)
+	http.HandleFunc("/api/2fa/setup", handlers.TwoFactorSetupHandler)
+	http.HandleFunc("/api/2fa/confirm", handlers.TwoFactorConfirmHandler)
+	http.HandleFunc("/api/2fa/disable", handlers.TwoFactorDisableHandler)
+	http.HandleFunc("/api/2fa/recovery-codes", handlers.TwoFactorRecoveryCodesHandler)
 
 	// Route pour la blockchain
 	http.HandleFunc("/blockchain", handlers.BlockchainHandler(bc))
+	http.HandleFunc("POST /blockchain", handlers.RequireAccess(utils.RoleMember, utils.ScopeMine, handlers.MineBlockHandler(bc)))
 
 	// Route pour les statistiques des mineurs
 	http.HandleFunc("/miners-stats", handlers.MinersStatsHandler(bc))
@@ -88,18 +215,122 @@ func main() {
 	// Route pour les statistiques
 	http.HandleFunc("/stats", handlers.StatsHandler(bc))
 
+	// Routes d'administration (réservées aux administrateurs)
+	http.HandleFunc("/admin", handlers.RequireRole(utils.RoleAdmin, handlers.AdminPageHandler))
+	http.HandleFunc("/admin/users", handlers.RequireRole(utils.RoleAdmin, handlers.AdminUsersHandler))
+	http.HandleFunc("/admin/users/role", handlers.RequireRole(utils.RoleAdmin, handlers.AdminSetRoleHandler(bc)))
+	http.HandleFunc("/admin/users/disable", handlers.RequireRole(utils.RoleAdmin, handlers.AdminDisableUserHandler(bc)))
+	http.HandleFunc("/admin/users/reset-2fa", handlers.RequireRole(utils.RoleAdmin, handlers.AdminResetTwoFactorHandler(bc)))
+	http.HandleFunc("/admin/users/delete", handlers.RequireRole(utils.RoleAdmin, handlers.AdminDeleteUserHandler(bc)))
+	http.HandleFunc("/admin/sessions/logout", handlers.RequireRole(utils.RoleAdmin, handlers.AdminForceLogoutHandler(bc)))
+	http.HandleFunc("/admin/audit", handlers.RequireRole(utils.RoleAdmin, handlers.AdminAuditHandler(bc)))
+	http.HandleFunc("/admin/lockouts", handlers.RequireRole(utils.RoleAdmin, handlers.AdminLockoutsHandler))
+	http.HandleFunc("/admin/lockouts/clear", handlers.RequireRole(utils.RoleAdmin, handlers.AdminClearLockoutHandler))
+	http.HandleFunc("/admin/geoip", handlers.RequireRole(utils.RoleAdmin, handlers.AdminGeoIPHandler))
+
+	// Sondes de vivacité et de disponibilité
+	http.HandleFunc("/healthz", handlers.HealthzHandler)
+	http.HandleFunc("/readyz", handlers.ReadyzHandler(bc))
+
+	// Métriques au format Prometheus
+	http.Handle("/metrics", metrics.Handler())
+
 	// Servir les fichiers statiques
-	fs := http.FileServer(http.Dir("static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	http.Handle("/static/", handlers.StaticHandler())
 
-	// Ouvre le navigateur automatiquement
-	go func() {
-		log.Println("🌍 Ouverture du navigateur...")
-		openBrowser("http://localhost:8080")
-	}()
+	// Ouvre le navigateur automatiquement (désactivable avec -open-browser=false)
+	if cfg.OpenBrowser {
+		go func() {
+			slog.Info("Ouverture du navigateur", "url", cfg.LocalURL())
+			openBrowser(cfg.LocalURL())
+		}()
+	}
+
+	// Toutes les routes passent par le journal des requêtes et la protection CSRF ; en HTTPS,
+	// les réponses portent l'en-tête HSTS
+	server := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: handlers.LoggingMiddleware(handlers.HSTSMiddleware(handlers.LocaleMiddleware(handlers.CSRFMiddleware(handlers.RouteMiddleware(http.DefaultServeMux))))),
+	}
+
+	// Les flux Server-Sent Events sont terminés dès le début de l'arrêt
+	server.RegisterOnShutdown(handlers.CloseEventStreams)
+
+	// SIGINT / SIGTERM déclenchent un arrêt propre
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	servers := []*http.Server{server}
+	serverErr := make(chan error, 2)
+	if cfg.TLS.Enabled {
+		// HTTPS : certificat fourni ou autosigné (généré et conservé dans le répertoire des données)
+		cert, err := utils.LoadCertificate(cfg.DataPath(cfg.TLS.CertFile), cfg.DataPath(cfg.TLS.KeyFile), cfg.TLS.SelfSigned, cfg.TLS.Hosts)
+		if err != nil {
+			fatal("Chargement du certificat TLS impossible", err)
+		}
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		go func() {
+			serverErr <- server.ListenAndServeTLS("", "")
+		}()
 
-	fmt.Println("🚀 Serveur lancé sur : http://localhost:8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatalf("❌ Erreur lors du démarrage du serveur : %v", err)
+		// Redirection des requêtes HTTP vers HTTPS
+		if cfg.TLS.RedirectAddr != "" {
+			redirect := &http.Server{
+				Addr:    cfg.TLS.RedirectAddr,
+				Handler: handlers.LoggingMiddleware(handlers.HTTPSRedirectHandler(cfg.Addr)),
+			}
+			servers = append(servers, redirect)
+			go func() {
+				serverErr <- redirect.ListenAndServe()
+			}()
+			slog.Info("Redirection HTTP vers HTTPS", "addr", cfg.TLS.RedirectAddr)
+		}
+	} else {
+		go func() {
+			serverErr <- server.ListenAndServe()
+		}()
 	}
+	fmt.Printf("🚀 Serveur lancé sur : %s\n", cfg.LocalURL())
+
+	select {
+	case err := <-serverErr:
+		fatal("Erreur lors du démarrage du serveur", err)
+	case sig := <-stop:
+		slog.Info("Signal reçu, arrêt du serveur", "signal", sig.String())
+	}
+	signal.Stop(stop) // Un second signal interrompt immédiatement le programme
+
+	shutdown(bc, servers...)
+	return 0
+}
+
+// shutdown arrête le serveur : plus de nouvelles requêtes, fermeture des WebSocket, attente des
+// minages en cours (au plus shutdownTimeout), arrêt des webhooks, puis sauvegarde de l'état et
+// fermeture du journal
+func shutdown(bc *blockchain.Blockchain, servers ...*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("Requêtes interrompues", "addr", server.Addr, "error", err)
+		}
+	}
+	handlers.CloseWebSockets()
+
+	if err := bc.Drain(ctx); err != nil {
+		slog.Warn("Minages en cours non terminés", "error", err)
+	}
+	if err := handlers.StopWebhooks(ctx); err != nil {
+		slog.Error("Sauvegarde des webhooks impossible", "error", err)
+	}
+	if err := handlers.SaveState(); err != nil {
+		slog.Error("Sauvegarde impossible", "error", err)
+	}
+
+	slog.Info("Serveur arrêté")
+	utils.CloseLog()
 }
@@ -0,0 +1,433 @@
+// Package metrics implémente des compteurs, jauges et histogrammes exposés au format texte
+// de Prometheus (version 0.0.4), sans dépendance externe.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets sont les bornes par défaut des histogrammes de durées, en secondes
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector est une métrique enregistrée, capable d'écrire ses échantillons
+type collector interface {
+	describe() (name, help, kind string)
+	write(w *bufio.Writer)
+}
+
+// Registry regroupe les métriques exposées par un même point d'accès
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// NewRegistry crée un registre vide
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Default est le registre utilisé par les constructeurs du paquet et par Handler
+var Default = NewRegistry()
+
+// register ajoute une métrique ; un nom déjà enregistré est une erreur de programmation
+func (r *Registry) register(c collector) {
+	name, _, _ := c.describe()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.collectors[name]; exists {
+		panic("metrics: métrique déjà enregistrée: " + name)
+	}
+	r.collectors[name] = c
+}
+
+// WriteTo écrit toutes les métriques au format texte de Prometheus, triées par nom
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.Unlock()
+
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+	for _, c := range collectors {
+		name, help, kind := c.describe()
+		fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeHelp(help))
+		fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+		c.write(buf)
+	}
+	err := buf.Flush()
+	return counter.n, err
+}
+
+// Handler sert les métriques du registre par défaut
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// Handler sert les métriques du registre
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// countingWriter compte les octets écrits
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Counter est une valeur qui ne fait qu'augmenter
+type Counter struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewCounter crée et enregistre un compteur
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	Default.register(c)
+	return c
+}
+
+// Inc incrémente le compteur
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add ajoute une valeur positive au compteur
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+// Value retourne la valeur courante
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+func (c *Counter) describe() (string, string, string) { return c.name, c.help, "counter" }
+
+func (c *Counter) write(w *bufio.Writer) {
+	writeSample(w, c.name, nil, nil, c.Value())
+}
+
+// Gauge est une valeur qui peut augmenter ou diminuer
+type Gauge struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewGauge crée et enregistre une jauge
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	Default.register(g)
+	return g
+}
+
+// Set fixe la valeur de la jauge
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Inc augmente la jauge de 1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec diminue la jauge de 1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add ajoute une valeur (éventuellement négative) à la jauge
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+// Value retourne la valeur courante
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+func (g *Gauge) describe() (string, string, string) { return g.name, g.help, "gauge" }
+
+func (g *Gauge) write(w *bufio.Writer) {
+	writeSample(w, g.name, nil, nil, g.Value())
+}
+
+// gaugeFunc est une jauge dont la valeur est calculée à chaque lecture
+type gaugeFunc struct {
+	name, help string
+	value      func() float64
+}
+
+// NewGaugeFunc enregistre une jauge calculée par value au moment de l'exposition
+func NewGaugeFunc(name, help string, value func() float64) {
+	Default.register(&gaugeFunc{name: name, help: help, value: value})
+}
+
+func (g *gaugeFunc) describe() (string, string, string) { return g.name, g.help, "gauge" }
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeSample(w, g.name, nil, nil, g.value())
+}
+
+// CounterVec est un ensemble de compteurs distingués par des étiquettes
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*labelledValue
+}
+
+// labelledValue est la valeur d'une combinaison d'étiquettes
+type labelledValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec crée et enregistre un ensemble de compteurs étiquetés
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*labelledValue)}
+	Default.register(c)
+	return c
+}
+
+// Inc incrémente le compteur correspondant aux valeurs d'étiquettes (dans l'ordre de déclaration)
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add ajoute une valeur positive au compteur correspondant aux valeurs d'étiquettes
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 || len(labelValues) != len(c.labels) {
+		return
+	}
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	entry, ok := c.values[key]
+	if !ok {
+		entry = &labelledValue{labels: append([]string(nil), labelValues...)}
+		c.values[key] = entry
+	}
+	entry.value += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) describe() (string, string, string) { return c.name, c.help, "counter" }
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		entry := c.values[key]
+		writeSample(w, c.name, c.labels, entry.labels, entry.value)
+	}
+}
+
+// Histogram répartit des observations dans des intervalles cumulatifs
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	mu         sync.Mutex
+	counts     []uint64 // Observations par intervalle (non cumulées), la dernière case pour +Inf
+	sum        float64
+	count      uint64
+}
+
+// NewHistogram crée et enregistre un histogramme ; buckets doit être trié par ordre croissant
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(name, help, buckets)
+	Default.register(h)
+	return h
+}
+
+func newHistogram(name, help string, buckets []float64) *Histogram {
+	return &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe enregistre une observation
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v) // Premier intervalle dont la borne est >= v
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) describe() (string, string, string) { return h.name, h.help, "histogram" }
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeSamples(w, nil, nil)
+}
+
+// writeSamples écrit les intervalles cumulés, la somme et le nombre d'observations
+func (h *Histogram) writeSamples(w *bufio.Writer, labels, labelValues []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucketLabels := append(append([]string(nil), labels...), "le")
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		writeSample(w, h.name+"_bucket", bucketLabels, append(append([]string(nil), labelValues...), formatFloat(bound)), float64(cumulative))
+	}
+	writeSample(w, h.name+"_bucket", bucketLabels, append(append([]string(nil), labelValues...), "+Inf"), float64(h.count))
+	writeSample(w, h.name+"_sum", labels, labelValues, h.sum)
+	writeSample(w, h.name+"_count", labels, labelValues, float64(h.count))
+}
+
+// HistogramVec est un ensemble d'histogrammes distingués par des étiquettes
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	labels     []string
+	mu         sync.Mutex
+	histograms map[string]*labelledHistogram
+}
+
+// labelledHistogram est l'histogramme d'une combinaison d'étiquettes
+type labelledHistogram struct {
+	labels    []string
+	histogram *Histogram
+}
+
+// NewHistogramVec crée et enregistre un ensemble d'histogrammes étiquetés
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, buckets: buckets, labels: labels, histograms: make(map[string]*labelledHistogram)}
+	Default.register(h)
+	return h
+}
+
+// Observe enregistre une observation pour les valeurs d'étiquettes (dans l'ordre de déclaration)
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		return
+	}
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	entry, ok := h.histograms[key]
+	if !ok {
+		entry = &labelledHistogram{
+			labels:    append([]string(nil), labelValues...),
+			histogram: newHistogram(h.name, h.help, h.buckets),
+		}
+		h.histograms[key] = entry
+	}
+	h.mu.Unlock()
+	entry.histogram.Observe(v)
+}
+
+func (h *HistogramVec) describe() (string, string, string) { return h.name, h.help, "histogram" }
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	entries := make([]*labelledHistogram, 0, len(h.histograms))
+	for _, key := range sortedKeys(h.histograms) {
+		entries = append(entries, h.histograms[key])
+	}
+	h.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.histogram.writeSamples(w, h.labels, entry.labels)
+	}
+}
+
+// sortedKeys retourne les clés d'une table dans l'ordre lexicographique
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeSample écrit une ligne « nom{étiquette="valeur",...} valeur »
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabel(labelValues[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat formate une valeur selon les conventions de Prometheus
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update réécrit les fichiers de référence : go test ./metrics -update
+var update = flag.Bool("update", false, "réécrire les fichiers de référence de testdata")
+
+// testRegistry remplace le registre par défaut le temps du test
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+	previous := Default
+	Default = NewRegistry()
+	t.Cleanup(func() { Default = previous })
+	return Default
+}
+
+func TestExpositionGolden(t *testing.T) {
+	registry := testRegistry(t)
+
+	// Enregistrées dans le désordre : l'exposition est triée par nom
+	requests := NewCounterVec("bkc_http_requests_total", "Requêtes HTTP traitées.", "method", "status")
+	requests.Inc("GET", "200")
+	requests.Add(2, "POST", "201")
+	requests.Inc("GET", "200")
+	requests.Add(-1, "GET", "200")    // Ignoré : un compteur ne diminue pas
+	requests.Inc("GET")               // Ignoré : nombre d'étiquettes incorrect
+	requests.Inc("GET", `a"b\c`+"\n") // Valeur à échapper
+
+	blocks := NewCounter("bkc_blocks_mined_total", "Blocs minés.\nLigne \\ suivante.")
+	blocks.Add(3)
+	blocks.Add(0.5)
+
+	sessions := NewGauge("bkc_sessions_active", "Sessions ouvertes.")
+	sessions.Set(5)
+	sessions.Inc()
+	sessions.Dec()
+	sessions.Dec()
+
+	NewGaugeFunc("bkc_chain_height", "Hauteur de la chaîne.", func() float64 { return 42 })
+	NewGaugeFunc("bkc_special_values", "Valeurs particulières.", func() float64 { return math.Inf(1) })
+
+	duration := NewHistogram("bkc_mining_duration_seconds", "Durée du minage.", []float64{0.1, 1, 10})
+	for _, v := range []float64{0.05, 0.1, 0.5, 3, 30} {
+		duration.Observe(v)
+	}
+
+	latency := NewHistogramVec("bkc_http_request_duration_seconds", "Durée des requêtes.", []float64{0.01, 0.1}, "route")
+	latency.Observe(0.005, "/stats")
+	latency.Observe(0.5, "/stats")
+	latency.Observe(0.05, "/api/v1/blocks")
+	latency.Observe(1, "/stats", "en trop") // Ignoré
+
+	var buf bytes.Buffer
+	n, err := registry.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo retourne %d octets, %d écrits", n, buf.Len())
+	}
+
+	golden := filepath.Join("testdata", "exposition.txt")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("exposition différente de %s :\n%s", golden, buf.String())
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	testRegistry(t)
+	NewCounter("bkc_doublon", "Premier.")
+	defer func() {
+		if recover() == nil {
+			t.Error("nom déjà enregistré accepté")
+		}
+	}()
+	NewGauge("bkc_doublon", "Second.")
+}
+
+func TestHandler(t *testing.T) {
+	registry := testRegistry(t)
+	NewCounter("bkc_test_total", "Test.").Inc()
+
+	tests := []struct {
+		method string
+		status int
+		body   string
+	}{
+		{http.MethodGet, http.StatusOK, "# HELP bkc_test_total Test.\n# TYPE bkc_test_total counter\nbkc_test_total 1\n"},
+		{http.MethodHead, http.StatusOK, ""},
+		{http.MethodPost, http.StatusMethodNotAllowed, "Méthode non autorisée\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		registry.Handler().ServeHTTP(w, httptest.NewRequest(tt.method, "/metrics", nil))
+		if w.Code != tt.status || (tt.method != http.MethodHead && w.Body.String() != tt.body) {
+			t.Errorf("%s : %d %q, attendu %d %q", tt.method, w.Code, w.Body, tt.status, tt.body)
+		}
+		if tt.status == http.StatusOK && w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+			t.Errorf("%s : Content-Type %q", tt.method, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Gestion du compte : mot de passe, connexions actives et suppression
+document.addEventListener('DOMContentLoaded', function() {
+  const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+  const sessionsTable = document.getElementById('sessions-table');
+  const notificationArea = document.getElementById('notification-area');
+
+  // Afficher une notification temporaire
+  function showNotification(message, type = 'info') {
+    const notification = document.createElement('div');
+    notification.className = `p-3 rounded shadow-lg ${type === 'success' ? 'bg-green-500' : type === 'error' ? 'bg-red-500' : 'bg-blue-500'}`;
+    notification.textContent = message;
+    notificationArea.appendChild(notification);
+    setTimeout(() => notification.remove(), 3000);
+  }
+
+  // Envoyer une requête et renvoyer la réponse JSON
+  async function accountRequest(url, method, body) {
+    const response = await fetch(url, {
+      method: method,
+      headers: {
+        'Content-Type': 'application/json',
+        'X-CSRF-Token': csrfToken,
+      },
+      body: body ? JSON.stringify(body) : undefined
+    });
+    const text = await response.text();
+    if (!response.ok) {
+      throw new Error(text);
+    }
+    return JSON.parse(text);
+  }
+
+  // Charger les connexions actives
+  function loadSessions() {
+    fetch('/api/account/sessions')
+      .then(response => response.json())
+      .then(devices => {
+        sessionsTable.innerHTML = '';
+        devices.forEach(device => {
+          const row = document.createElement('tr');
+          const cells = [
+            device.browser + (device.current ? ' (cette connexion)' : ''),
+            device.ip,
+            new Date(device.createdAt).toLocaleString(),
+            new Date(device.lastSeen).toLocaleString()
+          ];
+          cells.forEach(value => {
+            const cell = document.createElement('td');
+            cell.className = 'py-2 px-4 border-b border-gray-600';
+            cell.textContent = value;
+            cell.title = device.userAgent;
+            row.appendChild(cell);
+          });
+
+          const actionsCell = document.createElement('td');
+          actionsCell.className = 'py-2 px-4 border-b border-gray-600';
+          if (!device.current) {
+            const revokeButton = document.createElement('button');
+            revokeButton.className = 'px-2 py-1 rounded text-xs bg-red-600 hover:bg-red-700';
+            revokeButton.textContent = 'Déconnecter';
+            revokeButton.addEventListener('click', async () => {
+              try {
+                const result = await accountRequest(`/api/account/sessions?id=${encodeURIComponent(device.id)}`, 'DELETE');
+                showNotification(result.message, 'success');
+                loadSessions();
+              } catch (error) {
+                showNotification(`Erreur: ${error.message}`, 'error');
+              }
+            });
+            actionsCell.appendChild(revokeButton);
+          }
+          row.appendChild(actionsCell);
+          sessionsTable.appendChild(row);
+        });
+      })
+      .catch(error => console.error('❌ Erreur chargement connexions:', error));
+  }
+
+  // Enregistrer l'adresse e-mail
+  document.getElementById('email-form').addEventListener('submit', async function(e) {
+    e.preventDefault();
+    const passwordInput = document.getElementById('email-password');
+    const codeInput = document.getElementById('email-code');
+    try {
+      const result = await accountRequest('/api/account/email', 'POST', {
+        email: document.getElementById('email').value,
+        password: passwordInput.value,
+        code: codeInput ? codeInput.value : ''
+      });
+      showNotification(result.message, 'success');
+      passwordInput.value = '';
+      if (codeInput) {
+        codeInput.value = '';
+      }
+    } catch (error) {
+      showNotification(`Erreur: ${error.message}`, 'error');
+    }
+  });
+
+  // Changer le mot de passe
+  const passwordForm = document.getElementById('password-form');
+  passwordForm.addEventListener('submit', async function(e) {
+    e.preventDefault();
+    try {
+      const result = await accountRequest('/api/account/password', 'POST', {
+        currentPassword: document.getElementById('current-password').value,
+        newPassword: document.getElementById('new-password').value,
+        confirmPassword: document.getElementById('confirm-password').value
+      });
+      showNotification(result.message, 'success');
+      passwordForm.reset();
+      loadSessions();
+    } catch (error) {
+      showNotification(`Erreur: ${error.message}`, 'error');
+    }
+  });
+
+  // Supprimer le compte
+  document.getElementById('delete-form').addEventListener('submit', async function(e) {
+    e.preventDefault();
+    if (!confirm('Supprimer définitivement votre compte ? Cette action est irréversible.')) {
+      return;
+    }
+    const codeInput = document.getElementById('delete-code');
+    try {
+      await accountRequest('/api/account/delete', 'POST', {
+        password: document.getElementById('delete-password').value,
+        code: codeInput ? codeInput.value : ''
+      });
+      window.location.href = '/login';
+    } catch (error) {
+      showNotification(`Erreur: ${error.message}`, 'error');
+    }
+  });
+
+  loadSessions();
+});
@@ -0,0 +1,187 @@
+// Gestion de la page d'administration
+document.addEventListener('DOMContentLoaded', function() {
+  const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+  const currentUser = document.getElementById('username').textContent;
+  const usersTable = document.getElementById('users-table');
+  const auditList = document.getElementById('audit-list');
+  const notificationArea = document.getElementById('notification-area');
+
+  // Afficher une notification temporaire
+  function showNotification(message, type = 'info') {
+    const notification = document.createElement('div');
+    notification.className = `p-3 rounded shadow-lg ${type === 'success' ? 'bg-green-500' : type === 'error' ? 'bg-red-500' : 'bg-blue-500'}`;
+    notification.textContent = message;
+    notificationArea.appendChild(notification);
+    setTimeout(() => notification.remove(), 3000);
+  }
+
+  // Envoyer une action d'administration
+  async function adminAction(url, body) {
+    try {
+      const response = await fetch(url, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'X-CSRF-Token': csrfToken,
+        },
+        body: JSON.stringify(body)
+      });
+      const text = await response.text();
+      if (!response.ok) {
+        throw new Error(text);
+      }
+      showNotification(JSON.parse(text).message, 'success');
+      loadUsers();
+      setTimeout(loadAudit, 1500); // Laisser le temps au bloc d'audit d'être miné
+    } catch (error) {
+      showNotification(`Erreur: ${error.message}`, 'error');
+    }
+  }
+
+  // Créer un bouton d'action
+  function actionButton(label, color, onClick) {
+    const button = document.createElement('button');
+    button.className = `px-2 py-1 mr-1 rounded text-xs ${color}`;
+    button.textContent = label;
+    button.addEventListener('click', onClick);
+    return button;
+  }
+
+  // Charger la liste des comptes
+  function loadUsers() {
+    fetch('/admin/users')
+      .then(response => response.json())
+      .then(users => {
+        usersTable.innerHTML = '';
+        users.forEach(user => {
+          const row = document.createElement('tr');
+
+          const nameCell = document.createElement('td');
+          nameCell.className = 'py-2 px-4 border-b border-gray-600 font-semibold';
+          nameCell.textContent = user.username;
+
+          const roleCell = document.createElement('td');
+          roleCell.className = 'py-2 px-4 border-b border-gray-600';
+          const roleSelect = document.createElement('select');
+          roleSelect.className = 'bg-gray-800 rounded px-2 py-1';
+          ['admin', 'member', 'readonly'].forEach(role => {
+            const option = document.createElement('option');
+            option.value = role;
+            option.textContent = role;
+            option.selected = user.role === role;
+            roleSelect.appendChild(option);
+          });
+          roleSelect.disabled = user.username === currentUser;
+          roleSelect.addEventListener('change', () => {
+            adminAction('/admin/users/role', { username: user.username, role: roleSelect.value });
+          });
+          roleCell.appendChild(roleSelect);
+
+          const stateCell = document.createElement('td');
+          stateCell.className = `py-2 px-4 border-b border-gray-600 ${user.disabled ? 'text-red-400' : user.online ? 'text-green-400' : 'text-gray-400'}`;
+          const presenceLabels = { online: 'En ligne', away: 'Absent', busy: 'Occupé' };
+          stateCell.textContent = (user.disabled ? 'Désactivé' : presenceLabels[user.status] || 'Hors ligne') + (user.twoFactor ? ' 🔐' : '');
+
+          const devicesCell = document.createElement('td');
+          devicesCell.className = 'py-2 px-4 border-b border-gray-600';
+          devicesCell.textContent = user.devices;
+
+          const actionsCell = document.createElement('td');
+          actionsCell.className = 'py-2 px-4 border-b border-gray-600';
+          if (user.username !== currentUser) {
+            actionsCell.appendChild(actionButton(user.disabled ? 'Réactiver' : 'Désactiver', 'bg-yellow-600 hover:bg-yellow-700', () => {
+              adminAction('/admin/users/disable', { username: user.username, disabled: !user.disabled });
+            }));
+            actionsCell.appendChild(actionButton('Déconnecter', 'bg-blue-600 hover:bg-blue-700', () => {
+              adminAction('/admin/sessions/logout', { username: user.username });
+            }));
+            if (user.twoFactor) {
+              actionsCell.appendChild(actionButton('Réinitialiser 2FA', 'bg-purple-600 hover:bg-purple-700', () => {
+                if (confirm(`Désactiver la double authentification de ${user.username} ?`)) {
+                  adminAction('/admin/users/reset-2fa', { username: user.username });
+                }
+              }));
+            }
+            actionsCell.appendChild(actionButton('Supprimer', 'bg-red-600 hover:bg-red-700', () => {
+              if (confirm(`Supprimer définitivement le compte ${user.username} ?`)) {
+                adminAction('/admin/users/delete', { username: user.username });
+              }
+            }));
+          }
+
+          row.appendChild(nameCell);
+          row.appendChild(roleCell);
+          row.appendChild(stateCell);
+          row.appendChild(devicesCell);
+          row.appendChild(actionsCell);
+          usersTable.appendChild(row);
+        });
+      })
+      .catch(error => console.error('❌ Erreur chargement utilisateurs:', error));
+  }
+
+  // Charger les blocages anti force brute
+  function loadLockouts() {
+    fetch('/admin/lockouts')
+      .then(response => response.json())
+      .then(lockouts => {
+        const lockoutsTable = document.getElementById('lockouts-table');
+        lockoutsTable.innerHTML = '';
+        if (lockouts.length === 0) {
+          lockoutsTable.innerHTML = '<tr><td class="py-2 px-4 text-gray-400" colspan="5">Aucun blocage actif.</td></tr>';
+          return;
+        }
+        const kinds = { account: 'Compte', ip: 'IP', signup: 'Inscriptions' };
+        lockouts.forEach(lockout => {
+          const row = document.createElement('tr');
+          const cells = [
+            kinds[lockout.kind] + (lockout.locked ? ' (verrouillé)' : ''),
+            lockout.key,
+            lockout.failures,
+            new Date(lockout.blockedUntil).toLocaleString()
+          ];
+          cells.forEach(value => {
+            const cell = document.createElement('td');
+            cell.className = 'py-2 px-4 border-b border-gray-600';
+            cell.textContent = value;
+            row.appendChild(cell);
+          });
+
+          const actionsCell = document.createElement('td');
+          actionsCell.className = 'py-2 px-4 border-b border-gray-600';
+          actionsCell.appendChild(actionButton('Débloquer', 'bg-green-600 hover:bg-green-700', async () => {
+            await adminAction('/admin/lockouts/clear', { kind: lockout.kind, key: lockout.key });
+            loadLockouts();
+          }));
+          row.appendChild(actionsCell);
+          lockoutsTable.appendChild(row);
+        });
+      })
+      .catch(error => console.error('❌ Erreur chargement blocages:', error));
+  }
+
+  // Charger la piste d'audit
+  function loadAudit() {
+    fetch('/admin/audit')
+      .then(response => response.json())
+      .then(events => {
+        auditList.innerHTML = '';
+        if (events.length === 0) {
+          auditList.textContent = 'Aucun événement enregistré.';
+          return;
+        }
+        events.forEach(event => {
+          const item = document.createElement('div');
+          item.className = 'bg-gray-700 rounded p-2';
+          const date = new Date(event.timestamp).toLocaleString();
+          item.textContent = `${date} — ${event.actor} : ${event.action} sur ${event.target}${event.details ? ` (${event.details})` : ''}`;
+          auditList.appendChild(item);
+        });
+      })
+      .catch(error => console.error('❌ Erreur chargement audit:', error));
+  }
+
+  loadUsers();
+  loadLockouts();
+  loadAudit();
+});
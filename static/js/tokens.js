@@ -0,0 +1,105 @@
+// Gestion des jetons d'API personnels
+document.addEventListener('DOMContentLoaded', function() {
+  const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+  const tokensTable = document.getElementById('tokens-table');
+  const tokenForm = document.getElementById('token-form');
+  const notificationArea = document.getElementById('notification-area');
+
+  // Afficher une notification temporaire
+  function showNotification(message, type = 'info') {
+    const notification = document.createElement('div');
+    notification.className = `p-3 rounded shadow-lg ${type === 'success' ? 'bg-green-500' : type === 'error' ? 'bg-red-500' : 'bg-blue-500'}`;
+    notification.textContent = message;
+    notificationArea.appendChild(notification);
+    setTimeout(() => notification.remove(), 3000);
+  }
+
+  // Charger la liste des jetons
+  function loadTokens() {
+    fetch('/api/tokens')
+      .then(response => response.json())
+      .then(tokens => {
+        tokensTable.innerHTML = '';
+        if (tokens.length === 0) {
+          tokensTable.innerHTML = '<tr><td class="py-2 px-4 text-gray-400" colspan="5">Aucun jeton.</td></tr>';
+          return;
+        }
+        tokens.forEach(token => {
+          const row = document.createElement('tr');
+          const lastUsed = new Date(token.lastUsed);
+          const cells = [
+            token.name,
+            token.scopes.join(', '),
+            new Date(token.createdAt).toLocaleString(),
+            lastUsed.getFullYear() > 1 ? lastUsed.toLocaleString() : 'Jamais'
+          ];
+          cells.forEach(value => {
+            const cell = document.createElement('td');
+            cell.className = 'py-2 px-4 border-b border-gray-600';
+            cell.textContent = value;
+            row.appendChild(cell);
+          });
+
+          const actionsCell = document.createElement('td');
+          actionsCell.className = 'py-2 px-4 border-b border-gray-600';
+          const revokeButton = document.createElement('button');
+          revokeButton.className = 'px-2 py-1 rounded text-xs bg-red-600 hover:bg-red-700';
+          revokeButton.textContent = 'Révoquer';
+          revokeButton.addEventListener('click', () => revokeToken(token));
+          actionsCell.appendChild(revokeButton);
+          row.appendChild(actionsCell);
+
+          tokensTable.appendChild(row);
+        });
+      })
+      .catch(error => console.error('❌ Erreur chargement jetons:', error));
+  }
+
+  // Révoquer un jeton
+  async function revokeToken(token) {
+    if (!confirm(`Révoquer le jeton « ${token.name} » ?`)) {
+      return;
+    }
+    try {
+      const response = await fetch(`/api/tokens?id=${encodeURIComponent(token.id)}`, {
+        method: 'DELETE',
+        headers: { 'X-CSRF-Token': csrfToken }
+      });
+      if (!response.ok) {
+        throw new Error(await response.text());
+      }
+      showNotification('Jeton révoqué', 'success');
+      loadTokens();
+    } catch (error) {
+      showNotification(`Erreur: ${error.message}`, 'error');
+    }
+  }
+
+  // Créer un jeton
+  tokenForm.addEventListener('submit', async function(e) {
+    e.preventDefault();
+    const scopes = Array.from(tokenForm.querySelectorAll('input[name="scope"]:checked')).map(input => input.value);
+    try {
+      const response = await fetch('/api/tokens', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'X-CSRF-Token': csrfToken,
+        },
+        body: JSON.stringify({ name: document.getElementById('token-name').value, scopes: scopes })
+      });
+      const text = await response.text();
+      if (!response.ok) {
+        throw new Error(text);
+      }
+      document.getElementById('new-token-value').textContent = JSON.parse(text).token;
+      document.getElementById('new-token').classList.remove('hidden');
+      tokenForm.reset();
+      loadTokens();
+    } catch (error) {
+      showNotification(`Erreur: ${error.message}`, 'error');
+    }
+  });
+
+  loadTokens();
+});
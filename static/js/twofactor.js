@@ -0,0 +1,94 @@
+// Gestion de la double authentification (TOTP)
+document.addEventListener('DOMContentLoaded', function() {
+  const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+  const notificationArea = document.getElementById('notification-area');
+
+  // Afficher une notification temporaire
+  function showNotification(message, type = 'info') {
+    const notification = document.createElement('div');
+    notification.className = `p-3 rounded shadow-lg ${type === 'success' ? 'bg-green-500' : type === 'error' ? 'bg-red-500' : 'bg-blue-500'}`;
+    notification.textContent = message;
+    notificationArea.appendChild(notification);
+    setTimeout(() => notification.remove(), 3000);
+  }
+
+  // Envoyer une action de double authentification
+  async function twoFactorAction(url, body) {
+    const response = await fetch(url, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'X-CSRF-Token': csrfToken,
+      },
+      body: JSON.stringify(body || {})
+    });
+    const text = await response.text();
+    if (!response.ok) {
+      throw new Error(text);
+    }
+    return JSON.parse(text);
+  }
+
+  // Afficher les codes de récupération une seule fois
+  function showRecoveryCodes(codes) {
+    document.getElementById('recovery-codes-list').textContent = codes.join('\n');
+    document.getElementById('recovery-codes').classList.remove('hidden');
+  }
+
+  const setupButton = document.getElementById('setup-button');
+  if (setupButton) {
+    setupButton.addEventListener('click', async function() {
+      try {
+        const result = await twoFactorAction('/api/2fa/setup');
+        document.getElementById('setup-secret').textContent = result.secret;
+        const uri = document.getElementById('setup-uri');
+        uri.textContent = result.uri;
+        uri.href = result.uri;
+        document.getElementById('setup-step').classList.remove('hidden');
+        setupButton.classList.add('hidden');
+      } catch (error) {
+        showNotification(`Erreur: ${error.message}`, 'error');
+      }
+    });
+
+    document.getElementById('confirm-form').addEventListener('submit', async function(e) {
+      e.preventDefault();
+      try {
+        const result = await twoFactorAction('/api/2fa/confirm', { code: document.getElementById('confirm-code').value });
+        showNotification(result.message, 'success');
+        document.getElementById('setup-step').classList.add('hidden');
+        showRecoveryCodes(result.recoveryCodes);
+      } catch (error) {
+        showNotification(`Erreur: ${error.message}`, 'error');
+      }
+    });
+  }
+
+  const disableButton = document.getElementById('disable-button');
+  if (disableButton) {
+    const codeInput = document.getElementById('manage-code');
+
+    disableButton.addEventListener('click', async function() {
+      if (!confirm('Désactiver la double authentification ?')) {
+        return;
+      }
+      try {
+        await twoFactorAction('/api/2fa/disable', { code: codeInput.value });
+        window.location.reload();
+      } catch (error) {
+        showNotification(`Erreur: ${error.message}`, 'error');
+      }
+    });
+
+    document.getElementById('regenerate-button').addEventListener('click', async function() {
+      try {
+        const result = await twoFactorAction('/api/2fa/recovery-codes', { code: codeInput.value });
+        showNotification(result.message, 'success');
+        codeInput.value = '';
+        showRecoveryCodes(result.recoveryCodes);
+      } catch (error) {
+        showNotification(`Erreur: ${error.message}`, 'error');
+      }
+    });
+  }
+});
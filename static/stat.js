@@ -56,10 +56,15 @@ document.addEventListener('DOMContentLoaded', () => {
                 document.getElementById('visitor-count').innerText = data.visitorCount;
                 document.getElementById('active-sessions').innerText = data.activeSessions;
                 document.getElementById('daily-transactions').innerText = data.dailyTransactions;
+                document.getElementById('active-lockouts').innerText = data.activeLockouts;
                 document.getElementById('last-block').innerText = JSON.stringify(data.lastBlock, null, 2);
                 
                 // Mise à jour du tableau des connexions
                 updateConnectionsTable(data.recentConnections);
+
+                // Répartitions par pays et par fournisseur
+                updateDistribution('countries-list', data.countries, code => `${getFlagEmoji(code)} ${code === '??' ? 'Inconnu' : code}`);
+                updateDistribution('isps-list', data.isps, isp => isp);
                 
                 // Mise à jour du graphique
                 updateChart(data.transactionHistory);
@@ -137,6 +142,49 @@ document.addEventListener('DOMContentLoaded', () => {
         });
     }
     
+    // Afficher une répartition sous forme de barres proportionnelles
+    function updateDistribution(listId, entries, formatLabel) {
+        const list = document.getElementById(listId);
+        if (!list) {
+            return;
+        }
+        list.innerHTML = '';
+
+        if (!entries || entries.length === 0) {
+            const empty = document.createElement('li');
+            empty.className = 'text-gray-400';
+            empty.textContent = 'Aucune donnée';
+            list.appendChild(empty);
+            return;
+        }
+
+        const total = entries.reduce((sum, entry) => sum + entry.count, 0);
+        entries.forEach(entry => {
+            const percent = Math.round(entry.count * 100 / total);
+
+            const item = document.createElement('li');
+            const header = document.createElement('div');
+            header.className = 'flex justify-between text-sm';
+            const label = document.createElement('span');
+            label.textContent = formatLabel(entry.label);
+            const value = document.createElement('span');
+            value.textContent = `${entry.count} (${percent}%)`;
+            header.appendChild(label);
+            header.appendChild(value);
+
+            const bar = document.createElement('div');
+            bar.className = 'h-2 bg-gray-700 rounded';
+            const fill = document.createElement('div');
+            fill.className = 'h-2 bg-purple-500 rounded';
+            fill.style.width = `${percent}%`;
+            bar.appendChild(fill);
+
+            item.appendChild(header);
+            item.appendChild(bar);
+            list.appendChild(item);
+        });
+    }
+
     // Récupérer le temps écoulé depuis une date au format humain
     function getTimeElapsed(date) {
         const now = new Date();
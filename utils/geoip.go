@@ -0,0 +1,289 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GeoRecord contient les informations de géolocalisation d'une adresse IP
+type GeoRecord struct {
+	CountryCode string // Code pays ISO 3166-1 alpha-2
+	City        string // Ville
+	ASN         uint32 // Numéro de système autonome
+	ISP         string // Fournisseur d'accès ou organisation du système autonome
+}
+
+// merge complète les champs vides avec ceux d'un autre enregistrement
+func (g *GeoRecord) merge(other GeoRecord) {
+	if g.CountryCode == "" {
+		g.CountryCode = other.CountryCode
+	}
+	if g.City == "" {
+		g.City = other.City
+	}
+	if g.ASN == 0 {
+		g.ASN = other.ASN
+	}
+	if g.ISP == "" {
+		g.ISP = other.ISP
+	}
+}
+
+// GeoDatabase est une base de géolocalisation locale
+type GeoDatabase interface {
+	Lookup(ip net.IP) (GeoRecord, bool)
+}
+
+// GeoIPStatus décrit les bases de géolocalisation chargées
+type GeoIPStatus struct {
+	Files    []string  `json:"files"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+var (
+	geoDatabases []GeoDatabase // Bases interrogées dans l'ordre, la première réponse l'emporte champ par champ
+	geoStatus    GeoIPStatus
+	geoMu        sync.RWMutex
+)
+
+// OpenGeoDatabase ouvre une base MaxMind DB (.mmdb) ou un fichier CSV de plages CIDR
+func OpenGeoDatabase(path string) (GeoDatabase, error) {
+	if strings.EqualFold(filepath.Ext(path), ".mmdb") {
+		reader, err := openMMDB(path)
+		if err != nil {
+			return nil, err
+		}
+		return &mmdbGeoDatabase{reader: reader}, nil
+	}
+	return openCSVGeoDatabase(path)
+}
+
+// LoadGeoIP charge les bases de géolocalisation indiquées (par exemple une base de villes et une
+// base ASN). En cas d'erreur, les bases précédemment chargées restent en service.
+func LoadGeoIP(paths []string) error {
+	databases := make([]GeoDatabase, 0, len(paths))
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		db, err := OpenGeoDatabase(path)
+		if err != nil {
+			return fmt.Errorf("base de géolocalisation %s: %v", path, err)
+		}
+		databases = append(databases, db)
+		files = append(files, path)
+	}
+
+	geoMu.Lock()
+	geoDatabases = databases
+	geoStatus = GeoIPStatus{Files: files, LoadedAt: time.Now()}
+	geoMu.Unlock()
+
+	if len(files) > 0 {
+		slog.Info("Bases de géolocalisation chargées", "files", files)
+	}
+	return nil
+}
+
+// CurrentGeoIPStatus retourne les bases chargées et la date du dernier chargement
+func CurrentGeoIPStatus() GeoIPStatus {
+	geoMu.RLock()
+	defer geoMu.RUnlock()
+	status := geoStatus
+	status.Files = append([]string{}, geoStatus.Files...)
+	return status
+}
+
+// LookupGeoIP interroge les bases chargées et fusionne leurs réponses
+func LookupGeoIP(ip net.IP) (GeoRecord, bool) {
+	geoMu.RLock()
+	databases := geoDatabases
+	geoMu.RUnlock()
+
+	var record GeoRecord
+	found := false
+	for _, db := range databases {
+		if r, ok := db.Lookup(ip); ok {
+			record.merge(r)
+			found = true
+		}
+	}
+	return record, found
+}
+
+// parseASN accepte "AS15169" ou "15169"
+func parseASN(value string) uint32 {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "AS")
+	asn, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(asn)
+}
+
+// mmdbGeoDatabase adapte une base MaxMind DB (GeoLite2/GeoIP2 City, Country, ASN, ISP ;
+// DB-IP ; IPinfo) au format GeoRecord
+type mmdbGeoDatabase struct {
+	reader *mmdbReader
+}
+
+// Lookup implémente GeoDatabase
+func (db *mmdbGeoDatabase) Lookup(ip net.IP) (GeoRecord, bool) {
+	value, err := db.reader.lookup(ip)
+	if err != nil || value == nil {
+		return GeoRecord{}, false
+	}
+
+	var record GeoRecord
+
+	// Pays : GeoIP2 ({"country":{"iso_code":"FR"}}) ou IPinfo ({"country":"FR"})
+	switch country := mmdbPath(value, "country").(type) {
+	case string:
+		record.CountryCode = country
+	default:
+		record.CountryCode = mmdbToString(mmdbPath(country, "iso_code"))
+	}
+	if record.CountryCode == "" {
+		record.CountryCode = mmdbToString(mmdbPath(value, "registered_country", "iso_code"))
+	}
+	record.CountryCode = strings.ToUpper(record.CountryCode)
+
+	// Ville : nom français de préférence, sinon anglais ; IPinfo utilise {"city":"Paris"}
+	switch city := mmdbPath(value, "city").(type) {
+	case string:
+		record.City = city
+	default:
+		record.City = mmdbToString(mmdbPath(city, "names", "fr"))
+		if record.City == "" {
+			record.City = mmdbToString(mmdbPath(city, "names", "en"))
+		}
+	}
+
+	// Système autonome et fournisseur
+	record.ASN = uint32(mmdbToUint(mmdbPath(value, "autonomous_system_number")))
+	if record.ASN == 0 {
+		record.ASN = parseASN(mmdbToString(mmdbPath(value, "asn")))
+	}
+	for _, key := range []string{"isp", "autonomous_system_organization", "as_name", "organization"} {
+		if record.ISP = mmdbToString(mmdbPath(value, key)); record.ISP != "" {
+			break
+		}
+	}
+
+	return record, record != GeoRecord{}
+}
+
+// csvGeoDatabase est une base de plages CIDR chargée depuis un fichier CSV :
+//
+//	network,country_code,city,asn,isp
+//	203.0.113.0/24,FR,Paris,AS64500,Exemple Télécom
+//
+// La ligne d'en-tête et les lignes commençant par '#' sont ignorées ; les colonnes après
+// le pays sont facultatives. La plage la plus spécifique l'emporte.
+type csvGeoDatabase struct {
+	prefixes []int                        // Longueurs de préfixe présentes (sur 128 bits), décroissantes
+	networks map[int]map[string]GeoRecord // Enregistrements par longueur de préfixe et adresse réseau
+}
+
+// openCSVGeoDatabase charge un fichier CSV de plages CIDR
+func openCSVGeoDatabase(path string) (*csvGeoDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	db := &csvGeoDatabase{networks: make(map[int]map[string]GeoRecord)}
+	for first := true; ; first = false {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		network, err := parseNetwork(fields[0])
+		if err != nil {
+			if first {
+				continue // Ligne d'en-tête
+			}
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("ligne %d: plage invalide %q", line, fields[0])
+		}
+
+		var record GeoRecord
+		column := func(i int) string {
+			if i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		record.CountryCode = strings.ToUpper(column(1))
+		record.City = column(2)
+		record.ASN = parseASN(column(3))
+		record.ISP = column(4)
+
+		ones, _ := network.Mask.Size()
+		if len(network.IP) == net.IPv4len {
+			ones += 96 // Les adresses IPv4 sont indexées sous leur forme IPv6 (::ffff:a.b.c.d)
+		}
+		if db.networks[ones] == nil {
+			db.networks[ones] = make(map[string]GeoRecord)
+			db.prefixes = append(db.prefixes, ones)
+		}
+		db.networks[ones][string(network.IP.To16())] = record
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(db.prefixes)))
+	return db, nil
+}
+
+// parseNetwork accepte une plage CIDR ou une adresse seule
+func parseNetwork(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("adresse invalide: %q", value)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	return network, err
+}
+
+// Lookup implémente GeoDatabase
+func (db *csvGeoDatabase) Lookup(ip net.IP) (GeoRecord, bool) {
+	address := ip.To16()
+	if address == nil {
+		return GeoRecord{}, false
+	}
+	for _, ones := range db.prefixes {
+		key := string(address.Mask(net.CIDRMask(ones, 128)))
+		if record, ok := db.networks[ones][key]; ok {
+			return record, true
+		}
+	}
+	return GeoRecord{}, false
+}
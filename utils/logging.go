@@ -1,19 +1,192 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"os"
+	"sync"
 )
 
-var LogFile *os.File
+// LogFile est le fichier journal (JSON), archivé lorsqu'il dépasse sa taille maximale
+var LogFile *RotatingFile
 
-// LogRequest enregistre les journaux des requêtes
-func LogRequest(r *http.Request) {
+// LogOptions décrit la destination et le format du journal
+type LogOptions struct {
+	Level      slog.Level // Niveau minimal
+	JSON       bool       // Format JSON sur la sortie d'erreur (le fichier est toujours en JSON)
+	File       string     // Fichier journal ("" : sortie d'erreur uniquement)
+	MaxSize    int64      // Taille au-delà de laquelle le fichier est archivé (0 : jamais)
+	MaxBackups int        // Nombre d'archives conservées
+}
+
+// SetupLogging installe le journal structuré par défaut : sortie d'erreur (texte ou JSON) et
+// fichier JSON avec rotation. Les appels au paquet log passent également par ce journal.
+func SetupLogging(opts LogOptions) error {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+
+	var console slog.Handler = slog.NewTextHandler(os.Stderr, handlerOpts)
+	if opts.JSON {
+		console = slog.NewJSONHandler(os.Stderr, handlerOpts)
+	}
+	handlers := []slog.Handler{console}
+
+	if opts.File != "" {
+		file, err := OpenRotatingFile(opts.File, opts.MaxSize, opts.MaxBackups)
+		if err != nil {
+			return err
+		}
+		LogFile = file
+		handlers = append(handlers, slog.NewJSONHandler(file, handlerOpts))
+	}
+
+	slog.SetDefault(slog.New(fanoutHandler(handlers)))
+	return nil
+}
+
+// CloseLog ferme le fichier journal ; les messages suivants ne sont plus écrits que sur la sortie d'erreur
+func CloseLog() error {
 	if LogFile == nil {
-		return
+		return nil
+	}
+	return LogFile.Close()
+}
+
+// fanoutHandler transmet chaque enregistrement à plusieurs destinations
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, record.Level) {
+			if err := handler.Handle(ctx, record.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
+
+// RotatingFile est un fichier journal renommé en path.1 (les archives précédentes devenant
+// path.2, path.3...) lorsqu'une écriture lui ferait dépasser maxSize octets
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// OpenRotatingFile ouvre (ou crée) un fichier journal en ajout
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open ouvre le fichier courant. L'appelant doit détenir r.mu (ou être le constructeur).
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write implémente io.Writer
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			// Mieux vaut dépasser la taille maximale que perdre des lignes du journal
+			fmt.Fprintf(os.Stderr, "rotation du journal impossible: %v\n", err)
+		}
+		if r.file == nil {
+			return 0, os.ErrClosed
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate archive le fichier courant et en ouvre un nouveau. L'appelant doit détenir r.mu.
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		r.open()
+		return err
+	}
+
+	if r.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			r.open()
+			return err
+		}
+	} else if err := os.Truncate(r.path, 0); err != nil {
+		r.open()
+		return err
+	}
+
+	return r.open()
+}
+
+// Close ferme le fichier journal
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
 	}
-	clientIP := GetVisitorIP(r)
-	logLine := fmt.Sprintf("%s - %s %s\n", clientIP, r.Method, r.URL.Path)
-	LogFile.WriteString(logLine)
+	err := r.file.Close()
+	r.file = nil
+	return err
 }
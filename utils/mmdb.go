@@ -0,0 +1,349 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// Lecteur minimal du format MaxMind DB (.mmdb), utilisé par les bases GeoLite2/GeoIP2,
+// DB-IP et IPinfo. Spécification : https://maxmind.github.io/MaxMind-DB/
+
+// mmdbMetadataMarker précède la section des métadonnées, à la fin du fichier
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// mmdbDataSeparator est la taille du séparateur entre l'arbre de recherche et les données
+const mmdbDataSeparator = 16
+
+// mmdbMaxDepth limite l'imbrication des données pour refuser les fichiers malformés
+const mmdbMaxDepth = 32
+
+// errMMDBCorrupt signale un fichier dont la structure est incohérente
+var errMMDBCorrupt = errors.New("base MaxMind DB corrompue")
+
+// mmdbReader recherche des adresses IP dans une base MaxMind DB chargée en mémoire
+type mmdbReader struct {
+	tree         []byte // Arbre binaire de recherche
+	data         []byte // Section des données
+	nodeCount    uint32
+	recordSize   uint16
+	ipVersion    uint16
+	ipv4Start    uint32 // Nœud correspondant à ::/96, point de départ des adresses IPv4
+	databaseType string
+}
+
+// openMMDB charge une base MaxMind DB
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	markerAt := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if markerAt < 0 {
+		return nil, fmt.Errorf("%s: métadonnées MaxMind DB introuvables", path)
+	}
+
+	metadata, _, err := mmdbDecoder(buf[markerAt+len(mmdbMetadataMarker):]).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: métadonnées illisibles: %v", path, err)
+	}
+	meta, ok := metadata.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: métadonnées illisibles", path)
+	}
+
+	r := &mmdbReader{
+		nodeCount:    uint32(mmdbToUint(meta["node_count"])),
+		recordSize:   uint16(mmdbToUint(meta["record_size"])),
+		ipVersion:    uint16(mmdbToUint(meta["ip_version"])),
+		databaseType: mmdbToString(meta["database_type"]),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%s: taille d'enregistrement non prise en charge (%d)", path, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%s: version IP non prise en charge (%d)", path, r.ipVersion)
+	}
+
+	treeSize := int(r.nodeCount) * int(r.recordSize) / 4
+	if treeSize+mmdbDataSeparator > markerAt {
+		return nil, fmt.Errorf("%s: %v", path, errMMDBCorrupt)
+	}
+	r.tree = buf[:treeSize]
+	r.data = buf[treeSize+mmdbDataSeparator : markerAt]
+
+	// Les adresses IPv4 d'une base IPv6 se trouvent sous ::/96
+	if r.ipVersion == 6 {
+		node := uint32(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+
+	return r, nil
+}
+
+// readNode lit l'enregistrement gauche (bit 0) ou droit (bit 1) d'un nœud de l'arbre
+func (r *mmdbReader) readNode(node uint32, bit byte) uint32 {
+	switch r.recordSize {
+	case 24:
+		b := r.tree[node*6+uint32(bit)*3:]
+		return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		b := r.tree[node*8+uint32(bit)*4:]
+		return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	}
+}
+
+// lookup retourne les données associées à une adresse, ou nil si elle n'est pas dans la base
+func (r *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	node := uint32(0)
+	address := ip.To16()
+	if ip4 := ip.To4(); ip4 != nil {
+		address = ip4
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+	if address == nil {
+		return nil, nil
+	}
+
+	bitCount := len(address) * 8
+	for i := 0; i < bitCount && node < r.nodeCount; i++ {
+		bit := (address[i>>3] >> (7 - uint(i&7))) & 1
+		node = r.readNode(node, bit)
+	}
+
+	switch {
+	case node == r.nodeCount:
+		return nil, nil // Adresse absente de la base
+	case node < r.nodeCount:
+		return nil, errMMDBCorrupt
+	}
+
+	offset := int(node-r.nodeCount) - mmdbDataSeparator
+	if offset < 0 || offset >= len(r.data) {
+		return nil, errMMDBCorrupt
+	}
+	value, _, err := mmdbDecoder(r.data).decode(offset, 0)
+	return value, err
+}
+
+// mmdbDecoder décode la section des données (ou des métadonnées) d'une base MaxMind DB
+type mmdbDecoder []byte
+
+// Types de données du format MaxMind DB
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// take retourne n octets à partir de offset
+func (d mmdbDecoder) take(offset, n int) ([]byte, error) {
+	if offset < 0 || n < 0 || offset+n > len(d) {
+		return nil, errMMDBCorrupt
+	}
+	return d[offset : offset+n], nil
+}
+
+// decode décode la valeur située à offset et retourne l'offset qui la suit
+func (d mmdbDecoder) decode(offset, depth int) (interface{}, int, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errMMDBCorrupt
+	}
+
+	b, err := d.take(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	control := b[0]
+	offset++
+	kind := int(control >> 5)
+
+	// Un pointeur désigne une valeur située ailleurs dans la section des données
+	if kind == mmdbPointer {
+		sizeBits := int(control>>3) & 0x3
+		b, err := d.take(offset, sizeBits+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		target := 0
+		if sizeBits < 3 {
+			target = int(control & 0x7)
+		}
+		for _, c := range b {
+			target = target<<8 | int(c)
+		}
+		target += [4]int{0, 2048, 526336, 0}[sizeBits]
+
+		value, _, err := d.decode(target, depth+1)
+		return value, offset + sizeBits + 1, err
+	}
+
+	if kind == mmdbExtended {
+		b, err := d.take(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		kind = 7 + int(b[0])
+		offset++
+	}
+
+	size := int(control & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		b, err := d.take(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		extra := 0
+		for _, c := range b {
+			extra = extra<<8 | int(c)
+		}
+		size = [4]int{0, 29, 285, 65821}[n] + extra
+		offset += n
+	}
+
+	switch kind {
+	case mmdbMap:
+		m := make(map[string]interface{}, min(size, 64)) // Taille plafonnée : la valeur annoncée peut être falsifiée
+		for i := 0; i < size; i++ {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errMMDBCorrupt
+			}
+			value, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+
+	case mmdbArray:
+		values := make([]interface{}, 0, min(size, 64))
+		for i := 0; i < size; i++ {
+			value, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+
+	case mmdbBool:
+		return size != 0, offset, nil
+
+	case mmdbContainer, mmdbEndMarker:
+		return nil, offset, nil
+	}
+
+	b, err = d.take(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset += size
+
+	switch kind {
+	case mmdbString:
+		return string(b), offset, nil
+	case mmdbBytes, mmdbUint128:
+		return append([]byte(nil), b...), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return math.Float64frombits(uint64(beUint(b))), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return float64(math.Float32frombits(uint32(beUint(b)))), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		if size > 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return beUint(b), offset, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		shift := 32 - 8*uint(size) // Extension du signe des entiers stockés sur moins de 4 octets
+		return int64(int32(uint32(beUint(b))<<shift) >> shift), offset, nil
+	}
+	return nil, 0, fmt.Errorf("type MaxMind DB inconnu: %d", kind)
+}
+
+// beUint décode un entier non signé big-endian
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// mmdbToUint convertit une valeur décodée en entier non signé
+func mmdbToUint(v interface{}) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		if n > 0 {
+			return uint64(n)
+		}
+	}
+	return 0
+}
+
+// mmdbToString convertit une valeur décodée en chaîne
+func mmdbToString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// mmdbPath parcourt des tables imbriquées (ex: "city", "names", "fr")
+func mmdbPath(v interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// mmdbEncode encode une valeur au format de données MaxMind DB
+func mmdbEncode(v interface{}) []byte {
+	header := func(kind, size int) []byte {
+		var b []byte
+		switch {
+		case size < 29:
+			b = []byte{byte(size)}
+		case size < 285:
+			b = []byte{29, byte(size - 29)}
+		default:
+			b = []byte{30, byte((size - 285) >> 8), byte(size - 285)}
+		}
+		if kind < 8 {
+			b[0] |= byte(kind << 5)
+			return b
+		}
+		return append([]byte{b[0]}, append([]byte{byte(kind - 7)}, b[1:]...)...)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return append(header(mmdbString, len(v)), v...)
+	case uint16:
+		return append(header(mmdbUint16, 2), byte(v>>8), byte(v))
+	case uint32:
+		b := binary.BigEndian.AppendUint32(nil, v)
+		return append(header(mmdbUint32, 4), b...)
+	case float64:
+		b := binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+		return append(header(mmdbDouble, 8), b...)
+	case bool:
+		if v {
+			return header(mmdbBool, 1)
+		}
+		return header(mmdbBool, 0)
+	case []interface{}:
+		b := header(mmdbArray, len(v))
+		for _, item := range v {
+			b = append(b, mmdbEncode(item)...)
+		}
+		return b
+	case map[string]interface{}:
+		b := header(mmdbMap, len(v))
+		for key, value := range v {
+			b = append(b, mmdbEncode(key)...)
+			b = append(b, mmdbEncode(value)...)
+		}
+		return b
+	}
+	panic("type non pris en charge")
+}
+
+// mmdbTrieNode est un nœud de l'arbre de recherche en construction
+type mmdbTrieNode struct {
+	child [2]*mmdbTrieNode
+	data  int // Indice de l'enregistrement pour une feuille, -1 pour un nœud interne
+}
+
+// buildMMDB construit une base MaxMind DB associant chaque réseau à un enregistrement
+func buildMMDB(ipVersion uint16, recordSize int, networks map[string]map[string]interface{}) []byte {
+	root := &mmdbTrieNode{data: -1}
+	var records [][]byte
+	for cidr, record := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		address := []byte(network.IP)
+		prefix, _ := network.Mask.Size()
+		if ipVersion == 6 && len(address) == net.IPv4len {
+			address = append(make([]byte, 12), address...) // ::a.b.c.d
+			prefix += 96
+		}
+
+		node := root
+		for i := 0; i < prefix-1; i++ {
+			bit := address[i>>3] >> (7 - uint(i&7)) & 1
+			if node.child[bit] == nil {
+				node.child[bit] = &mmdbTrieNode{data: -1}
+			}
+			node = node.child[bit]
+		}
+		last := prefix - 1
+		node.child[address[last>>3]>>(7-uint(last&7))&1] = &mmdbTrieNode{data: len(records)}
+		records = append(records, mmdbEncode(record))
+	}
+
+	// Numérotation des nœuds internes en largeur
+	var nodes []*mmdbTrieNode
+	numbers := make(map[*mmdbTrieNode]uint32)
+	for queue := []*mmdbTrieNode{root}; len(queue) > 0; queue = queue[1:] {
+		node := queue[0]
+		numbers[node] = uint32(len(nodes))
+		nodes = append(nodes, node)
+		for _, child := range node.child {
+			if child != nil && child.data < 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var data []byte
+	offsets := make([]int, len(records))
+	for i, record := range records {
+		offsets[i] = len(data)
+		data = append(data, record...)
+	}
+
+	nodeCount := uint32(len(nodes))
+	value := func(child *mmdbTrieNode) uint32 {
+		switch {
+		case child == nil:
+			return nodeCount
+		case child.data < 0:
+			return numbers[child]
+		}
+		return nodeCount + mmdbDataSeparator + uint32(offsets[child.data])
+	}
+
+	var tree []byte
+	for _, node := range nodes {
+		left, right := value(node.child[0]), value(node.child[1])
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			tree = append(tree, byte(left>>16), byte(left>>8), byte(left),
+				byte(left>>24&0x0f)<<4|byte(right>>24&0x0f), byte(right>>16), byte(right>>8), byte(right))
+		default:
+			tree = binary.BigEndian.AppendUint32(tree, left)
+			tree = binary.BigEndian.AppendUint32(tree, right)
+		}
+	}
+
+	metadata := mmdbEncode(map[string]interface{}{
+		"node_count":    nodeCount,
+		"record_size":   uint16(recordSize),
+		"ip_version":    ipVersion,
+		"database_type": "BkC-Test",
+	})
+
+	buf := append(tree, make([]byte, mmdbDataSeparator)...)
+	buf = append(buf, data...)
+	buf = append(buf, mmdbMetadataMarker...)
+	return append(buf, metadata...)
+}
+
+// writeMMDB écrit une base dans un fichier temporaire et retourne son chemin
+func writeMMDB(t *testing.T, buf []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMMDBDecode(t *testing.T) {
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'a'
+	}
+	tests := []struct {
+		name  string
+		data  []byte
+		value interface{}
+		valid bool
+	}{
+		{"chaîne", []byte{0x42, 'f', 'r'}, "fr", true},
+		{"chaîne longue", mmdbEncode(string(long)), string(long), true},
+		{"uint16", []byte{0xa2, 0x01, 0x00}, uint64(256), true},
+		{"uint32 vide", []byte{0xc0}, uint64(0), true},
+		{"int32 négatif", []byte{0x01, 0x01, 0xff}, int64(-1), true},
+		{"booléen", []byte{0x01, 0x07}, true, true},
+		{"double", mmdbEncode(48.85), 48.85, true},
+		{"table", []byte{0xe1, 0x41, 'a', 0x41, 'b'}, map[string]interface{}{"a": "b"}, true},
+		{"tableau", []byte{0x02, 0x04, 0x41, 'a', 0xa0}, []interface{}{"a", uint64(0)}, true},
+		{"pointeur", []byte{0x20, 0x03, 0x00, 0x41, 'x'}, "x", true},
+		{"chaîne tronquée", []byte{0x43, 'a'}, nil, false},
+		{"clé non textuelle", []byte{0xe1, 0xc0, 0x41, 'a'}, nil, false},
+		{"pointeur en boucle", []byte{0x20, 0x00}, nil, false},
+		{"double de 4 octets", []byte{0x64, 0, 0, 0, 0}, nil, false},
+		{"table annoncée trop grande", []byte{0xfe, 0xff, 0xff}, nil, false},
+	}
+	for _, tt := range tests {
+		value, _, err := mmdbDecoder(tt.data).decode(0, 0)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s : erreur %v", tt.name, err)
+			continue
+		}
+		if tt.valid && !reflect.DeepEqual(value, tt.value) {
+			t.Errorf("%s : %#v, attendu %#v", tt.name, value, tt.value)
+		}
+	}
+}
+
+func TestMMDBLookup(t *testing.T) {
+	networks := map[string]map[string]interface{}{
+		"203.0.113.0/24": {
+			"country": map[string]interface{}{"iso_code": "FR"},
+			"city":    map[string]interface{}{"names": map[string]interface{}{"en": "Paris", "fr": "Paris"}},
+		},
+		"198.51.100.0/25": {"registered_country": map[string]interface{}{"iso_code": "de"}},
+		"192.0.2.128/25":  {"country": "US", "city": "Mountain View", "asn": "AS15169", "as_name": "Google LLC"},
+		"192.0.2.0/25":    {"autonomous_system_number": uint32(64500), "autonomous_system_organization": "Exemple Télécom"},
+		"2001:db8::/32":   {"country": map[string]interface{}{"iso_code": "NL"}},
+	}
+
+	tests := []struct {
+		ip   string
+		want GeoRecord
+	}{
+		{"203.0.113.42", GeoRecord{CountryCode: "FR", City: "Paris"}},
+		{"198.51.100.1", GeoRecord{CountryCode: "DE"}},
+		{"198.51.100.200", GeoRecord{}},
+		{"192.0.2.200", GeoRecord{CountryCode: "US", City: "Mountain View", ASN: 15169, ISP: "Google LLC"}},
+		{"192.0.2.1", GeoRecord{ASN: 64500, ISP: "Exemple Télécom"}},
+		{"::ffff:203.0.113.1", GeoRecord{CountryCode: "FR", City: "Paris"}},
+		{"2001:db8::1", GeoRecord{CountryCode: "NL"}},
+		{"2001:db9::1", GeoRecord{}},
+		{"8.8.8.8", GeoRecord{}},
+	}
+	for _, ipVersion := range []uint16{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			db, err := OpenGeoDatabase(writeMMDB(t, buildMMDB(ipVersion, recordSize, networks)))
+			if err != nil {
+				t.Fatalf("IPv%d, %d bits : %v", ipVersion, recordSize, err)
+			}
+			for _, tt := range tests {
+				ip := net.ParseIP(tt.ip)
+				if ipVersion == 4 && ip.To4() == nil {
+					continue // Absente d'une base IPv4
+				}
+				got, found := db.Lookup(ip)
+				if got != tt.want || found != (tt.want != GeoRecord{}) {
+					t.Errorf("IPv%d, %d bits, %s : %+v, %v ; attendu %+v", ipVersion, recordSize, tt.ip, got, found, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenMMDBRejectsInvalidFiles(t *testing.T) {
+	valid := buildMMDB(4, 24, map[string]map[string]interface{}{"203.0.113.0/24": {"country": "FR"}})
+	markerAt := len(valid) - len(mmdbEncode(map[string]interface{}{
+		"node_count": uint32(0), "record_size": uint16(0), "ip_version": uint16(0), "database_type": "BkC-Test",
+	})) - len(mmdbMetadataMarker)
+	withMetadata := func(meta map[string]interface{}) []byte {
+		buf := append([]byte{}, valid[:markerAt]...)
+		buf = append(buf, mmdbMetadataMarker...)
+		return append(buf, mmdbEncode(meta)...)
+	}
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"sans métadonnées", valid[:markerAt]},
+		{"métadonnées tronquées", valid[:markerAt+len(mmdbMetadataMarker)+3]},
+		{"métadonnées non tabulaires", append(append(append([]byte{}, valid[:markerAt]...), mmdbMetadataMarker...), 0x41, 'x')},
+		{"taille d'enregistrement", withMetadata(map[string]interface{}{
+			"node_count": uint32(24), "record_size": uint16(20), "ip_version": uint16(4)})},
+		{"version IP", withMetadata(map[string]interface{}{
+			"node_count": uint32(24), "record_size": uint16(24), "ip_version": uint16(5)})},
+		{"arbre plus grand que le fichier", withMetadata(map[string]interface{}{
+			"node_count": uint32(1000), "record_size": uint16(24), "ip_version": uint16(4)})},
+	}
+	for _, tt := range tests {
+		if _, err := openMMDB(writeMMDB(t, tt.buf)); err == nil {
+			t.Errorf("%s : base acceptée", tt.name)
+		}
+	}
+}
@@ -7,12 +7,81 @@ import (
 	"time"
 )
 
+// Role représente le niveau d'autorisation d'un compte
+type Role string
+
+const (
+	RoleReadOnly Role = "readonly" // Consultation uniquement
+	RoleMember   Role = "member"   // Peut miner et envoyer des messages
+	RoleAdmin    Role = "admin"    // Accès complet, y compris l'administration
+)
+
+// roleLevels ordonne les rôles du moins au plus privilégié
+var roleLevels = map[Role]int{
+	RoleReadOnly: 1,
+	RoleMember:   2,
+	RoleAdmin:    3,
+}
+
+// ParseRole convertit une chaîne en rôle connu
+func ParseRole(s string) (Role, bool) {
+	role := Role(s)
+	_, ok := roleLevels[role]
+	return role, ok
+}
+
+// Includes indique si le rôle donne au moins les droits du rôle requis
+func (r Role) Includes(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
 // User représente un utilisateur enregistré dans le système
 type User struct {
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"password_hash"` // Stocke le hash du mot de passe, pas le mot de passe lui-même
 	CreatedAt    time.Time `json:"created_at"`
 	LastLogin    time.Time `json:"last_login"`
+	Role         Role      `json:"role"`
+	Disabled     bool      `json:"disabled"`           // Compte désactivé par un administrateur
+	Email        string    `json:"email,omitempty"`    // Adresse utilisée pour la réinitialisation du mot de passe
+	Language     string    `json:"language,omitempty"` // Langue préférée de l'interface ("fr", "en")
+
+	// Double authentification (TOTP)
+	TOTPEnabled   bool     `json:"totp_enabled"`
+	TOTPSecret    string   `json:"totp_secret,omitempty"`    // Secret confirmé
+	TOTPPending   string   `json:"totp_pending,omitempty"`   // Secret en attente de confirmation
+	TOTPLastStep  int64    `json:"totp_last_step,omitempty"` // Dernière période utilisée (anti-rejeu)
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // Empreintes des codes de récupération restants
+}
+
+// ResetTwoFactor désactive la double authentification et efface ses secrets
+func (u *User) ResetTwoFactor() {
+	u.TOTPEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPPending = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = nil
+}
+
+// VerifySecondFactor vérifie un code TOTP ou, à défaut, consomme un code de récupération
+func (u *User) VerifySecondFactor(code string, now time.Time) bool {
+	if !u.TOTPEnabled {
+		return false
+	}
+	if step, ok := ValidateTOTP(u.TOTPSecret, code, now, u.TOTPLastStep); ok {
+		u.TOTPLastStep = step
+		return true
+	}
+	remaining, ok := UseRecoveryCode(u.RecoveryCodes, code)
+	if ok {
+		u.RecoveryCodes = remaining
+	}
+	return ok
+}
+
+// CheckPassword vérifie un mot de passe en clair contre le hash enregistré
+func (u *User) CheckPassword(password string) bool {
+	return u.PasswordHash == HashPassword(password)
 }
 
 // Message représente un message envoyé entre utilisateurs
@@ -31,6 +100,12 @@ func HashPassword(password string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// HashToken crée l'empreinte d'un jeton de session pour ne pas le stocker en clair
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 // CreateMessage crée un nouveau message
 func CreateMessage(sender, recipient, content string) Message {
 	contentHash := hashContent(sender, recipient, content, time.Now())
@@ -57,3 +132,47 @@ func generateMessageID(sender, recipient string, timestamp time.Time) string {
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:])[:16] // Utiliser seulement les 16 premiers caractères pour l'ID
 }
+
+// TokenScope représente une permission accordée à un jeton d'API
+type TokenScope string
+
+const (
+	ScopeReadChain    TokenScope = "chain:read"    // Lecture de la blockchain et des statistiques
+	ScopeSendMessages TokenScope = "messages:send" // Envoi de messages
+	ScopeMine         TokenScope = "mine"          // Minage de blocs
+	ScopeWebhooks     TokenScope = "webhooks"      // Gestion des webhooks
+)
+
+// AllScopes liste les portées connues
+var AllScopes = []TokenScope{ScopeReadChain, ScopeSendMessages, ScopeMine, ScopeWebhooks}
+
+// ParseScope convertit une chaîne en portée connue
+func ParseScope(s string) (TokenScope, bool) {
+	for _, scope := range AllScopes {
+		if string(scope) == s {
+			return scope, true
+		}
+	}
+	return "", false
+}
+
+// APIToken représente un jeton d'accès personnel pour les scripts et intégrations
+type APIToken struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Owner     string       `json:"owner"`
+	TokenHash string       `json:"token_hash"` // Empreinte du jeton, jamais le jeton en clair
+	Scopes    []TokenScope `json:"scopes"`
+	CreatedAt time.Time    `json:"created_at"`
+	LastUsed  time.Time    `json:"last_used"`
+}
+
+// HasScope indique si le jeton dispose de la portée demandée
+func (t *APIToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Notifier envoie une notification (ex: lien de réinitialisation) à un destinataire
+type Notifier interface {
+	Notify(to, subject, body string) error
+}
+
+// LogNotifier écrit les notifications dans le journal du serveur (développement)
+type LogNotifier struct{}
+
+// Notify écrit la notification dans les logs au lieu de l'envoyer
+func (LogNotifier) Notify(to, subject, body string) error {
+	if to == "" {
+		to = "(aucune adresse)"
+	}
+	slog.Info("Notification", "to", to, "subject", subject, "body", body)
+	return nil
+}
+
+// SMTPNotifier envoie les notifications par e-mail via un serveur SMTP
+type SMTPNotifier struct {
+	Addr     string // Adresse du serveur (hôte:port)
+	From     string // Adresse de l'expéditeur
+	Username string // Identifiant SMTP (optionnel)
+	Password string // Mot de passe SMTP (optionnel)
+}
+
+// NewSMTPNotifier crée un notifier SMTP et vérifie sa configuration
+func NewSMTPNotifier(addr, from, username, password string) (*SMTPNotifier, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("adresse SMTP invalide %q: %v", addr, err)
+	}
+	if !strings.Contains(from, "@") {
+		return nil, fmt.Errorf("adresse d'expéditeur invalide %q", from)
+	}
+	return &SMTPNotifier{Addr: addr, From: from, Username: username, Password: password}, nil
+}
+
+// Notify envoie un e-mail texte au destinataire
+func (n *SMTPNotifier) Notify(to, subject, body string) error {
+	if to == "" {
+		return fmt.Errorf("aucune adresse e-mail pour le destinataire")
+	}
+	// Refuser les retours à la ligne pour empêcher l'injection d'en-têtes
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("en-tête d'e-mail invalide")
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, _ := net.SplitHostPort(n.Addr)
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + n.From,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		strings.ReplaceAll(body, "\n", "\r\n"),
+	}, "\r\n")
+
+	return smtp.SendMail(n.Addr, auth, n.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"mime"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpSession est ce qu'a reçu le faux serveur SMTP
+type smtpSession struct {
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTP démarre un serveur SMTP minimal (sans STARTTLS ni AUTH) qui accepte un message
+func fakeSMTP(t *testing.T) (string, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var session smtpSession
+
+		tp.PrintfLine("220 localhost ESMTP test")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250-localhost")
+				tp.PrintfLine("250 8BITMIME")
+			case "MAIL":
+				session.from = arg
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				session.rcpt = append(session.rcpt, arg)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 Envoyez le message")
+				lines, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				session.data = strings.Join(lines, "\n")
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 Au revoir")
+				received <- session
+				return
+			default:
+				tp.PrintfLine("502 Commande inconnue")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSMTPNotifierSendsResetLink(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	notifier, err := NewSMTPNotifier(addr, "bkc@exemple.fr", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := "https://bkc.exemple.fr/reset-password?token=abc123"
+	body := "Bonjour alice,\n\nPour choisir un nouveau mot de passe : " + link + "\n"
+	if err := notifier.Notify("alice@exemple.fr", "Réinitialisation du mot de passe", body); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	session := <-received
+
+	if !strings.HasPrefix(session.from, "FROM:<bkc@exemple.fr>") {
+		t.Errorf("MAIL FROM = %q", session.from)
+	}
+	if len(session.rcpt) != 1 || session.rcpt[0] != "TO:<alice@exemple.fr>" {
+		t.Errorf("RCPT TO = %q", session.rcpt)
+	}
+
+	header, content, found := strings.Cut(session.data, "\n\n")
+	if !found {
+		t.Fatalf("message sans séparation en-têtes / corps: %q", session.data)
+	}
+	headers := make(map[string]string)
+	for _, line := range strings.Split(header, "\n") {
+		name, value, _ := strings.Cut(line, ": ")
+		headers[name] = value
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(headers["Subject"])
+	if err != nil {
+		t.Fatalf("sujet illisible %q: %v", headers["Subject"], err)
+	}
+	for name, want := range map[string]string{
+		"From":         "bkc@exemple.fr",
+		"To":           "alice@exemple.fr",
+		"MIME-Version": "1.0",
+		"Content-Type": "text/plain; charset=UTF-8",
+	} {
+		if headers[name] != want {
+			t.Errorf("en-tête %s = %q, attendu %q", name, headers[name], want)
+		}
+	}
+	if subject != "Réinitialisation du mot de passe" {
+		t.Errorf("sujet = %q", subject)
+	}
+	if headers["Date"] == "" {
+		t.Error("en-tête Date absent")
+	}
+	if !strings.Contains(content, link) {
+		t.Errorf("lien de réinitialisation absent du corps: %q", content)
+	}
+}
+
+func TestSMTPNotifierRejectsInvalidMessages(t *testing.T) {
+	// Aucun serveur n'écoute : une tentative d'envoi échouerait d'une autre façon
+	notifier := &SMTPNotifier{Addr: "127.0.0.1:1", From: "bkc@exemple.fr"}
+	tests := []struct {
+		name, to, subject string
+	}{
+		{"destinataire absent", "", "Sujet"},
+		{"injection dans le destinataire", "alice@exemple.fr\r\nBcc: mallory@exemple.fr", "Sujet"},
+		{"injection dans le sujet", "alice@exemple.fr", "Sujet\nBcc: mallory@exemple.fr"},
+	}
+	for _, tt := range tests {
+		err := notifier.Notify(tt.to, tt.subject, "corps")
+		if err == nil || strings.Contains(err.Error(), "connect") {
+			t.Errorf("%s : erreur inattendue %v", tt.name, err)
+		}
+	}
+}
+
+func TestNewSMTPNotifier(t *testing.T) {
+	tests := []struct {
+		addr, from string
+		valid      bool
+	}{
+		{"smtp.exemple.fr:587", "bkc@exemple.fr", true},
+		{"smtp.exemple.fr", "bkc@exemple.fr", false},
+		{"smtp.exemple.fr:25", "bkc", false},
+	}
+	for _, tt := range tests {
+		if _, err := NewSMTPNotifier(tt.addr, tt.from, "", ""); (err == nil) != tt.valid {
+			t.Errorf("NewSMTPNotifier(%q, %q) : erreur %v", tt.addr, tt.from, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Règles appliquées aux noms d'utilisateur et aux messages
+const (
+	UsernameMinLength = 3    // Longueur minimale d'un nom d'utilisateur
+	UsernameMaxLength = 32   // Longueur maximale d'un nom d'utilisateur
+	MessageMaxLength  = 1000 // Nombre maximal de caractères d'un message
+)
+
+// usernamePattern limite les noms aux lettres ASCII, chiffres, '.', '_' et '-',
+// en commençant par une lettre ou un chiffre
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// anonymousPattern correspond aux noms attribués aux visiteurs anonymes (ex: "Visiteur-1")
+var anonymousPattern = regexp.MustCompile(`(?i)^visiteur-[0-9]+$`)
+
+// reservedUsernames ne peuvent pas être choisis à l'inscription (comparaison insensible à la casse)
+var reservedUsernames = map[string]bool{
+	"admin":          true,
+	"administrator":  true,
+	"administrateur": true,
+	"root":           true,
+	"system":         true,
+	"systeme":        true,
+	"anonymous":      true,
+	"anonyme":        true,
+	"visiteur":       true,
+	"genesis":        true,
+	"bkc":            true,
+}
+
+// ValidationError décrit une donnée refusée par la politique de validation
+type ValidationError struct {
+	Field   string        `json:"field"`   // Champ concerné
+	Code    string        `json:"code"`    // Code stable, utilisable par les clients
+	Message string        `json:"message"` // Message lisible
+	Args    []interface{} `json:"-"`       // Paramètres du message, pour sa traduction
+}
+
+// Error implémente l'interface error
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidateUsername vérifie la longueur, les caractères et les noms réservés
+func ValidateUsername(username string) *ValidationError {
+	length := utf8.RuneCountInString(username)
+	switch {
+	case strings.TrimSpace(username) == "":
+		return &ValidationError{Field: "username", Code: "required", Message: "Le nom d'utilisateur est obligatoire"}
+	case length < UsernameMinLength:
+		return &ValidationError{Field: "username", Code: "too_short", Message: fmt.Sprintf("Le nom d'utilisateur doit contenir au moins %d caractères", UsernameMinLength), Args: []interface{}{UsernameMinLength}}
+	case length > UsernameMaxLength:
+		return &ValidationError{Field: "username", Code: "too_long", Message: fmt.Sprintf("Le nom d'utilisateur doit contenir au plus %d caractères", UsernameMaxLength), Args: []interface{}{UsernameMaxLength}}
+	case !usernamePattern.MatchString(username):
+		return &ValidationError{Field: "username", Code: "invalid_chars", Message: "Le nom d'utilisateur ne peut contenir que des lettres, chiffres, '.', '_' et '-' et doit commencer par une lettre ou un chiffre"}
+	case reservedUsernames[strings.ToLower(username)] || anonymousPattern.MatchString(username):
+		return &ValidationError{Field: "username", Code: "reserved", Message: "Ce nom d'utilisateur est réservé"}
+	}
+	return nil
+}
+
+// ValidateMessageContent vérifie qu'un message n'est pas vide et ne dépasse pas la taille maximale
+func ValidateMessageContent(content string) *ValidationError {
+	if !utf8.ValidString(content) {
+		return &ValidationError{Field: "content", Code: "invalid_encoding", Message: "Le message doit être encodé en UTF-8"}
+	}
+	if strings.TrimSpace(content) == "" {
+		return &ValidationError{Field: "content", Code: "required", Message: "Le message est vide"}
+	}
+	if utf8.RuneCountInString(content) > MessageMaxLength {
+		return &ValidationError{Field: "content", Code: "too_long", Message: fmt.Sprintf("Le message dépasse %d caractères", MessageMaxLength), Args: []interface{}{MessageMaxLength}}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ForwardedHeaders sont les en-têtes de transfert pris en charge, l'un d'eux étant choisi par
+// SetForwardedHeader
+var ForwardedHeaders = []string{"X-Forwarded-For", "Forwarded", "X-Real-IP", "CF-Connecting-IP", "True-Client-IP"}
+
+var (
+	trustedProxies   []*net.IPNet // Réseaux des proxies dont les en-têtes de transfert sont crus
+	forwardedHeader  = "X-Forwarded-For"
+	trustedProxiesMu sync.RWMutex
+)
+
+// SetTrustedProxies configure les proxies de confiance à partir de CIDR ou d'adresses seules
+// (ex: "10.0.0.0/8", "127.0.0.1", "::1"). Une liste vide désactive la lecture des en-têtes.
+func SetTrustedProxies(entries []string) error {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("adresse de proxy invalide: %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("CIDR de proxy invalide: %q", entry)
+		}
+		networks = append(networks, network)
+	}
+
+	trustedProxiesMu.Lock()
+	trustedProxies = networks
+	trustedProxiesMu.Unlock()
+	return nil
+}
+
+// SetForwardedHeader choisit le seul en-tête de transfert lu pour les requêtes des proxies de
+// confiance : celui que les proxies écrivent. Les autres en-têtes ont pu être envoyés par le client
+// et transmis tels quels par le proxy ; ils sont ignorés.
+func SetForwardedHeader(name string) error {
+	canonical := http.CanonicalHeaderKey(strings.TrimSpace(name))
+	for _, header := range ForwardedHeaders {
+		if http.CanonicalHeaderKey(header) == canonical {
+			trustedProxiesMu.Lock()
+			forwardedHeader = header
+			trustedProxiesMu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("en-tête de transfert non pris en charge: %q (%s)", name, strings.Join(ForwardedHeaders, ", "))
+}
+
+// trustedForwardedHeader retourne l'en-tête de transfert choisi par SetForwardedHeader
+func trustedForwardedHeader() string {
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	return forwardedHeader
+}
+
+// isTrustedProxy indique si une adresse appartient à un proxy de confiance
+func isTrustedProxy(ip net.IP) bool {
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHostIP analyse une adresse avec ou sans port, IPv4 ou IPv6
+// ("1.2.3.4", "1.2.3.4:80", "2001:db8::1", "[2001:db8::1]:443", "fe80::1%eth0")
+func parseHostIP(value string) net.IP {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if value == "" {
+		return nil
+	}
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	} else {
+		// IPv6 entre crochets sans port
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	}
+
+	// Retirer l'éventuelle zone IPv6 (fe80::1%eth0)
+	if i := strings.IndexByte(value, '%'); i >= 0 {
+		value = value[:i]
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	// Normaliser les adresses IPv4 encapsulées dans IPv6 (::ffff:1.2.3.4)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// forwardedChain extrait les valeurs "for=" des en-têtes Forwarded (RFC 7239), dans l'ordre
+func forwardedChain(headers []string) []string {
+	var chain []string
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					chain = append(chain, value)
+				}
+			}
+		}
+	}
+	return chain
+}
+
+// xForwardedForChain extrait les adresses des en-têtes X-Forwarded-For, dans l'ordre
+func xForwardedForChain(headers []string) []string {
+	var chain []string
+	for _, header := range headers {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				chain = append(chain, hop)
+			}
+		}
+	}
+	return chain
+}
+
+// clientFromChain parcourt la chaîne de droite à gauche et retourne le premier saut
+// qui n'est pas un proxy de confiance. Les sauts situés à gauche ont pu être
+// fabriqués par le client et sont ignorés.
+func clientFromChain(chain []string, remoteIP net.IP) net.IP {
+	client := remoteIP
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseHostIP(chain[i])
+		if ip == nil {
+			// Identifiant obfusqué ou invalide : on s'arrête au dernier proxy connu
+			return client
+		}
+		client = ip
+		if !isTrustedProxy(ip) {
+			return ip
+		}
+	}
+	return client
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// withTrustedProxies configure les proxies de confiance le temps d'un test
+func withTrustedProxies(t *testing.T, entries ...string) {
+	t.Helper()
+	if err := SetTrustedProxies(entries); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	tests := []struct {
+		entries []string
+		valid   bool
+	}{
+		{[]string{"10.0.0.0/8", "127.0.0.1", "::1", " 2001:db8::/32 ", ""}, true},
+		{nil, true},
+		{[]string{"10.0.0.300"}, false},
+		{[]string{"10.0.0.0/33"}, false},
+		{[]string{"proxy.local"}, false},
+	}
+	for _, tt := range tests {
+		if err := SetTrustedProxies(tt.entries); (err == nil) != tt.valid {
+			t.Errorf("SetTrustedProxies(%q) : erreur %v", tt.entries, err)
+		}
+	}
+	SetTrustedProxies(nil)
+}
+
+func TestParseHostIP(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"1.2.3.4", "1.2.3.4"},
+		{"1.2.3.4:80", "1.2.3.4"},
+		{`"1.2.3.4"`, "1.2.3.4"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{`"[2001:db8::1]:443"`, "2001:db8::1"},
+		{"fe80::1%eth0", "fe80::1"},
+		{"::ffff:1.2.3.4", "1.2.3.4"},
+		{"unknown", ""},
+		{"_hidden", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if ip := parseHostIP(tt.value); ip != nil {
+			got = ip.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseHostIP(%q) = %q, attendu %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSetForwardedHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"X-Forwarded-For", true},
+		{"forwarded", true},
+		{" x-real-ip ", true},
+		{"CF-Connecting-IP", true},
+		{"True-Client-IP", true},
+		{"X-Client-IP", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := SetForwardedHeader(tt.name); (err == nil) != tt.valid {
+			t.Errorf("SetForwardedHeader(%q) : erreur %v", tt.name, err)
+		}
+	}
+	SetForwardedHeader("X-Forwarded-For")
+}
+
+func TestGetVisitorIP(t *testing.T) {
+	withTrustedProxies(t, "10.0.0.0/8", "::1")
+	t.Cleanup(func() { SetForwardedHeader("X-Forwarded-For") })
+
+	// Un client placé derrière le proxy envoie lui-même les deux en-têtes de transfert
+	spoofed := map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "1.2.3.4"}
+
+	tests := []struct {
+		name       string
+		proxy      string // En-tête écrit par les proxies de confiance
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"client direct", "X-Forwarded-For", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"client direct avec en-têtes forgés", "X-Forwarded-For", "203.0.113.7:5000",
+			map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "1.1.1.1"}, "203.0.113.7"},
+		{"client direct avec Forwarded et X-Forwarded-For forgés", "X-Forwarded-For", "203.0.113.7:5000",
+			spoofed, "203.0.113.7"},
+		{"client direct avec Forwarded et X-Forwarded-For forgés (Forwarded)", "Forwarded", "203.0.113.7:5000",
+			spoofed, "203.0.113.7"},
+		{"proxy sans en-tête", "X-Forwarded-For", "10.0.0.2:5000", nil, "10.0.0.2"},
+		{"X-Forwarded-For", "X-Forwarded-For", "10.0.0.2:5000",
+			map[string]string{"X-Forwarded-For": "198.51.100.4"}, "198.51.100.4"},
+		{"saut forgé à gauche ignoré", "X-Forwarded-For", "10.0.0.2:5000",
+			map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.4, 10.0.0.3"}, "198.51.100.4"},
+		{"Forwarded du client ignoré derrière un proxy X-Forwarded-For", "X-Forwarded-For", "10.0.0.2:5000",
+			map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "198.51.100.4"}, "198.51.100.4"},
+		{"X-Forwarded-For du client ignoré derrière un proxy Forwarded", "Forwarded", "10.0.0.2:5000",
+			map[string]string{"Forwarded": `for=192.0.2.60;proto=https, for="[2001:db8::7]:4711"`,
+				"X-Forwarded-For": "1.2.3.4"}, "2001:db8::7"},
+		{"Forwarded absent", "Forwarded", "10.0.0.2:5000",
+			map[string]string{"X-Forwarded-For": "1.2.3.4"}, "10.0.0.2"},
+		{"identifiant obfusqué", "Forwarded", "10.0.0.2:5000",
+			map[string]string{"Forwarded": "for=192.0.2.60, for=_hidden"}, "10.0.0.2"},
+		{"X-Real-IP", "X-Real-IP", "[::1]:5000",
+			map[string]string{"X-Real-IP": "198.51.100.9", "X-Forwarded-For": "1.2.3.4"}, "198.51.100.9"},
+		{"X-Real-IP non lu", "X-Forwarded-For", "[::1]:5000",
+			map[string]string{"X-Real-IP": "1.2.3.4"}, "::1"},
+		{"CF-Connecting-IP", "CF-Connecting-IP", "10.0.0.2:5000",
+			map[string]string{"CF-Connecting-IP": "2001:db8::9"}, "2001:db8::9"},
+	}
+	for _, tt := range tests {
+		if err := SetForwardedHeader(tt.proxy); err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for name, value := range tt.headers {
+			r.Header.Set(name, value)
+		}
+		if got := GetVisitorIP(r); got != tt.want {
+			t.Errorf("%s : GetVisitorIP = %q, attendu %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// limiterSweepInterval est l'intervalle minimal entre deux balayages des clés expirées d'un limiteur.
+// Sans balayage, une clé qui ne revient jamais (IP ou nom d'utilisateur de passage) resterait en mémoire.
+const limiterSweepInterval = time.Minute
+
+// AttemptLimiter suit les tentatives échouées par clé (compte, IP...) et applique
+// une temporisation exponentielle, puis un verrouillage temporaire.
+type AttemptLimiter struct {
+	mu              sync.Mutex
+	entries         map[string]*attemptEntry
+	freeAttempts    int           // Échecs tolérés avant la première temporisation
+	baseDelay       time.Duration // Délai après le premier échec au-delà des échecs tolérés
+	maxDelay        time.Duration // Délai maximal de la temporisation exponentielle
+	lockoutAfter    int           // Nombre d'échecs déclenchant le verrouillage
+	lockoutDuration time.Duration // Durée du verrouillage
+	forgetAfter     time.Duration // Les échecs sont oubliés après cette période sans tentative
+	lastSweep       time.Time     // Dernier balayage des clés expirées
+}
+
+type attemptEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	locked       bool
+}
+
+// Lockout décrit une clé actuellement bloquée, pour l'affichage dans l'administration
+type Lockout struct {
+	Key          string    `json:"key"`
+	Failures     int       `json:"failures"`
+	BlockedUntil time.Time `json:"blockedUntil"`
+	Locked       bool      `json:"locked"` // true pour un verrouillage, false pour une simple temporisation
+}
+
+// NewAttemptLimiter crée un limiteur de tentatives
+func NewAttemptLimiter(freeAttempts int, baseDelay, maxDelay time.Duration, lockoutAfter int, lockoutDuration, forgetAfter time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		entries:         make(map[string]*attemptEntry),
+		freeAttempts:    freeAttempts,
+		baseDelay:       baseDelay,
+		maxDelay:        maxDelay,
+		lockoutAfter:    lockoutAfter,
+		lockoutDuration: lockoutDuration,
+		forgetAfter:     forgetAfter,
+	}
+}
+
+// entry retourne l'entrée d'une clé en oubliant les échecs trop anciens (mu doit être détenu)
+func (l *AttemptLimiter) entry(key string, now time.Time) *attemptEntry {
+	e, exists := l.entries[key]
+	if !exists {
+		return nil
+	}
+	if now.After(e.blockedUntil) && now.Sub(e.lastFailure) > l.forgetAfter {
+		delete(l.entries, key)
+		return nil
+	}
+	return e
+}
+
+// sweep supprime les clés dont les échecs sont oubliés, au plus une fois par limiterSweepInterval
+// (mu doit être détenu)
+func (l *AttemptLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key := range l.entries {
+		l.entry(key, now)
+	}
+}
+
+// Allow indique si une tentative est autorisée pour la clé, et sinon le temps d'attente restant
+func (l *AttemptLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	e := l.entry(key, now)
+	if e == nil || !now.Before(e.blockedUntil) {
+		return true, 0
+	}
+	return false, e.blockedUntil.Sub(now)
+}
+
+// Fail enregistre un échec et retourne la durée de blocage appliquée
+func (l *AttemptLimiter) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+	e := l.entry(key, now)
+	if e == nil {
+		e = &attemptEntry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	var delay time.Duration
+	switch {
+	case e.failures >= l.lockoutAfter:
+		delay = l.lockoutDuration
+		e.locked = true
+	case e.failures > l.freeAttempts:
+		// Doublement du délai à chaque échec supplémentaire
+		delay = l.baseDelay
+		for i := l.freeAttempts + 1; i < e.failures && delay < l.maxDelay; i++ {
+			delay *= 2
+		}
+		if delay > l.maxDelay {
+			delay = l.maxDelay
+		}
+	}
+
+	if delay > 0 {
+		e.blockedUntil = now.Add(delay)
+	}
+	return delay
+}
+
+// Reset oublie les échecs d'une clé (après une connexion réussie ou un déblocage manuel)
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// Lockouts retourne les clés actuellement bloquées
+func (l *AttemptLimiter) Lockouts() []Lockout {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	lockouts := make([]Lockout, 0)
+	for key := range l.entries {
+		e := l.entry(key, now)
+		if e == nil || !now.Before(e.blockedUntil) {
+			continue
+		}
+		lockouts = append(lockouts, Lockout{
+			Key:          key,
+			Failures:     e.failures,
+			BlockedUntil: e.blockedUntil,
+			Locked:       e.locked,
+		})
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].BlockedUntil.After(lockouts[j].BlockedUntil)
+	})
+	return lockouts
+}
+
+// RateLimiter limite le nombre d'actions par clé sur une fenêtre glissante
+type RateLimiter struct {
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	limit     int
+	window    time.Duration
+	lastSweep time.Time // Dernier balayage des clés expirées
+}
+
+// NewRateLimiter crée un limiteur autorisant limit actions par fenêtre
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		events: make(map[string][]time.Time),
+		limit:  limit,
+		window: window,
+	}
+}
+
+// sweep supprime les clés dont toutes les actions sont sorties de la fenêtre, au plus une fois par
+// limiterSweepInterval (mu doit être détenu)
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, events := range l.events {
+		if len(events) == 0 || now.Sub(events[len(events)-1]) >= l.window {
+			delete(l.events, key)
+		}
+	}
+}
+
+// Allow enregistre une action si elle est autorisée, sinon retourne le temps d'attente
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+	recent := l.events[key][:0]
+	for _, t := range l.events[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.events[key] = recent
+		return false, l.window - now.Sub(recent[0])
+	}
+
+	l.events[key] = append(recent, now)
+	return true, 0
+}
+
+// Reset oublie les actions d'une clé (déblocage manuel)
+func (l *RateLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.events, key)
+}
+
+// Lockouts retourne les clés ayant atteint la limite
+func (l *RateLimiter) Lockouts() []Lockout {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	lockouts := make([]Lockout, 0)
+	for key, events := range l.events {
+		count := 0
+		for _, t := range events {
+			if now.Sub(t) < l.window {
+				count++
+			}
+		}
+		if count == 0 {
+			delete(l.events, key)
+			continue
+		}
+		if count >= l.limit {
+			lockouts = append(lockouts, Lockout{
+				Key:          key,
+				Failures:     count,
+				BlockedUntil: events[len(events)-count].Add(l.window),
+			})
+		}
+	}
+	return lockouts
+}
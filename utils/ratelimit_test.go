@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiterSweepsForgottenKeys(t *testing.T) {
+	l := NewAttemptLimiter(3, time.Second, time.Minute, 10, 15*time.Minute, time.Hour)
+	for i := 0; i < 100; i++ {
+		l.Fail(fmt.Sprintf("10.0.0.%d", i))
+	}
+	l.Fail("actif")
+	for i := 0; i < 10; i++ {
+		l.Fail("verrouillé")
+	}
+
+	// Échecs oubliés pour les clés de passage ; le verrouillage en cours est conservé
+	past := time.Now().Add(-2 * time.Hour)
+	l.mu.Lock()
+	for key, e := range l.entries {
+		switch key {
+		case "actif":
+		case "verrouillé":
+			e.lastFailure = past
+		default:
+			e.lastFailure, e.blockedUntil = past, past
+		}
+	}
+	l.lastSweep = past
+	l.mu.Unlock()
+
+	l.Fail("nouveau")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) != 3 {
+		t.Errorf("%d clés après le balayage, attendu 3 (actif, verrouillé, nouveau)", len(l.entries))
+	}
+	if _, kept := l.entries["verrouillé"]; !kept {
+		t.Error("clé verrouillée supprimée par le balayage")
+	}
+}
+
+func TestRateLimiterSweepsExpiredKeys(t *testing.T) {
+	l := NewRateLimiter(3, time.Hour)
+	for i := 0; i < 100; i++ {
+		l.Allow(fmt.Sprintf("10.0.0.%d", i))
+	}
+	l.Allow("récent")
+
+	past := time.Now().Add(-2 * time.Hour)
+	l.mu.Lock()
+	for key := range l.events {
+		if key != "récent" {
+			l.events[key] = []time.Time{past}
+		}
+	}
+	l.lastSweep = past
+	l.mu.Unlock()
+
+	l.Allow("nouveau")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) != 2 {
+		t.Errorf("%d clés après le balayage, attendu 2 (récent, nouveau)", len(l.events))
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(3, time.Hour)
+	tests := []struct {
+		key     string
+		allowed bool
+	}{
+		{"a", true}, {"a", true}, {"b", true}, {"a", true}, {"a", false}, {"b", true},
+	}
+	for i, tt := range tests {
+		allowed, wait := l.Allow(tt.key)
+		if allowed != tt.allowed || (!allowed && wait <= 0) {
+			t.Errorf("appel %d (%s) : %v, attente %v", i, tt.key, allowed, wait)
+		}
+	}
+	if lockouts := l.Lockouts(); len(lockouts) != 1 || lockouts[0].Key != "a" {
+		t.Errorf("blocages : %+v", lockouts)
+	}
+
+	l.Reset("a")
+	if allowed, _ := l.Allow("a"); !allowed {
+		t.Error("clé toujours bloquée après Reset")
+	}
+}
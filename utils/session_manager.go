@@ -3,9 +3,8 @@ package utils
 import (
 	"BkC/blockchain"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 )
@@ -14,38 +13,43 @@ import (
 var sessionMutex sync.Mutex
 
 // GetVisitorIP extrait l'adresse IP du visiteur, en prenant en compte les proxies.
+// Seul l'en-tête de transfert écrit par les proxies (voir SetForwardedHeader) est lu, et
+// seulement si la connexion provient d'un proxy de confiance (voir SetTrustedProxies) ;
+// une chaîne est alors parcourue de droite à gauche jusqu'au premier saut qui n'est pas
+// un proxy de confiance.
 func GetVisitorIP(r *http.Request) string {
-	// Vérifier d'abord les en-têtes communs pour les proxies
-	ipHeaders := []string{
-		"X-Forwarded-For",
-		"X-Real-IP",
-		"CF-Connecting-IP", // Cloudflare
-		"True-Client-IP",   // Akamai
+	remoteIP := parseHostIP(r.RemoteAddr)
+	if remoteIP == nil {
+		return r.RemoteAddr
 	}
 
-	for _, header := range ipHeaders {
-		if ip := r.Header.Get(header); ip != "" {
-			// X-Forwarded-For peut contenir plusieurs IPs (client, proxy1, proxy2...)
-			// Nous voulons la première (celle du client)
-			if strings.Contains(ip, ",") {
-				return strings.TrimSpace(strings.Split(ip, ",")[0])
-			}
-			return strings.TrimSpace(ip)
-		}
+	// Un client direct ne peut pas imposer son adresse via les en-têtes
+	if !isTrustedProxy(remoteIP) {
+		return remoteIP.String()
 	}
 
-	// Si aucun en-tête n'est trouvé, utiliser l'adresse distante
-	remoteAddr := r.RemoteAddr
-	if strings.ContainsRune(remoteAddr, ':') {
-		// IPv4 ou IPv6 avec port, enlever le port
-		return remoteAddr[:strings.LastIndex(remoteAddr, ":")]
+	var chain []string
+	switch header := trustedForwardedHeader(); header {
+	case "Forwarded": // RFC 7239
+		chain = forwardedChain(r.Header.Values(header))
+	case "X-Forwarded-For":
+		chain = xForwardedForChain(r.Header.Values(header))
+	default:
+		// En-têtes à valeur unique remplacés par le proxy (X-Real-IP, Cloudflare, Akamai)
+		if ip := parseHostIP(r.Header.Get(header)); ip != nil {
+			return ip.String()
+		}
+	}
+	if len(chain) > 0 {
+		return clientFromChain(chain, remoteIP).String()
 	}
 
-	return remoteAddr
+	return remoteIP.String()
 }
 
-// TrackVisitor suit les connexions et déconnexions d'un visiteur
-func TrackVisitor(clientIP string, isConnected bool, sessions map[string]*UserSession, bc *blockchain.Blockchain) {
+// TrackVisitor suit les connexions et déconnexions d'un visiteur, enregistrées dans des blocs
+// minés à la difficulté indiquée
+func TrackVisitor(clientIP string, isConnected bool, sessions map[string]*UserSession, bc *blockchain.Blockchain, difficulty int) {
 	now := time.Now()
 	sessionMutex.Lock()
 	defer sessionMutex.Unlock()
@@ -55,7 +59,7 @@ func TrackVisitor(clientIP string, isConnected bool, sessions map[string]*UserSe
 		if _, exists := sessions[clientIP]; !exists {
 			// Enregistrer la connexion dans la blockchain
 			connectionData := fmt.Sprintf("Connexion de %s à %v", clientIP, now)
-			bc.AddBlockAsync(connectionData, 2) // Difficulté plus faible pour connexions
+			bc.AddBlockAsync(connectionData, difficulty)
 		}
 	} else {
 		// Utilisateur déconnecté
@@ -65,14 +69,15 @@ func TrackVisitor(clientIP string, isConnected bool, sessions map[string]*UserSe
 			if sessionDuration.Minutes() > 1 { // Éviter les déconnexions trop rapides
 				disconnectData := fmt.Sprintf("Déconnexion de %s après %v minutes",
 					clientIP, int(sessionDuration.Minutes()))
-				bc.AddBlockAsync(disconnectData, 2)
+				bc.AddBlockAsync(disconnectData, difficulty)
 			}
 		}
 	}
 }
 
 // ManageSession gère la logique de session en fonction de l'IP du client.
-func ManageSession(clientIP string, sessions map[string]*UserSession, bc *blockchain.Blockchain) {
+// Les sessions prolongées sont enregistrées dans des blocs minés à la difficulté indiquée.
+func ManageSession(clientIP string, sessions map[string]*UserSession, bc *blockchain.Blockchain, difficulty int) {
 	now := time.Now()
 	sessionMutex.Lock()
 	defer sessionMutex.Unlock()
@@ -81,16 +86,17 @@ func ManageSession(clientIP string, sessions map[string]*UserSession, bc *blockc
 
 	if !exists {
 		sessions[clientIP] = &UserSession{
-			IP:        clientIP,
-			StartTime: now,
-			LastSeen:  now,
+			IP:          clientIP,
+			NetworkInfo: NewNetworkInfo(clientIP),
+			StartTime:   now,
+			LastSeen:    now,
 		}
 		// Enregistrer la visite (mais pas comme bloc pour éviter de surcharger)
-		log.Printf("📡 Nouvelle visite de %s", clientIP)
+		slog.Debug("Nouvelle visite", "ip", clientIP)
 	} else {
 		if now.Sub(session.LastSeen) >= 5*time.Minute {
 			sessionData := fmt.Sprintf("Session de %s démarrée à %v", clientIP, session.StartTime)
-			bc.AddBlock(sessionData, 4)
+			bc.AddBlock(sessionData, difficulty)
 			session.StartTime = now
 		}
 		session.LastSeen = now
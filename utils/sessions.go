@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"net"
+	"strings"
 	"time"
 )
 
@@ -15,6 +17,33 @@ const (
 	StatusBusy
 )
 
+// statusNames associe chaque statut à son nom dans l'API et les messages WebSocket
+var statusNames = map[UserStatus]string{
+	StatusOffline: "offline",
+	StatusOnline:  "online",
+	StatusAway:    "away",
+	StatusBusy:    "busy",
+}
+
+// String retourne le nom du statut ("offline", "online", "away", "busy")
+func (s UserStatus) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return "offline"
+}
+
+// ParseUserStatus convertit un nom de statut en UserStatus
+func ParseUserStatus(name string) (UserStatus, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for status, statusName := range statusNames {
+		if statusName == name {
+			return status, nil
+		}
+	}
+	return StatusOffline, fmt.Errorf("statut inconnu: %q", name)
+}
+
 // UserSession définit la structure d'une session utilisateur.
 type UserSession struct {
 	Username       string
@@ -23,10 +52,20 @@ type UserSession struct {
 	StartTime      time.Time
 	LastSeen       time.Time
 	Status         UserStatus
-	IsRegistered   bool           // S'il s'agit d'un utilisateur enregistré ou juste un visiteur
-	UserAgent      string         // Agent utilisateur du navigateur
-	Visits         int            // Nombre de visites
-	MiningActivity map[string]int // Suivi de l'activité de minage ("blocksMinés", "dernierMinage", etc.)
+	IsRegistered   bool                      // S'il s'agit d'un utilisateur enregistré ou juste un visiteur
+	UserAgent      string                    // Agent utilisateur du navigateur
+	Visits         int                       // Nombre de visites
+	MiningActivity map[string]int            // Suivi de l'activité de minage ("blocksMinés", "dernierMinage", etc.)
+	Devices        map[string]*DeviceSession // Connexions authentifiées, indexées par l'empreinte du jeton
+}
+
+// DeviceSession représente une connexion authentifiée depuis un navigateur ou un appareil
+type DeviceSession struct {
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	CSRFToken string // Jeton anti-CSRF propre à la connexion
 }
 
 // NetworkInfo contient des informations détaillées sur la connexion réseau
@@ -34,10 +73,14 @@ type NetworkInfo struct {
 	RawIP       string // Adresse IP complète
 	IsIPv6      bool   // Si l'adresse est IPv6
 	ISP         string // Fournisseur d'accès Internet (déterminé si possible)
+	ASN         uint32 // Numéro de système autonome (0 si inconnu)
 	CountryCode string // Code pays (déterminé si possible)
 	City        string // Ville (déterminé si possible)
 }
 
+// UnknownCountry est le code pays des adresses absentes des bases de géolocalisation
+const UnknownCountry = "??"
+
 // NewNetworkInfo crée une structure NetworkInfo à partir d'une adresse IP
 func NewNetworkInfo(ipStr string) *NetworkInfo {
 	info := &NetworkInfo{
@@ -53,18 +96,33 @@ func NewNetworkInfo(ipStr string) *NetworkInfo {
 	// Détecter si c'est IPv6
 	info.IsIPv6 = ip.To4() == nil
 
-	// Dans une implémentation réelle, on pourrait utiliser une API de géolocalisation
-	// pour déterminer l'ISP, le pays et la ville
-
-	// Par défaut, nous allons simplement vérifier si c'est une adresse locale
-	if ip.IsLoopback() || ip.IsPrivate() {
-		info.ISP = "Réseau local"
-		info.CountryCode = "FR" // Supposons que c'est en France
-		info.City = "Local"
-	} else {
-		info.ISP = "Indéterminé"
-		info.CountryCode = "??"
-		info.City = "Inconnue"
+	// Géolocalisation à partir des bases locales (voir LoadGeoIP) ; une plage privée
+	// peut y être déclarée pour situer le réseau local
+	if record, found := LookupGeoIP(ip); found {
+		info.CountryCode = record.CountryCode
+		info.City = record.City
+		info.ASN = record.ASN
+		info.ISP = record.ISP
+	}
+
+	if info.ISP == "" {
+		if ip.IsLoopback() || ip.IsPrivate() {
+			info.ISP = "Réseau local"
+		} else if info.ASN != 0 {
+			info.ISP = fmt.Sprintf("AS%d", info.ASN)
+		} else {
+			info.ISP = "Indéterminé"
+		}
+	}
+	if info.CountryCode == "" {
+		info.CountryCode = UnknownCountry
+	}
+	if info.City == "" {
+		if ip.IsLoopback() || ip.IsPrivate() {
+			info.City = "Local"
+		} else {
+			info.City = "Inconnue"
+		}
 	}
 
 	return info
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Certificat autosigné généré pour un usage local
+const (
+	selfSignedOrganization = "BkC (certificat autosigné)"
+	selfSignedValidity     = 365 * 24 * time.Hour
+	selfSignedRenewBefore  = 7 * 24 * time.Hour // Renouvelé s'il expire avant ce délai
+)
+
+// LoadCertificate charge le certificat et la clé PEM. Si selfSigned est vrai et que les deux fichiers
+// sont absents, un certificat autosigné valable pour hosts est généré puis enregistré ; un
+// certificat autosigné précédemment généré est renouvelé à l'approche de son expiration.
+func LoadCertificate(certFile, keyFile string, selfSigned bool, hosts []string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	switch {
+	case err == nil:
+		if selfSigned && isExpiringSelfSigned(cert.Leaf) {
+			slog.Info("Renouvellement du certificat autosigné", "cert", certFile, "expires", cert.Leaf.NotAfter)
+			return createSelfSigned(certFile, keyFile, hosts)
+		}
+		return cert, nil
+	case selfSigned && !fileExists(certFile) && !fileExists(keyFile):
+		return createSelfSigned(certFile, keyFile, hosts)
+	default:
+		return tls.Certificate{}, fmt.Errorf("certificat TLS: %v", err)
+	}
+}
+
+// fileExists indique si un fichier existe (un fichier illisible existe)
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// isExpiringSelfSigned indique si le certificat a été généré par createSelfSigned et expire bientôt
+func isExpiringSelfSigned(leaf *x509.Certificate) bool {
+	if leaf == nil || !slices.Contains(leaf.Subject.Organization, selfSignedOrganization) {
+		return false
+	}
+	return time.Until(leaf.NotAfter) < selfSignedRenewBefore
+}
+
+// createSelfSigned génère un certificat ECDSA P-256 autosigné et l'enregistre (clé en 0600)
+func createSelfSigned(certFile, keyFile string, hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("génération de la clé TLS: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("génération du numéro de série: %v", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{selfSignedOrganization}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour), // Tolérance pour les horloges en retard
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("création du certificat: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("encodage de la clé TLS: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	for _, file := range []struct {
+		path string
+		data []byte
+		perm os.FileMode
+	}{
+		{keyFile, keyPEM, 0600},
+		{certFile, certPEM, 0644},
+	} {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0700); err != nil {
+			return tls.Certificate{}, fmt.Errorf("enregistrement du certificat: %v", err)
+		}
+		if err := os.WriteFile(file.path, file.data, file.perm); err != nil {
+			return tls.Certificate{}, fmt.Errorf("enregistrement du certificat: %v", err)
+		}
+	}
+
+	slog.Info("Certificat autosigné généré", "cert", certFile, "hosts", hosts, "expires", template.NotAfter)
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
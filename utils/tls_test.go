@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls", "cert.pem"), filepath.Join(dir, "tls", "key.pem")
+
+	// Génération, puis réutilisation du certificat enregistré
+	generated, err := LoadCertificate(certFile, keyFile, true, []string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := generated.Leaf
+	if !slices.Equal(leaf.DNSNames, []string{"localhost"}) || len(leaf.IPAddresses) != 1 || leaf.IPAddresses[0].String() != "127.0.0.1" {
+		t.Errorf("noms %q, adresses %v", leaf.DNSNames, leaf.IPAddresses)
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("clé : %v, %v", info.Mode(), err)
+	}
+	loaded, err := LoadCertificate(certFile, keyFile, true, []string{"autre"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Leaf.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		t.Error("certificat régénéré au lieu d'être relu")
+	}
+
+	tests := []struct {
+		name       string
+		cert, key  string
+		selfSigned bool
+	}{
+		{"fichiers absents sans autosigné", filepath.Join(dir, "absent.pem"), filepath.Join(dir, "absent.key"), false},
+		{"clé seule absente", certFile, filepath.Join(dir, "absent.key"), true},
+		{"clé d'un autre certificat", certFile, certFile, true},
+	}
+	for _, tt := range tests {
+		if _, err := LoadCertificate(tt.cert, tt.key, tt.selfSigned, []string{"localhost"}); err == nil {
+			t.Errorf("%s : certificat accepté", tt.name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "absent.key")); err == nil {
+		t.Error("clé générée malgré un certificat existant")
+	}
+}
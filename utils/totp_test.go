@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret est le secret SHA-1 des vecteurs de test de la RFC 6238 ("12345678901234567890")
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// Vecteurs de la RFC 6238, tronqués aux 6 derniers chiffres
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil || code != tt.code {
+			t.Errorf("TOTPCode(%d) = %q, %v ; attendu %q", tt.unix, code, err, tt.code)
+		}
+	}
+	if code, err := TOTPCode(strings.ToLower(rfc6238Secret), time.Unix(59, 0)); err != nil || code != "287082" {
+		t.Errorf("secret en minuscules : %q, %v", code, err)
+	}
+	if _, err := TOTPCode("pas du base32 !", time.Now()); err == nil {
+		t.Error("secret invalide accepté")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	step := at.Unix() / totpPeriod
+	previous, _ := TOTPCode(rfc6238Secret, at.Add(-totpPeriod*time.Second))
+	next, _ := TOTPCode(rfc6238Secret, at.Add(totpPeriod*time.Second))
+	stale, _ := TOTPCode(rfc6238Secret, at.Add(-2*totpPeriod*time.Second))
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		step     int64
+		valid    bool
+	}{
+		{"code courant", rfc6238Secret, "050471", 0, step, true},
+		{"espaces tolérés", rfc6238Secret, " 050 471 ", 0, step, true},
+		{"période précédente", rfc6238Secret, previous, 0, step - 1, true},
+		{"période suivante", rfc6238Secret, next, 0, step + 1, true},
+		{"décalage trop grand", rfc6238Secret, stale, 0, 0, false},
+		{"code déjà utilisé", rfc6238Secret, "050471", step, 0, false},
+		{"code suivant après usage", rfc6238Secret, next, step, step + 1, true},
+		{"mauvais code", rfc6238Secret, "123456", 0, 0, false},
+		{"longueur incorrecte", rfc6238Secret, "05047", 0, 0, false},
+		{"secret invalide", "!!", "050471", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ValidateTOTP(tt.secret, tt.code, at, tt.lastStep)
+		if ok != tt.valid || got != tt.step {
+			t.Errorf("%s : ValidateTOTP = %d, %v ; attendu %d, %v", tt.name, got, ok, tt.step, tt.valid)
+		}
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("BkC", "alice", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/BkC:alice" {
+		t.Errorf("URI %s", uri)
+	}
+	for name, want := range map[string]string{
+		"secret": rfc6238Secret, "issuer": "BkC", "algorithm": "SHA1", "digits": "6", "period": "30",
+	} {
+		if got := uri.Query().Get(name); got != want {
+			t.Errorf("paramètre %s = %q, attendu %q", name, got, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("%d codes et %d empreintes", len(codes), len(hashes))
+	}
+
+	remaining, ok := UseRecoveryCode(hashes, " "+strings.ToUpper(codes[3])+" ")
+	if !ok || len(remaining) != recoveryCodeCount-1 {
+		t.Fatalf("code valide refusé : %v, %d restants", ok, len(remaining))
+	}
+	if _, ok := UseRecoveryCode(remaining, codes[3]); ok {
+		t.Error("code de récupération réutilisable")
+	}
+	if _, ok := UseRecoveryCode(remaining, "abcd-efgh"); ok {
+		t.Error("code inconnu accepté")
+	}
+	if len(hashes) != recoveryCodeCount {
+		t.Error("la liste d'origine a été modifiée")
+	}
+}